@@ -16,27 +16,38 @@ import (
 	"github.com/navikt/zrooms/internal/web"
 )
 
+// Deadlines for shutting down. Together they stay within the 30 second grace period
+// Kubernetes gives a pod before killing it.
+const (
+	serverShutdownTimeout = 10 * time.Second
+	queueDrainTimeout     = 15 * time.Second
+)
+
 func main() {
 	// Get Redis configuration
 	redisConfig := config.GetRedisConfig()
+	boltConfig := config.GetBoltConfig()
+	journalConfig := config.GetJournalConfig()
+	inMemory := !redisConfig.Enabled && !boltConfig.Enabled()
 
 	// Initialize the repository using the factory
-	repo, err := repository.NewRepository(redisConfig)
+	repo, err := repository.NewRepository(redisConfig, boltConfig, journalConfig)
 	if err != nil {
 		log.Fatalf("Failed to initialize repository: %v", err)
 	}
 
-	// Check if we're using a Redis repository, and if so, close it properly on exit
-	if redisRepo, ok := repo.(interface{ Close() error }); ok {
+	// Close the Redis connection or the embedded store properly on exit
+	if closer, ok := repo.(interface{ Close() error }); ok {
 		defer func() {
-			if err := redisRepo.Close(); err != nil {
-				log.Printf("Error closing Redis connection: %v", err)
+			if err := closer.Close(); err != nil {
+				log.Printf("Error closing repository: %v", err)
 			}
 		}()
 	}
 
-	// Initialize the service layer
+	// Initialize the service layer, with handlers for the supported webhook events
 	meetingService := service.NewMeetingService(repo)
+	log.Printf("Registered handlers for %d webhook event types", len(meetingService.HandledEvents()))
 
 	// Set up web UI routes
 	webHandler, err := web.NewHandler(meetingService, "./internal/web/templates")
@@ -44,17 +55,96 @@ func main() {
 		log.Fatalf("Failed to initialize web handler: %v", err)
 	}
 
-	// Set up admin routes
-	adminHandler, err := web.NewAdminHandler(meetingService, repo, "./internal/web/templates")
-	if err != nil {
-		log.Fatalf("Failed to initialize admin handler: %v", err)
+	// Register the SSE update callbacks with the meeting service
+	meetingService.RegisterUpdateCallback(webHandler.NotifyMeetingUpdate)
+	meetingService.RegisterRoomUpdateCallback(webHandler.NotifyRoomUpdate)
+	meetingService.RegisterDeviceAlertCallback(webHandler.NotifyDeviceAlert)
+
+	// Set up the webhook handler, reporting valid events to the watchdog so stale data is noticed
+	watchdog := service.NewSilenceWatchdog(config.GetWatchdogConfig(), time.Now())
+	webhookHandler := api.NewWebhookHandler(repo, meetingService)
+	webhookHandler.SetWatchdog(watchdog)
+
+	// Meetings only expire in the Redis and embedded stores, so a rebuild does not bring back expired meetings
+	if redisConfig.Enabled {
+		webhookHandler.SetMeetingTTL(redisConfig.MeetingTTL)
+	} else if boltConfig.Enabled() {
+		webhookHandler.SetMeetingTTL(boltConfig.MeetingTTL)
 	}
+	webHandler.SetFreshnessMonitor(watchdog)
 
-	// Register the SSE update callback with the meeting service
-	meetingService.RegisterUpdateCallback(webHandler.NotifyMeetingUpdate)
+	// "zrooms migrate" upgrades stored meeting records to the current layout instead of serving
+	checker, persistent := repo.(repository.MeetingRecordChecker)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if !persistent {
+			log.Printf("Migrate failed: the repository does not persist meetings")
+			os.Exit(1)
+		}
+		if err := checkMeetingRecords(checker, true); err != nil {
+			log.Printf("Migrate failed: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Report stored meeting records this build cannot read, so a layout change that breaks them is noticed
+	if persistent {
+		if err := checkMeetingRecords(checker, false); err != nil {
+			log.Printf("Error checking stored meeting records: %v", err)
+		}
+	}
 
-	// Set up API routes with repository and meeting service
-	mux := api.SetupRoutes(repo, meetingService)
+	// "zrooms rebuild" recreates meeting state from the event journal instead of serving. In-memory state
+	// would be lost when the command exits, so the in-memory backend rebuilds when the server starts instead.
+	if len(os.Args) > 1 && os.Args[1] == "rebuild" {
+		if inMemory {
+			log.Printf("Rebuild failed: the in-memory repository does not keep state after this command exits; " +
+				"set EVENT_JOURNAL_FILE and restart the server to replay the journal")
+			os.Exit(1)
+		}
+		if err := rebuild(webhookHandler); err != nil {
+			log.Printf("Rebuild failed: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// The in-memory backend starts out empty, so recreate its state from the journal file before serving
+	if inMemory && journalConfig.File != "" {
+		if err := rebuild(webhookHandler); err != nil {
+			log.Fatalf("Failed to rebuild meeting state from the event journal: %v", err)
+		}
+	}
+
+	// Apply events through a worker pool unless disabled
+	var eventQueue *api.EventQueue
+	if queueConfig := config.GetWebhookQueueConfig(); queueConfig.Workers > 0 {
+		eventQueue = api.NewEventQueue(queueConfig, webhookHandler.ProcessEvent)
+		eventQueue.SetFailureHandler(webhookHandler.DeadLetterEvent)
+		webhookHandler.SetQueue(eventQueue)
+	}
+
+	// Only accept webhooks from allowed source addresses, if configured. The allowlist file is
+	// reloaded when it changes or on SIGHUP.
+	if allowlistConfig := config.GetWebhookAllowlistConfig(); allowlistConfig.Enabled() {
+		allowlist, err := api.NewIPAllowlist(allowlistConfig)
+		if err != nil {
+			log.Fatalf("Failed to load webhook IP allowlist: %v", err)
+		}
+		webhookHandler.SetAllowlist(allowlist)
+		go allowlist.WatchFile(context.Background(), 30*time.Second)
+		go reloadOnHangup(allowlist)
+	}
+
+	// Set up API routes with the webhook handler
+	mux := api.SetupRoutes(webhookHandler, watchdog)
+
+	// Set up admin routes, replaying failed events through the webhook handler
+	adminHandler, err := web.NewAdminHandler(meetingService, repo, webhookHandler, "./internal/web/templates")
+	if err != nil {
+		log.Fatalf("Failed to initialize admin handler: %v", err)
+	}
+	adminHandler.SetWebhookMonitor(webhookHandler)
 
 	// Set up web UI routes
 	webHandler.SetupRoutes(mux)
@@ -102,16 +192,69 @@ func main() {
 		webHandler.Shutdown()
 
 		// Create a deadline to wait for
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
+		serverCtx, cancelServer := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancelServer()
 
 		// Doesn't block if there are no connections, but will otherwise
-		// wait until the timeout deadline.
-		if err := server.Shutdown(ctx); err != nil {
+		// wait until the timeout deadline. The queue is drained even if this fails.
+		if err := server.Shutdown(serverCtx); err != nil {
+			log.Printf("Error shutting down server: %v", err)
 			server.Close()
-			log.Fatalf("Error shutting down server: %v", err)
+		}
+
+		// No new webhooks can arrive now, so apply the events still waiting in the queue.
+		// The queue gets its own deadline, so a slow HTTP drain does not leave it without time.
+		if eventQueue != nil {
+			queueCtx, cancelQueue := context.WithTimeout(context.Background(), queueDrainTimeout)
+			defer cancelQueue()
+			if err := eventQueue.Shutdown(queueCtx); err != nil {
+				log.Printf("Error draining webhook queue: %v", err)
+			}
 		}
 
 		log.Println("Server gracefully stopped")
 	}
 }
+
+// rebuild wipes all meetings and replays the event journal through the webhook handler
+func rebuild(webhookHandler *api.WebhookHandler) error {
+	log.Println("Rebuilding meeting state from the event journal...")
+
+	result, err := webhookHandler.RebuildFromJournal(context.Background())
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Rebuild complete: %d events replayed, %d skipped, %d of expired meetings skipped",
+		result.Replayed, result.Failed, result.Expired)
+	return nil
+}
+
+// checkMeetingRecords checks the stored meeting records and logs the ones that are outdated or cannot be read
+func checkMeetingRecords(checker repository.MeetingRecordChecker, migrate bool) error {
+	report, err := checker.CheckMeetingRecords(context.Background(), migrate)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range report.Unreadable {
+		log.Printf("Warning: Cannot read stored meeting %s: %s", record.Key, record.Error)
+	}
+	if report.Outdated > report.Migrated {
+		log.Printf("%d meeting records use an older layout and are upgraded when read; run \"zrooms migrate\" to rewrite them",
+			report.Outdated-report.Migrated)
+	}
+	log.Printf("Meeting record check: %s", report)
+	return nil
+}
+
+// reloadOnHangup reloads the webhook IP allowlist every time the process receives SIGHUP
+func reloadOnHangup(allowlist *api.IPAllowlist) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		if err := allowlist.Reload(); err != nil {
+			log.Printf("Error reloading webhook IP allowlist, keeping the current ranges: %v", err)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/navikt/zrooms/internal/config"
+	"github.com/navikt/zrooms/internal/models"
+)
+
+// maxRejectedSources is how many distinct rejected source addresses are counted individually
+const maxRejectedSources = 100
+
+// IPAllowlist restricts which source addresses may deliver webhook requests. The CIDR ranges come
+// from configuration and an optional file, which can be reloaded while the service is running.
+type IPAllowlist struct {
+	static      []netip.Prefix // Ranges from configuration, kept across reloads
+	file        string
+	trustedHops int // Number of reverse proxies in front of the service that append to X-Forwarded-For
+
+	mu       sync.RWMutex
+	prefixes []netip.Prefix
+	modTime  time.Time // Modification time of the file when it was last loaded
+
+	rejectedMu sync.Mutex
+	rejected   map[string]*models.RejectedSource
+}
+
+// NewIPAllowlist creates an allowlist from configured CIDR ranges and an optional file with one range per line.
+// Single addresses are accepted as ranges of one address.
+func NewIPAllowlist(cfg config.WebhookAllowlistConfig) (*IPAllowlist, error) {
+	static, err := parsePrefixes(cfg.CIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	allowlist := &IPAllowlist{
+		static:      static,
+		file:        cfg.File,
+		trustedHops: cfg.TrustedProxyHops,
+		rejected:    make(map[string]*models.RejectedSource),
+	}
+	if err := allowlist.Reload(); err != nil {
+		return nil, err
+	}
+	return allowlist, nil
+}
+
+// Reload reads the allowlist file again. The current ranges are kept when the file cannot be read or parsed.
+func (a *IPAllowlist) Reload() error {
+	prefixes := append([]netip.Prefix(nil), a.static...)
+	var modTime time.Time
+
+	if a.file != "" {
+		info, err := os.Stat(a.file)
+		if err != nil {
+			return fmt.Errorf("failed to read allowlist file: %w", err)
+		}
+		data, err := os.ReadFile(a.file)
+		if err != nil {
+			return fmt.Errorf("failed to read allowlist file: %w", err)
+		}
+		filePrefixes, err := parseAllowlistFile(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse allowlist file %s: %w", a.file, err)
+		}
+		prefixes = append(prefixes, filePrefixes...)
+		modTime = info.ModTime()
+	}
+
+	a.mu.Lock()
+	a.prefixes = prefixes
+	a.modTime = modTime
+	a.mu.Unlock()
+
+	if len(prefixes) == 0 {
+		log.Printf("Warning: Webhook IP allowlist is empty - all webhook requests will be rejected")
+	} else {
+		log.Printf("Loaded webhook IP allowlist with %d ranges", len(prefixes))
+	}
+	return nil
+}
+
+// WatchFile reloads the allowlist whenever the file changes, checking at the given interval until the context is done
+func (a *IPAllowlist) WatchFile(ctx context.Context, interval time.Duration) {
+	if a.file == "" {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(a.file)
+			if err != nil {
+				log.Printf("Error checking webhook IP allowlist file: %v", err)
+				continue
+			}
+
+			a.mu.RLock()
+			changed := !info.ModTime().Equal(a.modTime)
+			a.mu.RUnlock()
+
+			if changed {
+				if err := a.Reload(); err != nil {
+					log.Printf("Error reloading webhook IP allowlist, keeping the current ranges: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// Allows reports whether the source address is in the allowlist
+func (a *IPAllowlist) Allows(addr netip.Addr) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	addr = addr.Unmap()
+	for _, prefix := range a.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// SourceAddress returns the address a request originates from. With trusted proxy hops configured, it is
+// taken from X-Forwarded-For, skipping the entries appended by the trusted proxies. Entries further to the
+// left are set by the client and cannot be trusted.
+func (a *IPAllowlist) SourceAddress(r *http.Request) (netip.Addr, error) {
+	if a.trustedHops > 0 {
+		var forwarded []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			for _, entry := range strings.Split(header, ",") {
+				forwarded = append(forwarded, strings.TrimSpace(entry))
+			}
+		}
+
+		// With fewer entries than proxies the request did not pass through all of them,
+		// so the connecting address is the source
+		if len(forwarded) >= a.trustedHops {
+			addr, err := netip.ParseAddr(forwarded[len(forwarded)-a.trustedHops])
+			if err != nil {
+				return netip.Addr{}, fmt.Errorf("invalid X-Forwarded-For address: %w", err)
+			}
+			return addr.Unmap(), nil
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("invalid remote address: %w", err)
+	}
+	return addr.Unmap(), nil
+}
+
+// recordRejected counts a request from a source that is not in the allowlist,
+// forgetting the least recently seen source when too many are counted
+func (a *IPAllowlist) recordRejected(source string) {
+	a.rejectedMu.Lock()
+	defer a.rejectedMu.Unlock()
+
+	now := time.Now()
+	entry, exists := a.rejected[source]
+	if !exists {
+		if len(a.rejected) >= maxRejectedSources {
+			var oldest *models.RejectedSource
+			for _, candidate := range a.rejected {
+				if oldest == nil || candidate.LastSeen.Before(oldest.LastSeen) {
+					oldest = candidate
+				}
+			}
+			delete(a.rejected, oldest.Address)
+		}
+		entry = &models.RejectedSource{Address: source}
+		a.rejected[source] = entry
+	}
+	entry.Count++
+	entry.LastSeen = now
+}
+
+// RejectedSources returns the source addresses requests were rejected from, most frequent first
+func (a *IPAllowlist) RejectedSources() []models.RejectedSource {
+	a.rejectedMu.Lock()
+	defer a.rejectedMu.Unlock()
+
+	sources := make([]models.RejectedSource, 0, len(a.rejected))
+	for _, source := range a.rejected {
+		sources = append(sources, *source)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		if sources[i].Count != sources[j].Count {
+			return sources[i].Count > sources[j].Count
+		}
+		return sources[i].Address < sources[j].Address
+	})
+	return sources
+}
+
+// parseAllowlistFile parses one CIDR range or address per line, ignoring blank lines and # comments
+func parseAllowlistFile(data []byte) ([]netip.Prefix, error) {
+	var entries []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			entries = append(entries, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return parsePrefixes(entries)
+}
+
+// parsePrefixes parses CIDR ranges, treating a single address as a range of one address
+func parsePrefixes(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR range %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q: %w", entry, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
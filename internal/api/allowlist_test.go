@@ -0,0 +1,117 @@
+package api_test
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/navikt/zrooms/internal/api"
+	"github.com/navikt/zrooms/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIPAllowlist tests matching source addresses against configured ranges and a reloadable file
+func TestIPAllowlist(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "allowlist.txt")
+	require.NoError(t, os.WriteFile(file, []byte("# Zoom webhook ranges\n198.51.100.0/24\n\n2001:db8::/32 # IPv6\n"), 0o644))
+
+	allowlist, err := api.NewIPAllowlist(config.WebhookAllowlistConfig{CIDRs: []string{"203.0.113.7"}, File: file})
+	require.NoError(t, err)
+
+	for addr, allowed := range map[string]bool{
+		"203.0.113.7":          true,
+		"203.0.113.8":          false,
+		"198.51.100.42":        true,
+		"::ffff:198.51.100.42": true,
+		"2001:db8::1":          true,
+		"192.0.2.1":            false,
+	} {
+		assert.Equal(t, allowed, allowlist.Allows(netip.MustParseAddr(addr)), addr)
+	}
+
+	t.Run("Reload", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(file, []byte("192.0.2.0/24\n"), 0o644))
+		require.NoError(t, allowlist.Reload())
+
+		assert.True(t, allowlist.Allows(netip.MustParseAddr("192.0.2.1")))
+		assert.False(t, allowlist.Allows(netip.MustParseAddr("198.51.100.42")))
+		assert.True(t, allowlist.Allows(netip.MustParseAddr("203.0.113.7")), "Configured ranges should be kept")
+	})
+
+	t.Run("InvalidFileKeepsCurrentRanges", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(file, []byte("not-an-address\n"), 0o644))
+		assert.Error(t, allowlist.Reload())
+		assert.True(t, allowlist.Allows(netip.MustParseAddr("192.0.2.1")))
+	})
+
+	t.Run("InvalidConfiguration", func(t *testing.T) {
+		_, err := api.NewIPAllowlist(config.WebhookAllowlistConfig{CIDRs: []string{"10.0.0.0/33"}})
+		assert.Error(t, err)
+
+		_, err = api.NewIPAllowlist(config.WebhookAllowlistConfig{File: filepath.Join(t.TempDir(), "missing.txt")})
+		assert.Error(t, err)
+	})
+}
+
+// TestIPAllowlistWatchFile tests that changes to the allowlist file are picked up without a restart
+func TestIPAllowlistWatchFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "allowlist.txt")
+	require.NoError(t, os.WriteFile(file, []byte("198.51.100.0/24\n"), 0o644))
+
+	allowlist, err := api.NewIPAllowlist(config.WebhookAllowlistConfig{File: file})
+	require.NoError(t, err)
+
+	ctx := t.Context()
+	go allowlist.WatchFile(ctx, 5*time.Millisecond)
+
+	require.NoError(t, os.WriteFile(file, []byte("192.0.2.0/24\n"), 0o644))
+	// Make sure the modification time differs on file systems with coarse timestamps
+	require.NoError(t, os.Chtimes(file, time.Now(), time.Now().Add(time.Minute)))
+
+	assert.Eventually(t, func() bool {
+		return allowlist.Allows(netip.MustParseAddr("192.0.2.1"))
+	}, time.Second, 5*time.Millisecond)
+}
+
+// TestIPAllowlistSourceAddress tests which address is used as the source of a request
+func TestIPAllowlistSourceAddress(t *testing.T) {
+	tests := []struct {
+		name          string
+		trustedHops   int
+		remoteAddr    string
+		forwardedFor  []string
+		expected      string
+		expectedError bool
+	}{
+		{name: "RemoteAddress", remoteAddr: "192.0.2.1:1234", forwardedFor: []string{"198.51.100.1"}, expected: "192.0.2.1"},
+		{name: "OneTrustedProxy", trustedHops: 1, remoteAddr: "10.0.0.1:1234", forwardedFor: []string{"203.0.113.9, 198.51.100.1"}, expected: "198.51.100.1"},
+		{name: "TwoTrustedProxies", trustedHops: 2, remoteAddr: "10.0.0.1:1234", forwardedFor: []string{"203.0.113.9", "198.51.100.1, 10.0.0.2"}, expected: "198.51.100.1"},
+		{name: "BypassedProxy", trustedHops: 2, remoteAddr: "192.0.2.1:1234", forwardedFor: []string{"198.51.100.1"}, expected: "192.0.2.1"},
+		{name: "InvalidForwardedAddress", trustedHops: 1, remoteAddr: "10.0.0.1:1234", forwardedFor: []string{"unknown"}, expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowlist, err := api.NewIPAllowlist(config.WebhookAllowlistConfig{CIDRs: []string{"0.0.0.0/0"}, TrustedProxyHops: tt.trustedHops})
+			require.NoError(t, err)
+
+			req := httptest.NewRequest("POST", "/webhook", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+
+			addr, err := allowlist.SourceAddress(req)
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, addr.String())
+		})
+	}
+}
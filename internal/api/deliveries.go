@@ -0,0 +1,90 @@
+package api
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/navikt/zrooms/internal/models"
+)
+
+// defaultDeliveryLogSize is how many recent webhook deliveries are kept when no size is configured
+const defaultDeliveryLogSize = 100
+
+// deliveryLog is a ring buffer of the most recent webhook deliveries
+type deliveryLog struct {
+	mu         sync.Mutex
+	size       int
+	deliveries []models.WebhookDelivery // Oldest first
+	nextID     uint64
+}
+
+// newDeliveryLog creates a delivery log keeping the given number of deliveries
+func newDeliveryLog(size int) *deliveryLog {
+	if size <= 0 {
+		size = defaultDeliveryLogSize
+	}
+	return &deliveryLog{size: size}
+}
+
+// add records a new delivery, dropping the oldest one when full, and returns its ID
+func (l *deliveryLog) add(delivery models.WebhookDelivery) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.nextID++
+	delivery.ID = strconv.FormatUint(l.nextID, 10)
+
+	if len(l.deliveries) >= l.size {
+		l.deliveries = l.deliveries[1:]
+	}
+	l.deliveries = append(l.deliveries, delivery)
+	return delivery.ID
+}
+
+// update changes a recorded delivery, unless it has already been dropped from the log
+func (l *deliveryLog) update(id string, change func(delivery *models.WebhookDelivery)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := range l.deliveries {
+		if l.deliveries[i].ID == id {
+			change(&l.deliveries[i])
+			return
+		}
+	}
+}
+
+// complete records the outcome of a delivery and how long it took since the request was received
+func (l *deliveryLog) complete(id string, outcome models.DeliveryOutcome, detail string) {
+	l.update(id, func(delivery *models.WebhookDelivery) {
+		delivery.Outcome = outcome
+		delivery.Error = detail
+		delivery.Latency = time.Since(delivery.ReceivedAt)
+	})
+}
+
+// recent returns the recorded deliveries, newest first
+func (l *deliveryLog) recent() []models.WebhookDelivery {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	deliveries := make([]models.WebhookDelivery, len(l.deliveries))
+	for i, delivery := range l.deliveries {
+		deliveries[len(deliveries)-1-i] = delivery
+	}
+	return deliveries
+}
+
+// get returns a recorded delivery by ID
+func (l *deliveryLog) get(id string) (models.WebhookDelivery, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, delivery := range l.deliveries {
+		if delivery.ID == id {
+			return delivery, true
+		}
+	}
+	return models.WebhookDelivery{}, false
+}
@@ -4,6 +4,9 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"time"
+
+	"github.com/navikt/zrooms/internal/service"
 )
 
 // HealthResponse represents the response for health check endpoints
@@ -22,7 +25,8 @@ func HealthLiveHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// HealthReadyHandler handles Kubernetes readiness probe requests
+// HealthReadyHandler handles Kubernetes readiness probe requests.
+// It does not report webhook silence: taking the pod out of service would stop the very events that end it.
 func HealthReadyHandler(w http.ResponseWriter, r *http.Request) {
 	response := HealthResponse{
 		Status: "UP",
@@ -32,3 +36,41 @@ func HealthReadyHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
+
+// HealthStatusResponse represents the response for the status endpoint
+type HealthStatusResponse struct {
+	Status           string     `json:"status"`
+	LastEventAt      *time.Time `json:"last_event_at,omitempty"`
+	ExpectingTraffic bool       `json:"expecting_traffic"`
+	SilenceSeconds   int64      `json:"silence_seconds"`
+	Reason           string     `json:"reason,omitempty"`
+}
+
+// HealthStatusHandler returns a handler reporting whether webhook events arrive as expected.
+// It responds with 503 Service Unavailable while the watchdog reports a degraded state, so monitoring can alert on it.
+func HealthStatusHandler(watchdog *service.SilenceWatchdog) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := HealthStatusResponse{
+			Status: string(service.HealthUp),
+		}
+
+		statusCode := http.StatusOK
+		if watchdog != nil {
+			status := watchdog.Status()
+			response.Status = string(status.Status)
+			response.ExpectingTraffic = status.ExpectingTraffic
+			response.SilenceSeconds = int64(status.Silence / time.Second)
+			response.Reason = status.Reason
+			if !status.LastEventAt.IsZero() {
+				response.LastEventAt = &status.LastEventAt
+			}
+			if status.Stale() {
+				statusCode = http.StatusServiceUnavailable
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		json.NewEncoder(w).Encode(response)
+	}
+}
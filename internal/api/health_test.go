@@ -5,9 +5,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/navikt/zrooms/internal/api"
+	"github.com/navikt/zrooms/internal/config"
+	"github.com/navikt/zrooms/internal/service"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHealthLive(t *testing.T) {
@@ -63,3 +67,42 @@ func TestHealthReady(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "UP", response["status"])
 }
+
+func TestHealthStatus(t *testing.T) {
+	watchdog := service.NewSilenceWatchdog(config.WatchdogConfig{
+		SilenceThreshold: time.Minute,
+		ExpectedDays:     []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday},
+		ExpectedFrom:     0,
+		ExpectedUntil:    24 * time.Hour,
+	}, time.Now().Add(-time.Hour))
+	handler := api.HealthStatusHandler(watchdog)
+
+	status := func() (int, api.HealthStatusResponse) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/health/status", nil))
+
+		var response api.HealthStatusResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		return rr.Code, response
+	}
+
+	// No events for an hour while events are always expected
+	code, response := status()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "DEGRADED", response.Status)
+	assert.True(t, response.ExpectingTraffic)
+	assert.GreaterOrEqual(t, response.SilenceSeconds, int64(3600))
+	assert.Nil(t, response.LastEventAt)
+	assert.NotEmpty(t, response.Reason)
+
+	watchdog.RecordEvent(time.Now())
+	code, response = status()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "UP", response.Status)
+	assert.NotNil(t, response.LastEventAt)
+
+	// Without a watchdog the status is always up
+	rr := httptest.NewRecorder()
+	api.HealthStatusHandler(nil).ServeHTTP(rr, httptest.NewRequest("GET", "/health/status", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/navikt/zrooms/internal/models"
+)
+
+// RebuildResult summarizes a rebuild of meeting state from the event journal
+type RebuildResult struct {
+	Replayed int // Events applied successfully
+	Failed   int // Events that could not be applied and were skipped
+	Expired  int // Events of meetings that had expired by the time of the rebuild, which were skipped
+}
+
+// RebuildFromJournal wipes all meetings, rooms and device alerts and replays the event journal through the same
+// handlers used for live webhooks. This recovers from bugs in how state was stored without
+// waiting for new traffic from Zoom. Events that fail to apply are logged and skipped.
+// With a meeting TTL set, the events of meetings whose last event is older than the TTL are skipped,
+// as the repository would have expired those meetings.
+func (h *WebhookHandler) RebuildFromJournal(ctx context.Context) (RebuildResult, error) {
+	var result RebuildResult
+
+	expired, err := h.expiredMeetings(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	if err := h.repo.ClearMeetings(ctx); err != nil {
+		return result, fmt.Errorf("failed to clear meetings: %w", err)
+	}
+	if err := h.repo.ClearRooms(ctx); err != nil {
+		return result, fmt.Errorf("failed to clear rooms: %w", err)
+	}
+	if err := h.repo.ClearDeviceAlerts(ctx); err != nil {
+		return result, fmt.Errorf("failed to clear device alerts: %w", err)
+	}
+
+	err = h.repo.ReplayEvents(ctx, func(event *models.WebhookEvent) error {
+		if expired[h.meetingKey(event)] {
+			result.Expired++
+			return nil
+		}
+
+		eventCtx, cancel := context.WithTimeout(ctx, eventTimeout)
+		defer cancel()
+
+		if err := h.ProcessEvent(eventCtx, event); err != nil {
+			log.Printf("Skipping journaled event %s for meeting %s: %v", event.Event, event.MeetingID(), err)
+			result.Failed++
+			return nil
+		}
+		result.Replayed++
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to replay event journal: %w", err)
+	}
+
+	return result, nil
+}
+
+// expiredMeetings reads the journal and returns the keys of the meetings whose last event is older than
+// the meeting TTL. Meetings expire a TTL after their last update, so one recent event keeps all of them.
+func (h *WebhookHandler) expiredMeetings(ctx context.Context) (map[string]bool, error) {
+	expired := make(map[string]bool)
+	if h.meetingTTL <= 0 {
+		return expired, nil
+	}
+
+	lastEvent := make(map[string]int64)
+	err := h.repo.ReplayEvents(ctx, func(event *models.WebhookEvent) error {
+		if key := h.meetingKey(event); key != "" {
+			lastEvent[key] = max(lastEvent[key], event.EventTS)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event journal: %w", err)
+	}
+
+	cutoff := time.Now().Add(-h.meetingTTL).UnixMilli()
+	for key, eventTS := range lastEvent {
+		if eventTS < cutoff {
+			expired[key] = true
+		}
+	}
+	return expired, nil
+}
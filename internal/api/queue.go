@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/navikt/zrooms/internal/config"
+	"github.com/navikt/zrooms/internal/models"
+	"github.com/navikt/zrooms/internal/service"
+)
+
+// eventTimeout bounds the time spent on a single attempt to apply an event
+const eventTimeout = 5 * time.Second
+
+var (
+	// ErrInvalidEvent is returned for events that can never be applied, so retrying them is pointless.
+	// It is the error the meeting service returns for such events.
+	ErrInvalidEvent = service.ErrInvalidEvent
+	// ErrQueueFull is returned when the queue has no room for another event
+	ErrQueueFull = errors.New("webhook queue is full")
+	// ErrQueueClosed is returned when enqueuing after shutdown has started
+	ErrQueueClosed = errors.New("webhook queue is closed")
+)
+
+// EventProcessor applies a single webhook event
+type EventProcessor func(ctx context.Context, event *models.WebhookEvent) error
+
+// FailureHandler is called with an event that could not be applied after all attempts
+type FailureHandler func(event *models.WebhookEvent, err error, attempts int)
+
+// EventQueue is a bounded queue of webhook events applied by a pool of workers.
+// Events are partitioned by meeting ID, so events for the same meeting are applied
+// in the order they were received while different meetings are processed in parallel.
+type EventQueue struct {
+	partitions   []chan *models.WebhookEvent
+	process      EventProcessor
+	onFailure    FailureHandler
+	maxRetries   int
+	retryBackoff time.Duration
+
+	mu     sync.RWMutex // Guards closed against concurrent Enqueue and Shutdown
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewEventQueue creates an event queue and starts its workers
+func NewEventQueue(cfg config.WebhookQueueConfig, process EventProcessor) *EventQueue {
+	workers := cfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	// Split the capacity across the workers, keeping room for at least one event each
+	partitionSize := cfg.Size / workers
+	if partitionSize < 1 {
+		partitionSize = 1
+	}
+
+	q := &EventQueue{
+		partitions:   make([]chan *models.WebhookEvent, workers),
+		process:      process,
+		maxRetries:   cfg.MaxRetries,
+		retryBackoff: cfg.RetryBackoff,
+	}
+
+	for i := range q.partitions {
+		q.partitions[i] = make(chan *models.WebhookEvent, partitionSize)
+		q.wg.Add(1)
+		go q.worker(q.partitions[i])
+	}
+
+	log.Printf("Started webhook queue with %d workers", workers)
+	return q
+}
+
+// SetFailureHandler sets the function called with events that could not be applied.
+// Must be called before events are enqueued.
+func (q *EventQueue) SetFailureHandler(onFailure FailureHandler) {
+	q.onFailure = onFailure
+}
+
+// Enqueue adds an event to the queue without blocking
+func (q *EventQueue) Enqueue(event *models.WebhookEvent) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	select {
+	case q.partitions[q.partitionFor(event)] <- event:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Shutdown stops accepting events and waits until the queued events have been applied
+// or the context is done, whichever comes first
+func (q *EventQueue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		for _, partition := range q.partitions {
+			close(partition)
+		}
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Printf("Webhook queue drained")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// partitionFor picks the partition for an event based on its meeting ID
+func (q *EventQueue) partitionFor(event *models.WebhookEvent) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(event.MeetingID()))
+	return int(hash.Sum32() % uint32(len(q.partitions)))
+}
+
+// worker applies events from a single partition until it is closed
+func (q *EventQueue) worker(partition <-chan *models.WebhookEvent) {
+	defer q.wg.Done()
+
+	for event := range partition {
+		if attempts, err := q.processWithRetry(event); err != nil && q.onFailure != nil {
+			q.onFailure(event, err, attempts)
+		}
+	}
+}
+
+// processWithRetry applies an event, retrying with exponential backoff on failure.
+// Returns the number of attempts made and the last error, if any.
+func (q *EventQueue) processWithRetry(event *models.WebhookEvent) (int, error) {
+	backoff := q.retryBackoff
+	var err error
+
+	attempt := 0
+	for ; attempt <= q.maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), eventTimeout)
+		err = q.process(ctx, event)
+		cancel()
+
+		if err == nil {
+			return attempt + 1, nil
+		}
+
+		if errors.Is(err, ErrInvalidEvent) {
+			log.Printf("Not retrying invalid webhook event %s: %v", event.Event, err)
+			return attempt + 1, err
+		}
+
+		log.Printf("Error applying webhook event %s (attempt %d of %d): %v", event.Event, attempt+1, q.maxRetries+1, err)
+	}
+
+	log.Printf("Giving up on webhook event %s for meeting %s: %v", event.Event, event.MeetingID(), err)
+	return attempt, err
+}
@@ -0,0 +1,173 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/navikt/zrooms/internal/api"
+	"github.com/navikt/zrooms/internal/config"
+	"github.com/navikt/zrooms/internal/models"
+	"github.com/navikt/zrooms/internal/repository/memory"
+	"github.com/navikt/zrooms/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newQueueTestEvent creates a webhook event for the given meeting
+func newQueueTestEvent(meetingID string, eventTS int64) *models.WebhookEvent {
+	return &models.WebhookEvent{
+		Event:   "meeting.updated",
+		Payload: json.RawMessage(fmt.Sprintf(`{"object": {"id": "%s"}}`, meetingID)),
+		EventTS: eventTS,
+	}
+}
+
+// TestEventQueueOrderingPerMeeting tests that events for the same meeting are applied in order
+func TestEventQueueOrderingPerMeeting(t *testing.T) {
+	var mu sync.Mutex
+	applied := make(map[string][]int64)
+
+	queue := api.NewEventQueue(config.WebhookQueueConfig{Workers: 4, Size: 400}, func(ctx context.Context, event *models.WebhookEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		applied[event.MeetingID()] = append(applied[event.MeetingID()], event.EventTS)
+		return nil
+	})
+
+	for ts := int64(1); ts <= 50; ts++ {
+		for _, meetingID := range []string{"m1", "m2", "m3"} {
+			require.NoError(t, queue.Enqueue(newQueueTestEvent(meetingID, ts)))
+		}
+	}
+
+	require.NoError(t, queue.Shutdown(context.Background()))
+
+	for _, meetingID := range []string{"m1", "m2", "m3"} {
+		events := applied[meetingID]
+		require.Len(t, events, 50)
+		for i, ts := range events {
+			assert.Equal(t, int64(i+1), ts, "Events for %s should be applied in order", meetingID)
+		}
+	}
+}
+
+// TestEventQueueRetries tests retry with backoff and that invalid events are not retried
+func TestEventQueueRetries(t *testing.T) {
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+
+	queue := api.NewEventQueue(config.WebhookQueueConfig{Workers: 1, Size: 10, MaxRetries: 2, RetryBackoff: time.Millisecond}, func(ctx context.Context, event *models.WebhookEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[event.MeetingID()]++
+
+		switch event.MeetingID() {
+		case "flaky":
+			if attempts["flaky"] < 2 {
+				return errors.New("temporary failure")
+			}
+			return nil
+		case "broken":
+			return errors.New("permanent failure")
+		case "invalid":
+			return fmt.Errorf("%w: bad payload", api.ErrInvalidEvent)
+		}
+		return nil
+	})
+
+	failed := make(map[string]int)
+	queue.SetFailureHandler(func(event *models.WebhookEvent, err error, attempts int) {
+		mu.Lock()
+		defer mu.Unlock()
+		failed[event.MeetingID()] = attempts
+	})
+
+	for _, meetingID := range []string{"flaky", "broken", "invalid"} {
+		require.NoError(t, queue.Enqueue(newQueueTestEvent(meetingID, 1)))
+	}
+	require.NoError(t, queue.Shutdown(context.Background()))
+
+	assert.Equal(t, map[string]int{"broken": 3, "invalid": 1}, failed, "Only events that could not be applied are reported as failed")
+
+	assert.Equal(t, 2, attempts["flaky"], "Should succeed on the second attempt")
+	assert.Equal(t, 3, attempts["broken"], "Should give up after the configured retries")
+	assert.Equal(t, 1, attempts["invalid"], "Invalid events should not be retried")
+}
+
+// TestEventQueueFullAndClosed tests that a full or closed queue rejects events
+func TestEventQueueFullAndClosed(t *testing.T) {
+	release := make(chan struct{})
+	queue := api.NewEventQueue(config.WebhookQueueConfig{Workers: 1, Size: 1}, func(ctx context.Context, event *models.WebhookEvent) error {
+		<-release
+		return nil
+	})
+
+	// The first event is picked up by the worker, the second fills the buffer
+	require.NoError(t, queue.Enqueue(newQueueTestEvent("m1", 1)))
+	assert.Eventually(t, func() bool {
+		return queue.Enqueue(newQueueTestEvent("m1", 2)) == nil
+	}, time.Second, time.Millisecond)
+	assert.ErrorIs(t, queue.Enqueue(newQueueTestEvent("m1", 3)), api.ErrQueueFull)
+
+	close(release)
+	require.NoError(t, queue.Shutdown(context.Background()))
+	assert.ErrorIs(t, queue.Enqueue(newQueueTestEvent("m1", 4)), api.ErrQueueClosed)
+}
+
+// TestWebhookHandlerWithQueue tests that the handler acknowledges events and applies them asynchronously
+func TestWebhookHandlerWithQueue(t *testing.T) {
+	repo := memory.NewRepository()
+	handler := api.NewWebhookHandler(repo, service.NewMeetingService(repo))
+
+	release := make(chan struct{})
+	queue := api.NewEventQueue(config.WebhookQueueConfig{Workers: 1, Size: 1}, func(ctx context.Context, event *models.WebhookEvent) error {
+		<-release
+		return handler.ProcessEvent(ctx, event)
+	})
+	handler.SetQueue(queue)
+
+	send := func(meetingID string) *httptest.ResponseRecorder {
+		payload := fmt.Sprintf(`{"event": "meeting.created", "payload": {"object": {"uuid": "uuid-%s", "id": "%s", "topic": "Queued"}}, "event_ts": 1620123456789}`, meetingID, meetingID)
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Acknowledged before being applied
+	assert.Equal(t, http.StatusOK, send("q1").Code)
+	_, err := repo.GetMeeting(context.Background(), "q1")
+	assert.ErrorIs(t, err, memory.ErrNotFound)
+
+	// Fill the queue until it rejects an event
+	var rejected string
+	for i := 2; i < 10 && rejected == ""; i++ {
+		meetingID := fmt.Sprintf("q%d", i)
+		if send(meetingID).Code == http.StatusServiceUnavailable {
+			rejected = meetingID
+		}
+	}
+	require.NotEmpty(t, rejected, "A full queue should reject events")
+
+	// Drain the queue; the rejected event is accepted again on retry rather than treated as a duplicate
+	close(release)
+	require.NoError(t, queue.Shutdown(context.Background()))
+	handler.SetQueue(nil)
+	assert.Equal(t, http.StatusOK, send(rejected).Code)
+	assert.Equal(t, int64(0), handler.Stats().Duplicates)
+
+	meeting, err := repo.GetMeeting(context.Background(), "q1")
+	require.NoError(t, err)
+	assert.Equal(t, models.MeetingStatusCreated, meeting.Status)
+	_, err = repo.GetMeeting(context.Background(), rejected)
+	assert.NoError(t, err)
+}
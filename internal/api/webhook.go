@@ -10,20 +10,57 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/navikt/zrooms/internal/config"
 	"github.com/navikt/zrooms/internal/models"
 	"github.com/navikt/zrooms/internal/repository"
+	"github.com/navikt/zrooms/internal/service"
 	"github.com/navikt/zrooms/internal/web"
 )
 
-// WebhookHandler processes webhook events from Zoom
+const (
+	// defaultMaxRequestAge is the freshness window used when none is configured
+	defaultMaxRequestAge = 5 * time.Minute
+	// defaultDedupTTL is how long processed event keys are remembered when none is configured
+	defaultDedupTTL = 24 * time.Hour
+)
+
+// WebhookStats holds counters for rejected webhook requests
+type WebhookStats struct {
+	DisallowedSource int64
+	InvalidSignature int64
+	Stale            int64
+	Replayed         int64
+	Duplicates       int64
+	OutOfOrder       int64
+}
+
+// WebhookHandler receives webhook events from Zoom, verifies them and applies them through the meeting service
 type WebhookHandler struct {
 	repo           repository.Repository
 	meetingService web.MeetingServicer
-	secretToken    string
+	accounts       config.ZoomAccounts // Zoom accounts with their webhook secrets, default account first
+	maxRequestAge  time.Duration
+	dedupTTL       time.Duration
+	meetingTTL     time.Duration            // How long the repository keeps meetings after their last update, 0 for ever
+	queue          *EventQueue              // Optional; events are processed inline when nil
+	watchdog       *service.SilenceWatchdog // Optional; told about every valid event received
+	allowlist      *IPAllowlist             // Optional; requests from every source are verified when nil
+
+	// Counters for rejected requests
+	disallowedSourceCount atomic.Int64
+	invalidSignatureCount atomic.Int64
+	staleCount            atomic.Int64
+	replayedCount         atomic.Int64
+	duplicateCount        atomic.Int64
+	outOfOrderCount       atomic.Int64
+
+	// Recent webhook requests with their verification and processing outcome
+	deliveries *deliveryLog
 }
 
 // NewWebhookHandler creates a new webhook handler with the given repository and meeting service
@@ -32,17 +69,107 @@ func NewWebhookHandler(repo repository.Repository, meetingService web.MeetingSer
 	return &WebhookHandler{
 		repo:           repo,
 		meetingService: meetingService,
-		secretToken:    zoomConfig.WebhookSecretToken,
+		accounts:       zoomConfig.AllAccounts(),
+		maxRequestAge:  zoomConfig.WebhookMaxRequestAge,
+		dedupTTL:       zoomConfig.WebhookDedupTTL,
+		deliveries:     newDeliveryLog(zoomConfig.WebhookDeliveryLogSize),
 	}
 }
 
 // NewWebhookHandlerWithSecret creates a webhook handler with the given repository and secret token
 // This method is primarily used for testing webhook signature validation
 func NewWebhookHandlerWithSecret(repo repository.Repository, meetingService web.MeetingServicer, secretToken string) *WebhookHandler {
+	return NewWebhookHandlerWithAccounts(repo, meetingService, config.ZoomAccounts{
+		{Name: config.DefaultAccountName, WebhookSecrets: []config.WebhookSecret{{Name: "primary", Token: secretToken}}},
+	})
+}
+
+// NewWebhookHandlerWithAccounts creates a webhook handler for the given Zoom accounts, default account first.
+// Each account's events are verified with its own webhook secret.
+func NewWebhookHandlerWithAccounts(repo repository.Repository, meetingService web.MeetingServicer, accounts config.ZoomAccounts) *WebhookHandler {
 	return &WebhookHandler{
 		repo:           repo,
 		meetingService: meetingService,
-		secretToken:    secretToken,
+		accounts:       accounts,
+		maxRequestAge:  defaultMaxRequestAge,
+		dedupTTL:       defaultDedupTTL,
+		deliveries:     newDeliveryLog(defaultDeliveryLogSize),
+	}
+}
+
+// SetMaxRequestAge sets how old a request timestamp may be before the request is rejected as stale
+// A zero or negative value disables the freshness check
+func (h *WebhookHandler) SetMaxRequestAge(maxAge time.Duration) {
+	h.maxRequestAge = maxAge
+}
+
+// SetMeetingTTL sets how long the repository keeps meetings after their last update, so a rebuild
+// from the journal does not bring back meetings that had expired. Zero means meetings never expire.
+func (h *WebhookHandler) SetMeetingTTL(ttl time.Duration) {
+	h.meetingTTL = ttl
+}
+
+// SetQueue makes the handler enqueue verified events for asynchronous processing instead of applying them inline
+func (h *WebhookHandler) SetQueue(queue *EventQueue) {
+	h.queue = queue
+}
+
+// SetWatchdog makes the handler report every valid event it receives to the silence watchdog
+func (h *WebhookHandler) SetWatchdog(watchdog *service.SilenceWatchdog) {
+	h.watchdog = watchdog
+}
+
+// SetAllowlist makes the handler reject requests from source addresses outside the allowlist
+// before verifying their signature
+func (h *WebhookHandler) SetAllowlist(allowlist *IPAllowlist) {
+	h.allowlist = allowlist
+}
+
+// RejectedSources returns the source addresses requests were rejected from by the allowlist, most frequent first
+func (h *WebhookHandler) RejectedSources() []models.RejectedSource {
+	if h.allowlist == nil {
+		return nil
+	}
+	return h.allowlist.RejectedSources()
+}
+
+// RecentDeliveries returns the most recent webhook requests with their outcome, newest first
+func (h *WebhookHandler) RecentDeliveries() []models.WebhookDelivery {
+	return h.deliveries.recent()
+}
+
+// Delivery returns a recent webhook request by ID
+func (h *WebhookHandler) Delivery(id string) (models.WebhookDelivery, bool) {
+	return h.deliveries.get(id)
+}
+
+// RecentSecretMatches returns the most recent verified events with the webhook secret that matched, newest first
+func (h *WebhookHandler) RecentSecretMatches() []models.WebhookSecretMatch {
+	var matches []models.WebhookSecretMatch
+	for _, delivery := range h.deliveries.recent() {
+		if delivery.Signature != models.SignatureValid || delivery.Event == "" {
+			continue
+		}
+		matches = append(matches, models.WebhookSecretMatch{
+			ReceivedAt:  delivery.ReceivedAt,
+			Event:       delivery.Event,
+			MeetingID:   delivery.MeetingID,
+			AccountName: delivery.AccountName,
+			SecretName:  delivery.SecretName,
+		})
+	}
+	return matches
+}
+
+// Stats returns the current counters for rejected webhook requests
+func (h *WebhookHandler) Stats() WebhookStats {
+	return WebhookStats{
+		DisallowedSource: h.disallowedSourceCount.Load(),
+		InvalidSignature: h.invalidSignatureCount.Load(),
+		Stale:            h.staleCount.Load(),
+		Replayed:         h.replayedCount.Load(),
+		Duplicates:       h.duplicateCount.Load(),
+		OutOfOrder:       h.outOfOrderCount.Load(),
 	}
 }
 
@@ -54,17 +181,35 @@ func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Verify webhook signature if secret token is configured
-	if h.secretToken != "" {
-		if !h.verifyZoomWebhookSignature(r) {
-			log.Printf("Invalid webhook signature")
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	receivedAt := time.Now()
+
+	// Reject requests from sources outside the allowlist before doing any work for them
+	var sourceAddress string
+	if h.allowlist != nil {
+		source, err := h.allowlist.SourceAddress(r)
+		if err == nil && !h.allowlist.Allows(source) {
+			err = fmt.Errorf("source address %s not allowed", source)
+		}
+		sourceAddress = source.String() // "invalid IP" when the address could not be determined
+		if err != nil {
+			log.Printf("Rejected webhook request: %v", err)
+			h.disallowedSourceCount.Add(1)
+			h.allowlist.recordRejected(sourceAddress)
+			deliveryID := h.deliveries.add(models.WebhookDelivery{
+				ReceivedAt:    receivedAt,
+				SourceAddress: sourceAddress,
+				Signature:     models.SignatureNotChecked,
+			})
+			h.deliveries.complete(deliveryID, models.DeliveryRejected, err.Error())
+			http.Error(w, "Forbidden", http.StatusForbidden)
 			return
 		}
-	} else {
-		log.Printf("Warning: Webhook verification disabled - ZOOM_WEBHOOK_SECRET_TOKEN not set")
 	}
 
+	// Create a context with timeout for database operations
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
 	// Limit request body size to prevent abuse
 	body, err := io.ReadAll(io.LimitReader(r.Body, 1048576)) // 1MB limit
 	if err != nil {
@@ -74,17 +219,73 @@ func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	// Parse the webhook event
+	// Parse the webhook event; the body is only trusted once the signature has been verified,
+	// but the event name and meeting ID are recorded for every delivery
 	var event models.WebhookEvent
-	if err := json.Unmarshal(body, &event); err != nil {
-		log.Printf("Error parsing webhook JSON: %v", err)
+	parseErr := json.Unmarshal(body, &event)
+	deliveryID := h.deliveries.add(models.WebhookDelivery{
+		ReceivedAt:    receivedAt,
+		SourceAddress: sourceAddress,
+		Event:         event.Event,
+		MeetingID:     h.meetingKey(&event),
+		Signature:     models.SignatureNotChecked,
+		Payload:       models.RedactPayload(body),
+	})
+
+	// Verify webhook signature if a secret token is configured for any account
+	account := h.accounts.ByID("")
+	if h.verificationEnabled() {
+		var secret config.WebhookSecret
+		var verified bool
+		account, secret, verified = h.verifyZoomWebhookSignature(r, body)
+		h.deliveries.update(deliveryID, func(delivery *models.WebhookDelivery) {
+			delivery.Signature = models.SignatureInvalid
+			if verified {
+				delivery.Signature = models.SignatureValid
+				delivery.AccountName = account.Name
+				delivery.SecretName = secret.Name
+			}
+		})
+		if !verified {
+			log.Printf("Invalid webhook signature")
+			h.invalidSignatureCount.Add(1)
+			h.deliveries.complete(deliveryID, models.DeliveryRejected, "invalid signature")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		// Reject requests whose signed timestamp is outside the freshness window
+		if !h.isFreshRequest(r) {
+			log.Printf("Rejected stale webhook request: timestamp=%s", r.Header.Get("x-zm-request-timestamp"))
+			h.staleCount.Add(1)
+			h.deliveries.complete(deliveryID, models.DeliveryRejected, "request timestamp outside allowed window")
+			http.Error(w, "Request timestamp outside allowed window", http.StatusUnauthorized)
+			return
+		}
+
+		// Reject exact replays of a request we have already accepted
+		if h.isReplayedRequest(ctx, r) {
+			log.Printf("Rejected replayed webhook request")
+			h.replayedCount.Add(1)
+			h.deliveries.complete(deliveryID, models.DeliveryRejected, "request already processed")
+			http.Error(w, "Request already processed", http.StatusConflict)
+			return
+		}
+	} else {
+		log.Printf("Warning: Webhook verification disabled - ZOOM_WEBHOOK_SECRET_TOKEN not set")
+	}
+
+	if parseErr != nil {
+		log.Printf("Error parsing webhook JSON: %v", parseErr)
+		h.deliveries.complete(deliveryID, models.DeliveryMalformed, parseErr.Error())
 		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
 		return
 	}
 
-	// Create a context with timeout for database operations
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-	defer cancel()
+	// Any verified event, including retries and URL validation, shows that Zoom still delivers events
+	if h.watchdog != nil {
+		h.watchdog.RecordEvent(receivedAt)
+	}
 
 	// Handle Zoom URL validation challenge response
 	if event.Event == "endpoint.url_validation" {
@@ -108,8 +309,9 @@ func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// Generate the hash response using HMAC SHA-256
-		hash := hmac.New(sha256.New, []byte(h.secretToken))
+		// Generate the hash response using HMAC SHA-256, with the primary secret of the account that signed
+		// the request, so validating the endpoint in Zoom succeeds while a previous secret is still accepted
+		hash := hmac.New(sha256.New, []byte(account.PrimaryWebhookSecret()))
 		hash.Write([]byte(validationPayload.PlainToken))
 		encryptedToken := hex.EncodeToString(hash.Sum(nil))
 
@@ -130,269 +332,272 @@ func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 		// Write the response directly
 		w.Write(responseData)
+		h.deliveries.complete(deliveryID, models.DeliveryURLValidation, "")
 
 		log.Printf("Successfully responded to Zoom URL validation challenge")
 		return
 	}
 
-	// Process the event based on its type
-	switch event.Event {
-	case "meeting.started":
-		h.handleMeetingStarted(ctx, &event)
-	case "meeting.ended":
-		h.handleMeetingEnded(ctx, &event)
-	case "meeting.updated":
-		h.handleMeetingUpdated(ctx, &event)
-	case "meeting.participant_joined":
-		h.handleParticipantJoined(ctx, &event)
-	case "meeting.participant_left":
-		h.handleParticipantLeft(ctx, &event)
-	default:
-		// Log unsupported event type but return OK
-		log.Printf("Unsupported webhook event type: %s", event.Event)
-	}
-
-	// Always return success to Zoom
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, `{"success": true}`)
-}
-
-// verifyZoomWebhookSignature validates that the request is actually from Zoom
-// using the approach specified in Zoom's webhook documentation.
-// It verifies the x-zm-signature header against an HMAC-SHA256 hash of the timestamp and request body
-// using the configured webhook secret token.
-func (h *WebhookHandler) verifyZoomWebhookSignature(r *http.Request) bool {
-	// Get the signature from the header
-	signatureHeader := r.Header.Get("x-zm-signature")
-	if signatureHeader == "" {
-		log.Printf("Missing x-zm-signature header")
-		return false
+	// Acknowledge retried deliveries of an event we have already processed without applying it again
+	dedupKey, duplicate := h.markEventSeen(ctx, &event)
+	if duplicate {
+		log.Printf("Duplicate webhook event ignored: %s", event.Event)
+		h.duplicateCount.Add(1)
+		h.deliveries.complete(deliveryID, models.DeliveryDuplicate, "")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"success": true, "duplicate": true}`)
+		return
 	}
 
-	// Parse the signature format (should be v0=HASH)
-	parts := strings.SplitN(signatureHeader, "=", 2)
-	if len(parts) != 2 || parts[0] != "v0" {
-		log.Printf("Invalid signature format: %s", signatureHeader)
-		return false
+	// Record the accepted event in the journal before applying it, so state can be rebuilt later.
+	// Personal data is left out, as replay only needs the IDs of meetings and participants.
+	journaled := event
+	journaled.Payload = models.StripPayload(event.Payload)
+	if err := h.repo.AppendEvent(ctx, &journaled); err != nil {
+		log.Printf("Error writing webhook event %s to journal: %v", event.Event, err)
+		h.forgetEvent(ctx, dedupKey)
+		h.deliveries.complete(deliveryID, models.DeliveryFailed, fmt.Sprintf("writing to journal: %v", err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
-	receivedSignature := parts[1]
 
-	// Get the timestamp from the header
-	timestamp := r.Header.Get("x-zm-request-timestamp")
-	if timestamp == "" {
-		log.Printf("Missing x-zm-request-timestamp header")
-		return false
-	}
+	// Set after journaling, so events replayed from the journal are not attributed to this delivery
+	event.DeliveryID = deliveryID
 
-	// Read the request body for verification
-	var body []byte
-	var err error
-	if r.Body != nil {
-		// Create a new buffer to store the body content
-		body, err = io.ReadAll(r.Body)
-		if err != nil {
-			log.Printf("Error reading request body for signature verification: %v", err)
-			return false
+	if h.queue != nil {
+		// Hand the event to the worker pool and acknowledge right away. The delivery is marked
+		// as queued first, as a worker may apply the event before Enqueue returns.
+		h.deliveries.complete(deliveryID, models.DeliveryQueued, "")
+		if err := h.queue.Enqueue(&event); err != nil {
+			log.Printf("Error enqueuing webhook event %s: %v", event.Event, err)
+			h.forgetEvent(ctx, dedupKey)
+			h.deliveries.complete(deliveryID, models.DeliveryFailed, fmt.Sprintf("enqueuing: %v", err))
+			http.Error(w, "Service temporarily unavailable", http.StatusServiceUnavailable)
+			return
 		}
-
-		// Restore the body so it can be read again
-		r.Body = io.NopCloser(strings.NewReader(string(body)))
+	} else if err := h.ProcessEvent(ctx, &event); err != nil {
+		// Without a queue there is no retry, so keep the event for an admin and still acknowledge it
+		log.Printf("Error processing webhook event %s: %v", event.Event, err)
+		h.DeadLetterEvent(&event, err, 1)
 	}
 
-	// Construct the message string: v0:timestamp:body
-	message := fmt.Sprintf("v0:%s:%s", timestamp, string(body))
-
-	// Calculate the expected signature using HMAC-SHA256
-	mac := hmac.New(sha256.New, []byte(h.secretToken))
-	mac.Write([]byte(message))
-	computedHash := mac.Sum(nil)
-	computedHex := hex.EncodeToString(computedHash)
-
-	// Compare the computed signature with the received signature
-	expectedSignature := computedHex
-
-	// Direct comparison of hex-encoded signatures
-	return hmac.Equal([]byte(expectedSignature), []byte(receivedSignature))
+	// Always return success to Zoom
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, `{"success": true}`)
 }
 
-// clearMeetingParticipants removes all participants from a meeting
-func (h *WebhookHandler) clearMeetingParticipants(ctx context.Context, meetingID string) error {
-	// Get the meeting to access participant IDs
-	err := h.repo.ClearPartipantsInMeeting(ctx, meetingID)
+// ProcessEvent applies a single verified webhook event through the meeting service.
+// Errors wrapping ErrInvalidEvent mean the event can never be applied and should not be retried.
+func (h *WebhookHandler) ProcessEvent(ctx context.Context, event *models.WebhookEvent) error {
+	result, err := h.meetingService.ApplyEvent(ctx, event)
+	h.recordProcessing(event, result, err)
 	if err != nil {
-		log.Printf("Error clearing meeting to clear participants: %s", err)
 		return err
 	}
-	return nil
-}
 
-// handleMeetingStarted processes a meeting.started event
-func (h *WebhookHandler) handleMeetingStarted(ctx context.Context, event *models.WebhookEvent) {
-	meeting := event.ProcessMeetingStarted()
-	if meeting == nil {
-		log.Printf("Failed to process meeting.started event")
-		return
+	if result.Outcome == service.EventOutOfOrder {
+		h.outOfOrderCount.Add(1)
 	}
+	return nil
+}
 
-	log.Printf("Meeting started: ID=%s", meeting.ID)
-
-	// Parse the standard event payload to access object properties
-	var payload models.StandardEventPayload
-	if err := json.Unmarshal(event.Payload, &payload); err != nil {
-		log.Printf("Error parsing payload for meeting started event: %v", err)
+// recordProcessing records the result of applying an event on the delivery it was received in
+func (h *WebhookHandler) recordProcessing(event *models.WebhookEvent, result service.ApplyResult, err error) {
+	if event.DeliveryID == "" {
 		return
 	}
 
-	// Explicitly ensure the topic is set (fix for failing test)
-	if meeting.Topic == "" && payload.Object.Topic != "" {
-		meeting.Topic = payload.Object.Topic
-	}
-
-	// Save the meeting first to ensure it exists in the repository
-	if err := h.repo.SaveMeeting(ctx, meeting); err != nil {
-		log.Printf("Error saving meeting: %v", err)
+	outcome := models.DeliveryOutcome(result.Outcome)
+	detail := ""
+	if err != nil {
+		outcome = models.DeliveryFailed
+		detail = err.Error()
 	}
 
-	// Notify meeting service about the started meeting
-	if h.meetingService != nil {
-		h.meetingService.NotifyMeetingStarted(meeting)
-	}
+	h.deliveries.update(event.DeliveryID, func(delivery *models.WebhookDelivery) {
+		delivery.Attempts++
+	})
+	h.deliveries.complete(event.DeliveryID, outcome, detail)
 }
 
-func (h *WebhookHandler) handleMeetingUpdated(ctx context.Context, event *models.WebhookEvent) {
-	meeting := event.ProcessMeetingUpdated()
-	if meeting == nil {
-		log.Printf("Failed to process meeting.updated event")
+// DeadLetterEvent stores an event that could not be applied, so an admin can replay or discard it
+func (h *WebhookHandler) DeadLetterEvent(event *models.WebhookEvent, err error, attempts int) {
+	ctx, cancel := context.WithTimeout(context.Background(), eventTimeout)
+	defer cancel()
+
+	deadLetter := models.NewDeadLetter(*event, err.Error(), attempts)
+	if saveErr := h.repo.SaveDeadLetter(ctx, deadLetter); saveErr != nil {
+		log.Printf("Error storing dead letter for webhook event %s: %v", event.Event, saveErr)
 		return
 	}
 
-	log.Printf("Meeting updated: ID=%s", meeting.ID)
+	log.Printf("Stored webhook event %s for meeting %s as dead letter %s", event.Event, event.MeetingID(), deadLetter.ID)
+}
 
-	// Parse the standard event payload to access object properties
-	var payload models.StandardEventPayload
-	if err := json.Unmarshal(event.Payload, &payload); err != nil {
-		log.Printf("Error parsing payload for meeting updated event: %v", err)
-		return
+// verificationEnabled reports whether a webhook secret token is configured for any account
+func (h *WebhookHandler) verificationEnabled() bool {
+	// Expired secrets still count, so verification keeps rejecting requests once every secret has expired
+	for _, account := range h.accounts {
+		if len(account.WebhookSecrets) > 0 {
+			return true
+		}
 	}
+	return false
+}
 
-	// Explicitly ensure the topic is set (fix for failing test)
-	if meeting.Topic == "" && payload.Object.Topic != "" {
-		meeting.Topic = payload.Object.Topic
+// signingCandidates returns the accounts whose webhook secret may have signed the request body.
+// Events can only be signed by the account they are stored under, so events naming no configured account
+// can only be signed by the default account. URL validation requests change no state and carry no
+// account, so they may be signed by any account with a secret.
+func (h *WebhookHandler) signingCandidates(body []byte) config.ZoomAccounts {
+	var envelope struct {
+		Event   string `json:"event"`
+		Payload struct {
+			AccountID string `json:"account_id"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.Event != "endpoint.url_validation" {
+		return config.ZoomAccounts{h.accounts.ByID(envelope.Payload.AccountID)}
+	}
+
+	candidates := make(config.ZoomAccounts, 0, len(h.accounts))
+	for _, account := range h.accounts {
+		if len(account.WebhookSecrets) > 0 {
+			candidates = append(candidates, account)
+		}
 	}
+	return candidates
+}
 
-	// Save the meeting first to ensure it exists in the repository
-	if err := h.repo.SaveMeeting(ctx, meeting); err != nil {
-		log.Printf("Error saving meeting: %v", err)
+// verifyZoomWebhookSignature validates that the request is actually from Zoom
+// using the approach specified in Zoom's webhook documentation.
+// It verifies the x-zm-signature header against an HMAC-SHA256 hash of the timestamp and request body
+// using each active webhook secret token of the account the event is for, and returns that account
+// together with the secret that matched.
+func (h *WebhookHandler) verifyZoomWebhookSignature(r *http.Request, body []byte) (config.ZoomAccount, config.WebhookSecret, bool) {
+	// Get the signature from the header
+	signatureHeader := r.Header.Get("x-zm-signature")
+	if signatureHeader == "" {
+		log.Printf("Missing x-zm-signature header")
+		return config.ZoomAccount{}, config.WebhookSecret{}, false
 	}
 
-	// Notify meeting service about the updated meeting
-	if h.meetingService != nil {
-		h.meetingService.NotifyMeetingUpdated(meeting)
+	// Parse the signature format (should be v0=HASH)
+	parts := strings.SplitN(signatureHeader, "=", 2)
+	if len(parts) != 2 || parts[0] != "v0" {
+		log.Printf("Invalid signature format: %s", signatureHeader)
+		return config.ZoomAccount{}, config.WebhookSecret{}, false
 	}
-}
+	receivedSignature := parts[1]
 
-// handleMeetingEnded processes a meeting.ended event
-func (h *WebhookHandler) handleMeetingEnded(ctx context.Context, event *models.WebhookEvent) {
-	meeting := event.ProcessMeetingEnded()
-	if meeting == nil {
-		log.Printf("Failed to process meeting.ended event")
-		return
+	// Get the timestamp from the header
+	timestamp := r.Header.Get("x-zm-request-timestamp")
+	if timestamp == "" {
+		log.Printf("Missing x-zm-request-timestamp header")
+		return config.ZoomAccount{}, config.WebhookSecret{}, false
 	}
 
-	// Parse the standard event payload to access object properties
-	var payload models.StandardEventPayload
-	if err := json.Unmarshal(event.Payload, &payload); err != nil {
-		log.Printf("Error parsing payload for meeting ended event: %v", err)
-		return
-	}
+	// Construct the message string: v0:timestamp:body
+	message := fmt.Sprintf("v0:%s:%s", timestamp, string(body))
 
-	// Get existing meeting to preserve important details
-	existingMeeting, err := h.repo.GetMeeting(ctx, meeting.ID)
-	if err == nil {
-		// Keep topic from existing meeting if it's not set in the new one
-		if meeting.Topic == "" {
-			if existingMeeting.Topic != "" {
-				meeting.Topic = existingMeeting.Topic
-			} else if payload.Object.Topic != "" {
-				meeting.Topic = payload.Object.Topic
+	now := time.Now()
+	for _, account := range h.signingCandidates(body) {
+		for _, secret := range account.ActiveWebhookSecrets(now) {
+			// Calculate the expected signature using HMAC-SHA256
+			mac := hmac.New(sha256.New, []byte(secret.Token))
+			mac.Write([]byte(message))
+			expectedSignature := hex.EncodeToString(mac.Sum(nil))
+
+			// Direct comparison of hex-encoded signatures
+			if hmac.Equal([]byte(expectedSignature), []byte(receivedSignature)) {
+				return account, secret, true
 			}
 		}
-		// Preserve operator email from existing meeting if not set in the ended event
-		if meeting.OperatorEmail == "" && existingMeeting.OperatorEmail != "" {
-			meeting.OperatorEmail = existingMeeting.OperatorEmail
-		}
 	}
 
-	// Clear all participants when a meeting ends
-	h.clearMeetingParticipants(ctx, meeting.ID)
+	return config.ZoomAccount{}, config.WebhookSecret{}, false
+}
 
-	log.Printf("Meeting ended: ID=%s", meeting.ID)
-	if err := h.repo.SaveMeeting(ctx, meeting); err != nil {
-		log.Printf("Error updating meeting: %v", err)
+// meetingKey returns the ID the meeting an event refers to is stored under, namespaced by account
+func (h *WebhookHandler) meetingKey(event *models.WebhookEvent) string {
+	if event.MeetingID() == "" {
+		return ""
 	}
+	return models.MeetingKey(h.accounts.ByID(event.AccountID()).ID, event.MeetingID())
+}
 
-	// Notify meeting service about the ended meeting
-	if h.meetingService != nil {
-		h.meetingService.NotifyMeetingEnded(meeting)
+// isFreshRequest checks that the x-zm-request-timestamp header is within the configured freshness window.
+// The timestamp is covered by the signature, so it cannot be altered without invalidating the request.
+func (h *WebhookHandler) isFreshRequest(r *http.Request) bool {
+	if h.maxRequestAge <= 0 {
+		return true
 	}
-}
 
-// handleParticipantJoined processes a meeting.participant_joined event
-func (h *WebhookHandler) handleParticipantJoined(ctx context.Context, event *models.WebhookEvent) {
-	participant := event.ProcessParticipantJoined()
-	if participant == nil {
-		log.Printf("Failed to process participant_joined event")
-		return
+	timestamp, err := strconv.ParseInt(r.Header.Get("x-zm-request-timestamp"), 10, 64)
+	if err != nil {
+		log.Printf("Invalid x-zm-request-timestamp header: %v", err)
+		return false
 	}
 
-	// Parse the standard event payload to access object properties
-	var payload models.StandardEventPayload
-	if err := json.Unmarshal(event.Payload, &payload); err != nil {
-		log.Printf("Error parsing payload for participant joined event: %v", err)
-		return
+	age := time.Since(time.Unix(timestamp, 0))
+	if age < 0 {
+		age = -age // Allow for the same amount of clock skew into the future
 	}
 
-	meetingID := payload.Object.ID
-	participantID := participant.ID
+	return age <= h.maxRequestAge
+}
 
-	// Only store the participant ID to avoid storing PII
-	log.Printf("Participant joined: MeetingID=%s, ParticipantID=%s", meetingID, participantID)
-	if err := h.repo.AddParticipantToMeeting(ctx, meetingID, participantID); err != nil {
-		log.Printf("Error adding participant: %v", err)
+// isReplayedRequest records the request signature and reports whether it has been seen before.
+// Signatures are remembered for twice the freshness window, after which replays are rejected as stale anyway.
+func (h *WebhookHandler) isReplayedRequest(ctx context.Context, r *http.Request) bool {
+	ttl := 2 * h.maxRequestAge
+	if ttl <= 0 {
+		ttl = 2 * defaultMaxRequestAge
 	}
 
-	// Notify meeting service about the participant joined
-	if h.meetingService != nil {
-		h.meetingService.NotifyParticipantJoined(meetingID, participantID)
+	firstSeen, err := h.repo.MarkWebhookSeen(ctx, "signature:"+r.Header.Get("x-zm-signature"), ttl)
+	if err != nil {
+		// Fail open: the signature is valid and fresh, so losing the replay store should not drop events
+		log.Printf("Error checking webhook replay store: %v", err)
+		return false
 	}
+
+	return !firstSeen
 }
 
-// handleParticipantLeft processes a meeting.participant_left event
-func (h *WebhookHandler) handleParticipantLeft(ctx context.Context, event *models.WebhookEvent) {
-	participant := event.ProcessParticipantLeft()
-	if participant == nil {
-		log.Printf("Failed to process participant_left event")
-		return
+// markEventSeen records the event's idempotency key and reports whether the event has been seen before.
+// The recorded key is returned so it can be removed again if the event cannot be accepted.
+func (h *WebhookHandler) markEventSeen(ctx context.Context, event *models.WebhookEvent) (string, bool) {
+	key := event.IdempotencyKey()
+	if key == "" {
+		return "", false
 	}
+	key = "event:" + key
 
-	// Parse the standard event payload to access object properties
-	var payload models.StandardEventPayload
-	if err := json.Unmarshal(event.Payload, &payload); err != nil {
-		log.Printf("Error parsing payload for participant left event: %v", err)
-		return
+	ttl := h.dedupTTL
+	if ttl <= 0 {
+		ttl = defaultDedupTTL
 	}
 
-	meetingID := payload.Object.ID
-	participantID := participant.ID
-
-	log.Printf("Participant left: MeetingID=%s, ParticipantID=%s", meetingID, participantID)
-	if err := h.repo.RemoveParticipantFromMeeting(ctx, meetingID, participantID); err != nil {
-		log.Printf("Error removing participant: %v", err)
+	firstSeen, err := h.repo.MarkWebhookSeen(ctx, key, ttl)
+	if err != nil {
+		// Fail open: processing an event twice is better than losing it
+		log.Printf("Error checking webhook event deduplication store: %v", err)
+		return "", false
 	}
 
-	// Notify meeting service about the participant left
-	if h.meetingService != nil {
-		h.meetingService.NotifyParticipantLeft(meetingID, participantID)
+	return key, !firstSeen
+}
+
+// forgetEvent removes an event from the deduplication store so Zoom's retry of a
+// delivery we could not accept is not dropped as a duplicate
+func (h *WebhookHandler) forgetEvent(ctx context.Context, dedupKey string) {
+	if dedupKey == "" {
+		return
+	}
+	if err := h.repo.UnmarkWebhookSeen(ctx, dedupKey); err != nil {
+		log.Printf("Error removing webhook event from deduplication store: %v", err)
 	}
 }
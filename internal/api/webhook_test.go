@@ -14,11 +14,13 @@ import (
 	"time"
 
 	"github.com/navikt/zrooms/internal/api"
+	"github.com/navikt/zrooms/internal/config"
 	"github.com/navikt/zrooms/internal/models"
 	"github.com/navikt/zrooms/internal/repository/memory"
 	"github.com/navikt/zrooms/internal/service"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockMeetingService is a mock implementation of the MeetingServicer interface for testing
@@ -31,24 +33,9 @@ func (m *MockMeetingService) GetMeetingStatusData(ctx context.Context, includeEn
 	return args.Get(0).([]service.MeetingStatusData), args.Error(1)
 }
 
-func (m *MockMeetingService) NotifyMeetingStarted(meeting *models.Meeting) {
-	m.Called(meeting)
-}
-
-func (m *MockMeetingService) NotifyMeetingEnded(meeting *models.Meeting) {
-	m.Called(meeting)
-}
-
-func (m *MockMeetingService) NotifyMeetingUpdated(meeting *models.Meeting) {
-	m.Called(meeting)
-}
-
-func (m *MockMeetingService) NotifyParticipantJoined(meetingID string, participantID string) {
-	m.Called(meetingID, participantID)
-}
-
-func (m *MockMeetingService) NotifyParticipantLeft(meetingID string, participantID string) {
-	m.Called(meetingID, participantID)
+func (m *MockMeetingService) ApplyEvent(ctx context.Context, event *models.WebhookEvent) (service.ApplyResult, error) {
+	args := m.Called(ctx, event)
+	return args.Get(0).(service.ApplyResult), args.Error(1)
 }
 
 // TestWebhookSignatureValidation tests the webhook signature validation functionality
@@ -165,6 +152,61 @@ func TestWebhookHandler(t *testing.T) {
 		expectedStatusCode int
 		validateFunc       func(t *testing.T, repo *memory.Repository)
 	}{
+		{
+			name: "Meeting Created Event",
+			webhookPayload: `{
+				"event": "meeting.created",
+				"payload": {
+					"account_id": "abc123",
+					"object": {
+						"uuid": "uuid456",
+						"id": "555666777",
+						"host_id": "host123",
+						"topic": "Planned Meeting",
+						"type": 2,
+						"start_time": "2023-05-09T09:00:00Z",
+						"duration": 45,
+						"timezone": "UTC"
+					},
+					"operator": "planner@example.com"
+				},
+				"event_ts": 1620123400000
+			}`,
+			expectedStatusCode: http.StatusOK,
+			validateFunc: func(t *testing.T, repo *memory.Repository) {
+				// Verify meeting was saved as scheduled with its planned start and duration
+				meeting, err := repo.GetMeeting(ctx, "555666777")
+				assert.NoError(t, err)
+				assert.Equal(t, models.MeetingStatusCreated, meeting.Status)
+				assert.Equal(t, "Planned Meeting", meeting.Topic)
+				assert.Equal(t, time.Date(2023, 5, 9, 9, 0, 0, 0, time.UTC), meeting.StartTime.UTC())
+				assert.Equal(t, 45, meeting.Duration)
+				assert.Equal(t, "planner@example.com", meeting.OperatorEmail)
+			},
+		},
+		{
+			name: "Meeting Deleted Event",
+			webhookPayload: `{
+				"event": "meeting.deleted",
+				"payload": {
+					"account_id": "abc123",
+					"object": {
+						"uuid": "uuid456",
+						"id": "555666777",
+						"host_id": "host123",
+						"topic": "Planned Meeting",
+						"type": 2
+					}
+				},
+				"event_ts": 1620123410000
+			}`,
+			expectedStatusCode: http.StatusOK,
+			validateFunc: func(t *testing.T, repo *memory.Repository) {
+				// Verify meeting was removed from the repository
+				_, err := repo.GetMeeting(ctx, "555666777")
+				assert.ErrorIs(t, err, memory.ErrNotFound)
+			},
+		},
 		{
 			name: "Meeting Started Event",
 			webhookPayload: `{
@@ -356,7 +398,7 @@ func TestWebhookURLValidation(t *testing.T) {
 
 	// Set up the webhook secret token for testing
 	secretToken := "webhook_secret_token"
-	timestamp := "1739923528"
+	timestamp := fmt.Sprintf("%d", time.Now().Unix())
 
 	// Create a test request
 	req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(validationPayload))
@@ -400,138 +442,878 @@ func TestWebhookURLValidation(t *testing.T) {
 	assert.Equal(t, expectedToken, response["encryptedToken"], "encryptedToken should be correctly calculated")
 }
 
-// TestWebhookHandlerNotifiesService tests that the webhook handler calls the appropriate service methods
-func TestWebhookHandlerNotifiesService(t *testing.T) {
-	// Initialize repository
+// signWebhookRequest sets valid Zoom signature headers on the request for the given timestamp
+func signWebhookRequest(req *http.Request, payload string, secretToken string, timestamp time.Time) {
+	ts := fmt.Sprintf("%d", timestamp.Unix())
+	mac := hmac.New(sha256.New, []byte(secretToken))
+	mac.Write([]byte(fmt.Sprintf("v0:%s:%s", ts, payload)))
+	req.Header.Set("x-zm-request-timestamp", ts)
+	req.Header.Set("x-zm-signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+}
+
+// TestWebhookReplayProtection tests that stale and replayed signed requests are rejected
+func TestWebhookReplayProtection(t *testing.T) {
 	repo := memory.NewRepository()
-	// Initialize mock meeting service
 	mockService := new(MockMeetingService)
+	mockService.On("ApplyEvent", mock.Anything, mock.Anything).Return(service.ApplyResult{Outcome: service.EventApplied}, nil)
+
+	secretToken := "test_secret_token"
+	payload := `{"event": "meeting.started", "payload": {"account_id": "abc123", "object": {"id": "555", "topic": "Replay Test"}}}`
+
+	handler := api.NewWebhookHandlerWithSecret(repo, mockService, secretToken)
+	handler.SetMaxRequestAge(5 * time.Minute)
+
+	send := func(timestamp time.Time) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		signWebhookRequest(req, payload, secretToken, timestamp)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("StaleRequestRejected", func(t *testing.T) {
+		rr := send(time.Now().Add(-10 * time.Minute))
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, int64(1), handler.Stats().Stale)
+	})
+
+	t.Run("FutureRequestRejected", func(t *testing.T) {
+		rr := send(time.Now().Add(10 * time.Minute))
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Equal(t, int64(2), handler.Stats().Stale)
+	})
+
+	fresh := time.Now()
+
+	t.Run("FreshRequestAccepted", func(t *testing.T) {
+		rr := send(fresh)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockService.AssertNumberOfCalls(t, "ApplyEvent", 1)
+	})
+
+	t.Run("ReplayedRequestRejected", func(t *testing.T) {
+		rr := send(fresh)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, int64(1), handler.Stats().Replayed)
+		mockService.AssertNumberOfCalls(t, "ApplyEvent", 1)
+	})
+}
+
+// TestWebhookDuplicateEvents tests that retried deliveries of an event are acknowledged but not processed again
+func TestWebhookDuplicateEvents(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	updates := 0
+	meetingService.RegisterUpdateCallback(func(*models.Meeting) { updates++ })
+
+	started := `{"event": "meeting.started", "payload": {"object": {"uuid": "uuid777", "id": "777", "topic": "Dedup Test"}}, "event_ts": 1620123456789}`
+	joined := `{"event": "meeting.participant_joined", "payload": {"object": {"uuid": "uuid777", "id": "777", "participant": {"id": "part123"}}}, "event_ts": 1620123456999}`
+
+	handler := api.NewWebhookHandler(repo, meetingService)
+
+	send := func(payload string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for i := 0; i < 2; i++ {
+		rr := send(started)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		rr = send(joined)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	// The retried deliveries are acknowledged but each event is only applied once
+	assert.Equal(t, 2, updates)
+	assert.Equal(t, int64(2), handler.Stats().Duplicates)
+
+	count, err := repo.CountParticipantsInMeeting(context.Background(), "777")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+// TestWebhookOutOfOrderEvents tests that late deliveries do not override newer meeting state
+func TestWebhookOutOfOrderEvents(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	handler := api.NewWebhookHandler(repo, meetingService)
 	ctx := context.Background()
 
-	// Sample meeting
-	meetingID := "123456789"
-	existingMeeting := &models.Meeting{
-		ID:        meetingID,
-		Topic:     "Test Meeting",
-		Status:    models.MeetingStatusStarted,
-		StartTime: time.Now(),
+	send := func(payload string) {
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
 	}
-	_ = repo.SaveMeeting(ctx, existingMeeting)
 
-	// Setup mock expectations
-	mockService.On("NotifyMeetingStarted", mock.Anything).Return()
-	mockService.On("NotifyMeetingEnded", mock.Anything).Return()
-	mockService.On("NotifyParticipantJoined", meetingID, "part123").Return()
-	mockService.On("NotifyParticipantLeft", meetingID, "part123").Return()
+	// meeting.ended is delivered before the meeting.started that preceded it
+	send(`{"event": "meeting.ended", "payload": {"object": {"uuid": "uuid888", "id": "888", "topic": "Late Start"}}, "event_ts": 1620000600000}`)
+	send(`{"event": "meeting.started", "payload": {"object": {"uuid": "uuid888", "id": "888", "topic": "Late Start"}}, "event_ts": 1620000000000}`)
 
-	// Test cases for notifications
-	tests := []struct {
-		name           string
-		webhookPayload string
-		verify         func(t *testing.T, mockService *MockMeetingService)
-	}{
-		{
-			name: "Meeting Started Event Notification",
-			webhookPayload: `{
-				"event": "meeting.started",
-				"payload": {
-					"account_id": "abc123",
-					"object": {
-						"uuid": "uuid123",
-						"id": "987654321",
-						"host_id": "host123",
-						"topic": "Test Meeting",
-						"type": 2,
-						"start_time": "2025-05-08T15:00:00Z"
-					}
-				}
-			}`,
-			verify: func(t *testing.T, mockService *MockMeetingService) {
-				mockService.AssertCalled(t, "NotifyMeetingStarted", mock.Anything)
-			},
-		},
-		{
-			name: "Meeting Ended Event Notification",
-			webhookPayload: `{
-				"event": "meeting.ended",
-				"payload": {
-					"account_id": "abc123",
-					"object": {
-						"uuid": "uuid123",
-						"id": "123456789",
-						"host_id": "host123",
-						"topic": "Test Meeting"
-					}
-				}
-			}`,
-			verify: func(t *testing.T, mockService *MockMeetingService) {
-				mockService.AssertCalled(t, "NotifyMeetingEnded", mock.Anything)
-			},
-		},
-		{
-			name: "Participant Joined Event Notification",
-			webhookPayload: `{
-				"event": "meeting.participant_joined",
-				"payload": {
-					"account_id": "abc123",
-					"object": {
-						"uuid": "uuid123",
-						"id": "123456789",
-						"host_id": "host123",
-						"participant": {
-							"id": "part123",
-							"user_id": "user123",
-							"user_name": "Test User"
-						}
-					}
-				}
-			}`,
-			verify: func(t *testing.T, mockService *MockMeetingService) {
-				mockService.AssertCalled(t, "NotifyParticipantJoined", meetingID, "part123")
-			},
-		},
-		{
-			name: "Participant Left Event Notification",
-			webhookPayload: `{
-				"event": "meeting.participant_left",
-				"payload": {
-					"account_id": "abc123",
-					"object": {
-						"uuid": "uuid123",
-						"id": "123456789",
-						"host_id": "host123",
-						"participant": {
-							"id": "part123",
-							"user_id": "user123",
-							"user_name": "Test User"
-						}
-					}
-				}
-			}`,
-			verify: func(t *testing.T, mockService *MockMeetingService) {
-				mockService.AssertCalled(t, "NotifyParticipantLeft", meetingID, "part123")
-			},
-		},
+	meeting, err := repo.GetMeeting(ctx, "888")
+	assert.NoError(t, err)
+	assert.Equal(t, models.MeetingStatusEnded, meeting.Status, "Late start must not revive an ended meeting")
+	assert.Equal(t, time.UnixMilli(1620000000000), meeting.StartTime, "Start time should be merged from the late event")
+	assert.Equal(t, time.UnixMilli(1620000600000), meeting.EndTime)
+	assert.Equal(t, int64(1620000600000), meeting.LastEventTS)
+
+	// A join that happened before the end but arrives late is ignored
+	send(`{"event": "meeting.participant_joined", "payload": {"object": {"uuid": "uuid888", "id": "888", "participant": {"id": "part1"}}}, "event_ts": 1620000300000}`)
+	count, err := repo.CountParticipantsInMeeting(ctx, "888")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	assert.Equal(t, int64(2), handler.Stats().OutOfOrder)
+
+	// A newer start (next occurrence of the meeting) is applied
+	send(`{"event": "meeting.started", "payload": {"object": {"uuid": "uuid889", "id": "888", "topic": "Late Start"}}, "event_ts": 1620090000000}`)
+	meeting, err = repo.GetMeeting(ctx, "888")
+	assert.NoError(t, err)
+	assert.Equal(t, models.MeetingStatusStarted, meeting.Status)
+	assert.Equal(t, time.UnixMilli(1620090000000), meeting.StartTime)
+}
+
+// TestWebhookDeadLetters tests that events which cannot be applied are kept as dead letters
+func TestWebhookDeadLetters(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	handler := api.NewWebhookHandler(repo, meetingService)
+	ctx := context.Background()
+
+	// The meeting does not exist yet, so the participant cannot be added
+	payload := `{"event": "meeting.participant_joined", "payload": {"object": {"uuid": "uuid999", "id": "999", "participant": {"id": "part1", "user_name": "Ola Nordmann", "email": "ola@example.com"}}}, "event_ts": 1620123456789}`
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	deadLetters, err := repo.ListDeadLetters(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, deadLetters, 1) {
+		assert.Equal(t, "meeting.participant_joined", deadLetters[0].Event.Event)
+		assert.Equal(t, "999", deadLetters[0].MeetingID())
+		assert.Contains(t, deadLetters[0].Reason, "entity not found")
+		assert.Equal(t, 1, deadLetters[0].Attempts)
+
+		// Personal data is left out of the stored event, as it is from the journal
+		assert.NotContains(t, string(deadLetters[0].Event.Payload), "Ola Nordmann")
+		assert.NotContains(t, string(deadLetters[0].Event.Payload), "ola@example.com")
+		assert.Contains(t, string(deadLetters[0].Event.Payload), "part1")
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Create a request with the webhook payload
-			req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(tt.webhookPayload))
-			req.Header.Set("Content-Type", "application/json")
-			req.Header.Set("X-Zoom-Signature", "mock_signature") // Skip validation for this test
+	// Once the meeting exists the stored event can be applied
+	_ = repo.SaveMeeting(ctx, &models.Meeting{ID: "999", Status: models.MeetingStatusStarted})
+	assert.NoError(t, handler.ProcessEvent(ctx, &deadLetters[0].Event))
+	count, err := repo.CountParticipantsInMeeting(ctx, "999")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
 
-			// Create a response recorder
-			rr := httptest.NewRecorder()
+// TestWebhookRebuildFromJournal tests that meeting state can be recreated from the journaled events
+func TestWebhookRebuildFromJournal(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	handler := api.NewWebhookHandler(repo, meetingService)
+	ctx := context.Background()
 
-			// Create the handler with the mock service
-			handler := api.NewWebhookHandler(repo, mockService)
+	payloads := []string{
+		`{"event": "meeting.started", "payload": {"object": {"uuid": "uuid1", "id": "111", "topic": "Daily Standup"}}, "event_ts": 1620123456000}`,
+		`{"event": "meeting.participant_joined", "payload": {"object": {"uuid": "uuid1", "id": "111", "participant": {"id": "part1"}}}, "event_ts": 1620123457000}`,
+		`{"event": "meeting.participant_joined", "payload": {"object": {"uuid": "uuid1", "id": "111", "participant": {"id": "part2"}}}, "event_ts": 1620123458000}`,
+		`{"event": "meeting.participant_joined", "payload": {"object": {"uuid": "uuid9", "id": "999", "participant": {"id": "part3"}}}, "event_ts": 1620123459000}`,
+		`{"event": "meeting.started", "payload": {"object": {"uuid": "uuid2", "id": "222", "topic": "Retro"}}, "event_ts": 1620123460000}`,
+		`{"event": "meeting.ended", "payload": {"object": {"uuid": "uuid2", "id": "222", "topic": "Retro"}}, "event_ts": 1620123461000}`,
+	}
+	for _, payload := range payloads {
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
 
-			// Process the request
-			handler.ServeHTTP(rr, req)
+	// Simulate state damaged by a bug in how meetings were stored
+	require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "111", Topic: "Wrong", Status: models.MeetingStatusEnded}))
+	require.NoError(t, repo.ClearPartipantsInMeeting(ctx, "111"))
+	require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "333", Status: models.MeetingStatusStarted}))
 
-			// Verify service was called correctly
-			tt.verify(t, mockService)
-		})
+	result, err := handler.RebuildFromJournal(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, api.RebuildResult{Replayed: 5, Failed: 1}, result)
+
+	meeting, err := repo.GetMeeting(ctx, "111")
+	require.NoError(t, err)
+	assert.Equal(t, "Daily Standup", meeting.Topic)
+	assert.Equal(t, models.MeetingStatusStarted, meeting.Status)
+	count, err := repo.CountParticipantsInMeeting(ctx, "111")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	meeting, err = repo.GetMeeting(ctx, "222")
+	require.NoError(t, err)
+	assert.Equal(t, models.MeetingStatusEnded, meeting.Status)
+
+	_, err = repo.GetMeeting(ctx, "333")
+	assert.ErrorIs(t, err, memory.ErrNotFound, "Meetings not in the journal should be removed")
+}
+
+// TestWebhookRebuildSkipsExpiredMeetings tests that a rebuild does not bring back meetings the repository had expired
+func TestWebhookRebuildSkipsExpiredMeetings(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	handler := api.NewWebhookHandler(repo, meetingService)
+	handler.SetMeetingTTL(24 * time.Hour)
+	ctx := context.Background()
+
+	old := time.Now().Add(-48 * time.Hour).UnixMilli()
+	recent := time.Now().Add(-time.Hour).UnixMilli()
+	payloads := []string{
+		fmt.Sprintf(`{"event": "meeting.started", "payload": {"object": {"uuid": "uuid1", "id": "111", "topic": "Expired"}}, "event_ts": %d}`, old),
+		fmt.Sprintf(`{"event": "meeting.participant_joined", "payload": {"object": {"uuid": "uuid1", "id": "111", "participant": {"id": "part1"}}}, "event_ts": %d}`, old+1000),
+		fmt.Sprintf(`{"event": "meeting.started", "payload": {"object": {"uuid": "uuid2", "id": "222", "topic": "Long Running"}}, "event_ts": %d}`, old),
+		fmt.Sprintf(`{"event": "meeting.participant_joined", "payload": {"object": {"uuid": "uuid2", "id": "222", "participant": {"id": "part2"}}}, "event_ts": %d}`, recent),
+	}
+	for _, payload := range payloads {
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	result, err := handler.RebuildFromJournal(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, api.RebuildResult{Replayed: 2, Expired: 2}, result)
+
+	_, err = repo.GetMeeting(ctx, "111")
+	assert.ErrorIs(t, err, memory.ErrNotFound, "Meetings whose last event is older than the TTL should stay expired")
+
+	// A recent event keeps the whole meeting, including its older events
+	meeting, err := repo.GetMeeting(ctx, "222")
+	require.NoError(t, err)
+	assert.Equal(t, "Long Running", meeting.Topic)
+	count, err := repo.CountParticipantsInMeeting(ctx, "222")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+// TestWebhookJournalOmitsPersonalData tests that personal data in events is left out of the journal
+func TestWebhookJournalOmitsPersonalData(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	handler := api.NewWebhookHandler(repo, meetingService)
+	ctx := context.Background()
+
+	payload := `{"event": "meeting.participant_joined", "payload": {"account_id": "abc", "object": {"uuid": "uuid1", "id": "111", "host_id": "host1", "participant": {"id": "part1", "user_id": "16778240", "user_name": "Ola Nordmann", "email": "ola@example.com"}}}, "event_ts": 1620123457000}`
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var journaled []*models.WebhookEvent
+	require.NoError(t, repo.ReplayEvents(ctx, func(event *models.WebhookEvent) error {
+		journaled = append(journaled, event)
+		return nil
+	}))
+	require.Len(t, journaled, 1)
+	assert.JSONEq(t, `{"account_id": "abc", "object": {"uuid": "uuid1", "id": "111", "participant": {"id": "part1"}}}`, string(journaled[0].Payload))
+	assert.Equal(t, "111", journaled[0].MeetingID())
+}
+
+// TestWebhookRoomEvents tests that zoomroom.* events keep the state of each physical room
+func TestWebhookRoomEvents(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	handler := api.NewWebhookHandler(repo, meetingService)
+	ctx := context.Background()
+
+	var notified []models.RoomStatus
+	meetingService.RegisterRoomUpdateCallback(func(room *models.Room) {
+		notified = append(notified, room.Status)
+	})
+
+	send := func(payload string) {
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	send(`{"event": "zoomroom.checked_in", "payload": {"account_id": "abc123", "object": {"id": "room1", "room_name": "Oslo"}}, "event_ts": 1620123456000}`)
+	send(`{"event": "zoomroom.started_meeting", "payload": {"account_id": "abc123", "object": {"id": "room1", "meeting_id": "987654321", "topic": "Standup"}}, "event_ts": 1620123457000}`)
+
+	room, err := repo.GetRoom(ctx, "room1")
+	require.NoError(t, err)
+	assert.Equal(t, "Oslo", room.Name)
+	assert.Equal(t, models.RoomStatusInMeeting, room.Status)
+	assert.Equal(t, "Standup", room.MeetingTopic)
+
+	// A delayed check-in must not overwrite the newer meeting state
+	send(`{"event": "zoomroom.checked_in", "payload": {"account_id": "abc123", "object": {"id": "room1"}}, "event_ts": 1620123455000}`)
+	room, err = repo.GetRoom(ctx, "room1")
+	require.NoError(t, err)
+	assert.Equal(t, models.RoomStatusInMeeting, room.Status)
+	assert.Equal(t, int64(1), handler.Stats().OutOfOrder)
+
+	send(`{"event": "zoomroom.ended_meeting", "payload": {"account_id": "abc123", "object": {"id": "room1", "meeting_id": "987654321"}}, "event_ts": 1620123458000}`)
+	send(`{"event": "zoomroom.checked_out", "payload": {"account_id": "abc123", "object": {"id": "room1"}}, "event_ts": 1620123459000}`)
+
+	room, err = repo.GetRoom(ctx, "room1")
+	require.NoError(t, err)
+	assert.Equal(t, models.RoomStatusAvailable, room.Status)
+	assert.Equal(t, []models.RoomStatus{
+		models.RoomStatusCheckedIn,
+		models.RoomStatusInMeeting,
+		models.RoomStatusCheckedIn,
+		models.RoomStatusAvailable,
+	}, notified)
+
+	// Room events without a room ID cannot be applied
+	assert.ErrorIs(t, handler.ProcessEvent(ctx, &models.WebhookEvent{
+		Event:   "zoomroom.checked_in",
+		Payload: json.RawMessage(`{"object": {}}`),
+	}), api.ErrInvalidEvent)
+}
+
+// TestWebhookRoomAlerts tests that zoomroom.alert events are tracked as device alerts
+func TestWebhookRoomAlerts(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	handler := api.NewWebhookHandler(repo, meetingService)
+	ctx := context.Background()
+
+	var notified []bool
+	meetingService.RegisterDeviceAlertCallback(func(alert *models.DeviceAlert) {
+		notified = append(notified, alert.IsActive())
+	})
+
+	send := func(event string, alertKind int, eventTS int64) {
+		payload := fmt.Sprintf(`{"event": "%s", "payload": {"account_id": "abc123", "object": {"id": "room1", "room_name": "Oslo", "issue": "Camera disconnected", "alert_type": 7, "component": 1, "alert_kind": %d}}, "event_ts": %d}`,
+			event, alertKind, eventTS)
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	send("zoomroom.alert", models.AlertKindRaised, 1620123456000)
+	send("zoomroom.delayed_alert", models.AlertKindRaised, 1620123457000)
+	send("zoomroom.alert", models.AlertKindCleared, 1620123459000)
+
+	alert, err := repo.GetDeviceAlert(ctx, "room1:1:7")
+	require.NoError(t, err)
+	assert.Equal(t, "Camera disconnected", alert.Issue)
+	assert.Equal(t, time.UnixMilli(1620123456000), alert.RaisedAt, "Raised time should be from the first alert")
+	assert.Equal(t, time.UnixMilli(1620123459000), alert.ClearedAt)
+	assert.False(t, alert.IsActive())
+	assert.Equal(t, []bool{true, true, false}, notified)
+
+	// A delayed raise older than the clear must not reopen the alert
+	send("zoomroom.delayed_alert", models.AlertKindRaised, 1620123458000)
+	alert, err = repo.GetDeviceAlert(ctx, "room1:1:7")
+	require.NoError(t, err)
+	assert.False(t, alert.IsActive())
+}
+
+// TestWebhookWebinarEvents tests that webinars are stored with their own type and count participants
+func TestWebhookWebinarEvents(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	handler := api.NewWebhookHandler(repo, meetingService)
+	ctx := context.Background()
+
+	send := func(payload string) {
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	send(`{"event": "webinar.started", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "888", "topic": "All Hands"}}, "event_ts": 1620123456000}`)
+	send(`{"event": "webinar.participant_joined", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "888", "participant": {"id": "part1"}}}, "event_ts": 1620123457000}`)
+	send(`{"event": "webinar.participant_joined", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "888", "participant": {"id": "part2"}}}, "event_ts": 1620123458000}`)
+	send(`{"event": "webinar.participant_left", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "888", "participant": {"id": "part1"}}}, "event_ts": 1620123459000}`)
+
+	webinar, err := repo.GetMeeting(ctx, "888")
+	require.NoError(t, err)
+	assert.Equal(t, models.MeetingTypeWebinar, webinar.Type)
+	assert.Equal(t, models.MeetingStatusStarted, webinar.Status)
+	assert.Equal(t, "All Hands", webinar.Topic)
+
+	count, err := repo.CountParticipantsInMeeting(ctx, "888")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	send(`{"event": "webinar.ended", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "888", "topic": "All Hands"}}, "event_ts": 1620123460000}`)
+
+	webinar, err = repo.GetMeeting(ctx, "888")
+	require.NoError(t, err)
+	assert.Equal(t, models.MeetingStatusEnded, webinar.Status)
+	assert.True(t, webinar.IsWebinar())
+}
+
+// TestWebhookHandlerAppliesEventsThroughService tests that accepted events are handed to the meeting service
+// and that out-of-order results are counted
+func TestWebhookHandlerAppliesEventsThroughService(t *testing.T) {
+	repo := memory.NewRepository()
+	mockService := new(MockMeetingService)
+	eventNamed := func(name string) interface{} {
+		return mock.MatchedBy(func(event *models.WebhookEvent) bool { return event.Event == name })
+	}
+	mockService.On("ApplyEvent", mock.Anything, eventNamed("meeting.started")).
+		Return(service.ApplyResult{Outcome: service.EventApplied, MeetingID: "123456789"}, nil)
+	mockService.On("ApplyEvent", mock.Anything, eventNamed("meeting.ended")).
+		Return(service.ApplyResult{Outcome: service.EventOutOfOrder, MeetingID: "123456789"}, nil)
+	mockService.On("ApplyEvent", mock.Anything, eventNamed("meeting.deleted")).
+		Return(service.ApplyResult{}, fmt.Errorf("%w: meeting.deleted event is missing meeting ID", service.ErrInvalidEvent))
+
+	handler := api.NewWebhookHandler(repo, mockService)
+
+	send := func(payload string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusOK, send(`{"event": "meeting.started", "payload": {"object": {"uuid": "uuid123", "id": "123456789", "topic": "Test Meeting"}}, "event_ts": 1620123456000}`).Code)
+	assert.Equal(t, http.StatusOK, send(`{"event": "meeting.ended", "payload": {"object": {"uuid": "uuid123", "id": "123456789", "topic": "Test Meeting"}}, "event_ts": 1620123457000}`).Code)
+	mockService.AssertNumberOfCalls(t, "ApplyEvent", 2)
+	assert.Equal(t, int64(1), handler.Stats().OutOfOrder)
+
+	// Events the service cannot apply are still acknowledged, and kept as dead letters
+	assert.Equal(t, http.StatusOK, send(`{"event": "meeting.deleted", "payload": {"object": {"uuid": "uuid123"}}, "event_ts": 1620123458000}`).Code)
+	deadLetters, err := repo.ListDeadLetters(context.Background())
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, "meeting.deleted", deadLetters[0].Event.Event)
+}
+
+// TestWebhookWaitingRoom tests that waiting room events are counted separately from participants in the meeting
+func TestWebhookWaitingRoom(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	handler := api.NewWebhookHandler(repo, meetingService)
+	ctx := context.Background()
+
+	send := func(payload string) {
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+	waiting := func() int {
+		count, err := repo.CountParticipantsInWaitingRoom(ctx, "777")
+		require.NoError(t, err)
+		return count
+	}
+
+	// Joining before the host makes the meeting known before it has started
+	send(`{"event": "meeting.participant_jbh_waiting", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "777", "topic": "Standup", "participant": {"id": "part1"}}}, "event_ts": 1620123456000}`)
+	meeting, err := repo.GetMeeting(ctx, "777")
+	require.NoError(t, err)
+	assert.Equal(t, "Standup", meeting.Topic)
+	assert.Equal(t, 1, waiting())
+
+	send(`{"event": "meeting.started", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "777", "topic": "Standup"}}, "event_ts": 1620123457000}`)
+	send(`{"event": "meeting.participant_put_in_waiting_room", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "777", "participant": {"id": "part2"}}}, "event_ts": 1620123458000}`)
+	send(`{"event": "meeting.participant_joined_waiting_room", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "777", "participant": {"id": "part3"}}}, "event_ts": 1620123459000}`)
+	assert.Equal(t, 3, waiting())
+
+	// Admitted and left participants stop waiting
+	send(`{"event": "meeting.participant_admitted", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "777", "participant": {"id": "part2"}}}, "event_ts": 1620123460000}`)
+	send(`{"event": "meeting.participant_left_waiting_room", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "777", "participant": {"id": "part3"}}}, "event_ts": 1620123461000}`)
+	assert.Equal(t, 1, waiting())
+
+	// Joining the meeting also removes the participant from the waiting room
+	send(`{"event": "meeting.participant_joined", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "777", "participant": {"id": "part1"}}}, "event_ts": 1620123462000}`)
+	assert.Equal(t, 0, waiting())
+
+	statusData, err := meetingService.GetMeetingStatusData(ctx, false)
+	require.NoError(t, err)
+	require.Len(t, statusData, 1)
+	assert.Equal(t, 1, statusData[0].ParticipantCount)
+	assert.Equal(t, 0, statusData[0].WaitingCount)
+
+	// Ending the meeting clears anyone still waiting
+	send(`{"event": "meeting.participant_put_in_waiting_room", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "777", "participant": {"id": "part4"}}}, "event_ts": 1620123463000}`)
+	assert.Equal(t, 1, waiting())
+	send(`{"event": "meeting.ended", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "777", "topic": "Standup"}}, "event_ts": 1620123464000}`)
+	assert.Equal(t, 0, waiting())
+}
+
+// TestWebhookBreakoutRooms tests that participants moving into breakout rooms stay counted in the meeting
+func TestWebhookBreakoutRooms(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	handler := api.NewWebhookHandler(repo, meetingService)
+	ctx := context.Background()
+
+	send := func(payload string) {
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+	participants := func() int {
+		count, err := repo.CountParticipantsInMeeting(ctx, "555")
+		require.NoError(t, err)
+		return count
+	}
+
+	send(`{"event": "meeting.started", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "555", "topic": "Workshop"}}, "event_ts": 1620123456000}`)
+	send(`{"event": "meeting.participant_joined", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "555", "participant": {"id": "part1"}}}, "event_ts": 1620123457000}`)
+	send(`{"event": "meeting.participant_joined", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "555", "participant": {"id": "part2"}}}, "event_ts": 1620123458000}`)
+	assert.Equal(t, 2, participants())
+
+	// Moving into a breakout room is reported as leaving the main room, followed by joining the breakout room
+	send(`{"event": "meeting.participant_left", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "555", "participant": {"id": "part1", "leave_reason": "left the meeting to join breakout room."}}}, "event_ts": 1620123459000}`)
+	send(`{"event": "meeting.participant_joined_breakout_room", "payload": {"account_id": "abc123", "object": {"uuid": "bo1", "breakout_room_uuid": "bo1", "id": "555", "participant": {"id": "part1"}}}, "event_ts": 1620123460000}`)
+	assert.Equal(t, 2, participants())
+
+	rooms, err := repo.ListBreakoutRooms(ctx, "555")
+	require.NoError(t, err)
+	assert.Equal(t, []models.BreakoutRoom{{ID: "bo1", ParticipantCount: 1}}, rooms)
+
+	// Returning to the main room
+	send(`{"event": "meeting.participant_left_breakout_room", "payload": {"account_id": "abc123", "object": {"uuid": "bo1", "breakout_room_uuid": "bo1", "id": "555", "participant": {"id": "part1"}}}, "event_ts": 1620123461000}`)
+	send(`{"event": "meeting.participant_joined", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "555", "participant": {"id": "part1"}}}, "event_ts": 1620123462000}`)
+	assert.Equal(t, 2, participants())
+	rooms, err = repo.ListBreakoutRooms(ctx, "555")
+	require.NoError(t, err)
+	assert.Empty(t, rooms)
+
+	// Leaving the meeting still removes the participant
+	send(`{"event": "meeting.participant_left", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "555", "participant": {"id": "part2", "leave_reason": "left the meeting."}}}, "event_ts": 1620123463000}`)
+	assert.Equal(t, 1, participants())
+}
+
+// TestWebhookRecordingAndSharing tests that recording and screen sharing events set the meeting indicators
+func TestWebhookRecordingAndSharing(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	handler := api.NewWebhookHandler(repo, meetingService)
+	ctx := context.Background()
+
+	send := func(payload string) {
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+	get := func() *models.Meeting {
+		meeting, err := repo.GetMeeting(ctx, "444")
+		require.NoError(t, err)
+		return meeting
+	}
+
+	send(`{"event": "meeting.started", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "444", "topic": "Demo"}}, "event_ts": 1620123456000}`)
+	assert.False(t, get().IsRecording())
+	assert.False(t, get().Sharing)
+
+	send(`{"event": "recording.started", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "444", "topic": "Demo"}}, "event_ts": 1620123457000}`)
+	assert.Equal(t, models.RecordingStatusRecording, get().Recording)
+	send(`{"event": "recording.paused", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "444", "topic": "Demo"}}, "event_ts": 1620123458000}`)
+	assert.Equal(t, models.RecordingStatusPaused, get().Recording)
+	send(`{"event": "recording.resumed", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "444", "topic": "Demo"}}, "event_ts": 1620123459000}`)
+	assert.Equal(t, models.RecordingStatusRecording, get().Recording)
+	send(`{"event": "recording.stopped", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "444", "topic": "Demo"}}, "event_ts": 1620123460000}`)
+	assert.False(t, get().IsRecording())
+
+	send(`{"event": "meeting.sharing_started", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "444", "participant": {"id": "part1"}}}, "event_ts": 1620123461000}`)
+	assert.True(t, get().Sharing)
+	send(`{"event": "meeting.sharing_ended", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "444", "participant": {"id": "part1"}}}, "event_ts": 1620123462000}`)
+	assert.False(t, get().Sharing)
+
+	// Ending the meeting clears the indicators, and late events do not bring them back
+	send(`{"event": "recording.started", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "444", "topic": "Demo"}}, "event_ts": 1620123463000}`)
+	send(`{"event": "meeting.sharing_started", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "444", "participant": {"id": "part1"}}}, "event_ts": 1620123464000}`)
+	send(`{"event": "meeting.ended", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "444", "topic": "Demo"}}, "event_ts": 1620123466000}`)
+	assert.False(t, get().IsRecording())
+	assert.False(t, get().Sharing)
+
+	send(`{"event": "meeting.sharing_started", "payload": {"account_id": "abc123", "object": {"uuid": "uuid1", "id": "444", "participant": {"id": "part2"}}}, "event_ts": 1620123465000}`)
+	assert.False(t, get().Sharing)
+}
+
+// TestWebhookMultipleAccounts tests that events are verified with their account's secret and stored namespaced by account
+func TestWebhookMultipleAccounts(t *testing.T) {
+	repo := memory.NewRepository()
+	accounts := config.ZoomAccounts{
+		{Name: config.DefaultAccountName, WebhookSecrets: []config.WebhookSecret{{Name: "primary", Token: "main_secret"}}},
+		{Name: "sandbox", ID: "sandbox123", WebhookSecrets: []config.WebhookSecret{{Name: "primary", Token: "sandbox_secret"}}},
+	}
+	meetingService := service.NewMeetingService(repo)
+	meetingService.SetAccounts(accounts)
+	handler := api.NewWebhookHandlerWithAccounts(repo, meetingService, accounts)
+	ctx := context.Background()
+
+	send := func(payload, secretToken string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		signWebhookRequest(req, payload, secretToken, time.Now())
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	mainEvent := `{"event": "meeting.started", "payload": {"account_id": "main123", "object": {"uuid": "uuid1", "id": "999", "topic": "Main Meeting"}}, "event_ts": 1620123456000}`
+	sandboxEvent := `{"event": "meeting.started", "payload": {"account_id": "sandbox123", "object": {"uuid": "uuid2", "id": "999", "topic": "Sandbox Meeting"}}, "event_ts": 1620123457000}`
+
+	t.Run("EventsSignedWithOwnSecret", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, send(mainEvent, "main_secret").Code)
+		require.Equal(t, http.StatusOK, send(sandboxEvent, "sandbox_secret").Code)
+
+		// The same meeting ID in both accounts is stored as two meetings
+		mainMeeting, err := repo.GetMeeting(ctx, "999")
+		require.NoError(t, err)
+		assert.Equal(t, "Main Meeting", mainMeeting.Topic)
+		assert.Equal(t, "main123", mainMeeting.AccountID)
+
+		sandboxMeeting, err := repo.GetMeeting(ctx, models.MeetingKey("sandbox123", "999"))
+		require.NoError(t, err)
+		assert.Equal(t, "Sandbox Meeting", sandboxMeeting.Topic)
+		assert.Equal(t, "sandbox123", sandboxMeeting.AccountID)
+		assert.Equal(t, "999", sandboxMeeting.ZoomID())
+
+		// Participants are counted per account
+		participantEvent := `{"event": "meeting.participant_joined", "payload": {"account_id": "sandbox123", "object": {"uuid": "uuid2", "id": "999", "participant": {"id": "part1"}}}, "event_ts": 1620123458000}`
+		require.Equal(t, http.StatusOK, send(participantEvent, "sandbox_secret").Code)
+		count, err := repo.CountParticipantsInMeeting(ctx, sandboxMeeting.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+		count, err = repo.CountParticipantsInMeeting(ctx, mainMeeting.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("EventSignedWithOtherAccountsSecretRejected", func(t *testing.T) {
+		forged := `{"event": "meeting.ended", "payload": {"account_id": "sandbox123", "object": {"uuid": "uuid2", "id": "999"}}, "event_ts": 1620123459000}`
+		assert.Equal(t, http.StatusUnauthorized, send(forged, "main_secret").Code)
+	})
+
+	t.Run("UnknownAccountSignedWithOtherAccountsSecretRejected", func(t *testing.T) {
+		// Events for unknown or missing accounts are stored under the default account, so only its secret is accepted
+		forged := `{"event": "meeting.ended", "payload": {"account_id": "unknown456", "object": {"uuid": "uuid1", "id": "999"}}, "event_ts": 1620123459000}`
+		assert.Equal(t, http.StatusUnauthorized, send(forged, "sandbox_secret").Code)
+		forged = `{"event": "meeting.ended", "payload": {"object": {"uuid": "uuid1", "id": "999"}}, "event_ts": 1620123459000}`
+		assert.Equal(t, http.StatusUnauthorized, send(forged, "sandbox_secret").Code)
+
+		mainMeeting, err := repo.GetMeeting(ctx, "999")
+		require.NoError(t, err)
+		assert.Equal(t, models.MeetingStatusStarted, mainMeeting.Status)
+	})
+
+	t.Run("URLValidationUsesSigningAccountsSecret", func(t *testing.T) {
+		rr := send(`{"event": "endpoint.url_validation", "payload": {"plainToken": "token123"}}`, "sandbox_secret")
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var response map[string]string
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		mac := hmac.New(sha256.New, []byte("sandbox_secret"))
+		mac.Write([]byte("token123"))
+		assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), response["encryptedToken"])
+	})
+}
+
+// TestWebhookSecretRotation tests that previous secrets are accepted until they expire while URL validation uses the primary secret
+func TestWebhookSecretRotation(t *testing.T) {
+	repo := memory.NewRepository()
+	handler := api.NewWebhookHandlerWithAccounts(repo, service.NewMeetingService(repo), config.ZoomAccounts{
+		{Name: config.DefaultAccountName, WebhookSecrets: []config.WebhookSecret{
+			{Name: "primary", Token: "new_secret"},
+			{Name: "previous-1", Token: "old_secret", ExpiresAt: time.Now().Add(time.Hour)},
+			{Name: "previous-2", Token: "expired_secret", ExpiresAt: time.Now().Add(-time.Hour)},
+		}},
+	})
+
+	send := func(payload, secretToken string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		signWebhookRequest(req, payload, secretToken, time.Now())
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
 	}
+
+	assert.Equal(t, http.StatusOK, send(`{"event": "meeting.created", "payload": {"object": {"uuid": "uuid1", "id": "111", "topic": "New"}}, "event_ts": 1620123456000}`, "new_secret").Code)
+	assert.Equal(t, http.StatusOK, send(`{"event": "meeting.created", "payload": {"object": {"uuid": "uuid2", "id": "222", "topic": "Old"}}, "event_ts": 1620123457000}`, "old_secret").Code)
+	assert.Equal(t, http.StatusUnauthorized, send(`{"event": "meeting.created", "payload": {"object": {"uuid": "uuid3", "id": "333", "topic": "Expired"}}, "event_ts": 1620123458000}`, "expired_secret").Code)
+
+	// Recent events record the secret that verified them, newest first
+	matches := handler.RecentSecretMatches()
+	require.Len(t, matches, 2)
+	assert.Equal(t, "222", matches[0].MeetingID)
+	assert.Equal(t, "previous-1", matches[0].SecretName)
+	assert.Equal(t, "111", matches[1].MeetingID)
+	assert.Equal(t, "primary", matches[1].SecretName)
+	assert.Equal(t, config.DefaultAccountName, matches[1].AccountName)
+
+	// A validation request signed with a previous secret is answered with the primary secret
+	rr := send(`{"event": "endpoint.url_validation", "payload": {"plainToken": "token123"}}`, "old_secret")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var response map[string]string
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	mac := hmac.New(sha256.New, []byte("new_secret"))
+	mac.Write([]byte("token123"))
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), response["encryptedToken"])
+}
+
+// TestWebhookDeliveryLog tests that every webhook request is recorded with its verification and processing outcome
+func TestWebhookDeliveryLog(t *testing.T) {
+	repo := memory.NewRepository()
+	handler := api.NewWebhookHandlerWithSecret(repo, service.NewMeetingService(repo), "test_secret_token")
+
+	send := func(payload, secretToken string, timestamp time.Time) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		signWebhookRequest(req, payload, secretToken, timestamp)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	now := time.Now()
+	require.Equal(t, http.StatusOK, send(`{"event": "meeting.started", "payload": {"object": {"uuid": "uuid1", "id": "111"}}, "event_ts": 1620123455000}`, "test_secret_token", now).Code)
+	joined := `{"event": "meeting.participant_joined", "payload": {"object": {"uuid": "uuid1", "id": "111", "participant": {"id": "part1", "user_name": "Ola Nordmann", "email": "ola@example.com"}}}, "event_ts": 1620123456000}`
+	require.Equal(t, http.StatusOK, send(joined, "test_secret_token", now).Code)
+	require.Equal(t, http.StatusUnauthorized, send(`{"event": "meeting.started", "payload": {"object": {"id": "222"}}}`, "wrong_secret", now).Code)
+	require.Equal(t, http.StatusOK, send(`{"event": "meeting.chat_message_sent", "payload": {"object": {"id": "111"}}, "event_ts": 1620123457000}`, "test_secret_token", now).Code)
+	require.Equal(t, http.StatusBadRequest, send(`not json`, "test_secret_token", now).Code)
+
+	deliveries := handler.RecentDeliveries()
+	require.Len(t, deliveries, 5)
+
+	assert.Equal(t, models.SignatureValid, deliveries[0].Signature)
+	assert.Equal(t, models.DeliveryMalformed, deliveries[0].Outcome)
+	assert.Empty(t, deliveries[0].Payload, "Bodies that are not JSON should not be kept")
+
+	assert.Equal(t, "meeting.chat_message_sent", deliveries[1].Event)
+	assert.Equal(t, models.DeliveryOutcome(service.EventUnsupported), deliveries[1].Outcome)
+
+	assert.Equal(t, "meeting.started", deliveries[2].Event)
+	assert.Equal(t, "222", deliveries[2].MeetingID)
+	assert.Equal(t, models.SignatureInvalid, deliveries[2].Signature)
+	assert.Equal(t, models.DeliveryRejected, deliveries[2].Outcome)
+	assert.Equal(t, "invalid signature", deliveries[2].Error)
+
+	applied, ok := handler.Delivery(deliveries[3].ID)
+	require.True(t, ok)
+	assert.Equal(t, "meeting.participant_joined", applied.Event)
+	assert.Equal(t, "111", applied.MeetingID)
+	assert.Equal(t, models.SignatureValid, applied.Signature)
+	assert.Equal(t, "primary", applied.SecretName)
+	assert.Equal(t, models.DeliveryOutcome(service.EventApplied), applied.Outcome)
+	assert.Equal(t, 1, applied.Attempts)
+	assert.Positive(t, applied.Latency)
+	assert.NotContains(t, string(applied.Payload), "Ola Nordmann")
+	assert.NotContains(t, string(applied.Payload), "ola@example.com")
+	assert.Contains(t, string(applied.Payload), `"participant":{"email":"[redacted]","id":"part1","user_name":"[redacted]"}`)
+
+	// A retried delivery of the same event is recorded as a duplicate
+	require.Equal(t, http.StatusOK, send(joined, "test_secret_token", now.Add(time.Second)).Code)
+	assert.Equal(t, models.DeliveryDuplicate, handler.RecentDeliveries()[0].Outcome)
+
+	_, ok = handler.Delivery("unknown")
+	assert.False(t, ok)
+}
+
+// TestWebhookDeliveryLogQueued tests that queued deliveries are updated once a worker has applied the event
+func TestWebhookDeliveryLogQueued(t *testing.T) {
+	repo := memory.NewRepository()
+	handler := api.NewWebhookHandler(repo, service.NewMeetingService(repo))
+	queue := api.NewEventQueue(config.WebhookQueueConfig{Workers: 1, Size: 10}, handler.ProcessEvent)
+	handler.SetQueue(queue)
+
+	payload := `{"event": "meeting.started", "payload": {"object": {"uuid": "uuid1", "id": "111"}}, "event_ts": 1620123456000}`
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NoError(t, queue.Shutdown(context.Background()))
+
+	deliveries := handler.RecentDeliveries()
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, models.SignatureNotChecked, deliveries[0].Signature)
+	assert.Equal(t, models.DeliveryOutcome(service.EventApplied), deliveries[0].Outcome)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+}
+
+// TestWebhookReportsEventsToWatchdog tests that only verified events count as webhook traffic
+func TestWebhookReportsEventsToWatchdog(t *testing.T) {
+	repo := memory.NewRepository()
+	handler := api.NewWebhookHandlerWithSecret(repo, service.NewMeetingService(repo), "test_secret_token")
+	watchdog := service.NewSilenceWatchdog(config.WatchdogConfig{SilenceThreshold: time.Minute}, time.Now())
+	handler.SetWatchdog(watchdog)
+
+	payload := `{"event": "meeting.started", "payload": {"object": {"uuid": "uuid1", "id": "111"}}, "event_ts": 1620123456000}`
+	send := func(secretToken string) {
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		signWebhookRequest(req, payload, secretToken, time.Now())
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	send("wrong_secret")
+	assert.True(t, watchdog.Status().LastEventAt.IsZero(), "Unverified requests should not count as events")
+
+	send("test_secret_token")
+	assert.False(t, watchdog.Status().LastEventAt.IsZero())
+}
+
+// TestWebhookAllowlist tests that requests from sources outside the allowlist are rejected before verification
+func TestWebhookAllowlist(t *testing.T) {
+	repo := memory.NewRepository()
+	mockService := new(MockMeetingService)
+	mockService.On("ApplyEvent", mock.Anything, mock.Anything).Return(service.ApplyResult{Outcome: service.EventApplied}, nil)
+
+	handler := api.NewWebhookHandlerWithSecret(repo, mockService, "test_secret_token")
+	allowlist, err := api.NewIPAllowlist(config.WebhookAllowlistConfig{CIDRs: []string{"198.51.100.0/24"}, TrustedProxyHops: 1})
+	require.NoError(t, err)
+	handler.SetAllowlist(allowlist)
+
+	payload := `{"event": "meeting.started", "payload": {"object": {"uuid": "uuid1", "id": "111"}}, "event_ts": 1620123456000}`
+	send := func(forwardedFor string) int {
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		req.RemoteAddr = "10.0.0.1:4321"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		signWebhookRequest(req, payload, "test_secret_token", time.Now())
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// The leftmost entry is set by the client and ignored
+	assert.Equal(t, http.StatusForbidden, send("198.51.100.5, 192.0.2.1"))
+	assert.Equal(t, http.StatusForbidden, send("192.0.2.1"))
+	mockService.AssertNotCalled(t, "ApplyEvent", mock.Anything, mock.Anything)
+
+	assert.Equal(t, int64(2), handler.Stats().DisallowedSource)
+	assert.Equal(t, int64(0), handler.Stats().InvalidSignature)
+	require.Len(t, handler.RejectedSources(), 1)
+	assert.Equal(t, "192.0.2.1", handler.RejectedSources()[0].Address)
+	assert.Equal(t, int64(2), handler.RejectedSources()[0].Count)
+
+	rejected := handler.RecentDeliveries()[0]
+	assert.Equal(t, models.DeliveryRejected, rejected.Outcome)
+	assert.Equal(t, "192.0.2.1", rejected.SourceAddress)
+	assert.Equal(t, "source address 192.0.2.1 not allowed", rejected.Error)
+
+	assert.Equal(t, http.StatusOK, send("192.0.2.1, 198.51.100.5"))
+	mockService.AssertNumberOfCalls(t, "ApplyEvent", 1)
+	assert.Equal(t, "198.51.100.5", handler.RecentDeliveries()[0].SourceAddress)
 }
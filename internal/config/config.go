@@ -2,9 +2,13 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,6 +19,100 @@ type ZoomConfig struct {
 	RedirectURI        string
 	WebhookURL         string
 	WebhookSecretToken string
+	// Previous webhook secret tokens still accepted while rotating to WebhookSecretToken
+	PreviousWebhookSecrets []WebhookSecret
+	// Maximum accepted age of a webhook request timestamp (0 disables the check)
+	WebhookMaxRequestAge time.Duration
+	// How long processed event keys are remembered to drop duplicate deliveries
+	WebhookDedupTTL time.Duration
+	// Number of recent webhook deliveries kept for the admin UI
+	WebhookDeliveryLogSize int
+	// Additional Zoom accounts, each with their own credentials.
+	// The credentials above belong to the default account.
+	Accounts ZoomAccounts
+}
+
+// ZoomAccounts is a list of Zoom accounts, with the default account first
+type ZoomAccounts []ZoomAccount
+
+// ByID returns the account with the given Zoom account_id, falling back to the default account
+func (a ZoomAccounts) ByID(accountID string) ZoomAccount {
+	for _, account := range a {
+		if account.ID != "" && account.ID == accountID {
+			return account
+		}
+	}
+	for _, account := range a {
+		if account.ID == "" {
+			return account
+		}
+	}
+	return ZoomAccount{Name: DefaultAccountName}
+}
+
+// ByName returns the account with the given name
+func (a ZoomAccounts) ByName(name string) (ZoomAccount, bool) {
+	for _, account := range a {
+		if account.Name == name {
+			return account, true
+		}
+	}
+	return ZoomAccount{}, false
+}
+
+// DefaultAccountName is the name of the account configured with the global Zoom credentials
+const DefaultAccountName = "default"
+
+// ZoomAccount holds the credentials of a single Zoom account
+type ZoomAccount struct {
+	// Short name used to filter the dashboard and admin views
+	Name string
+	// Zoom account_id sent in webhook payloads. Empty for the default account,
+	// which receives events from accounts that are not configured.
+	ID           string
+	ClientID     string
+	ClientSecret string
+	// Webhook secret tokens accepted for the account, the primary secret first
+	WebhookSecrets []WebhookSecret
+}
+
+// WebhookSecret is a webhook secret token accepted when verifying Zoom webhook requests
+type WebhookSecret struct {
+	// Name identifies the secret in logs and the admin interface without revealing it
+	Name  string
+	Token string
+	// When the secret stops being accepted; zero for secrets that do not expire
+	ExpiresAt time.Time
+}
+
+// Fingerprint returns a short hash of the token, to tell secrets apart without revealing them
+func (s WebhookSecret) Fingerprint() string {
+	sum := sha256.Sum256([]byte(s.Token))
+	return hex.EncodeToString(sum[:4])
+}
+
+// IsExpired reports whether the secret is no longer accepted at the given time
+func (s WebhookSecret) IsExpired(now time.Time) bool {
+	return !s.ExpiresAt.IsZero() && !now.Before(s.ExpiresAt)
+}
+
+// PrimaryWebhookSecret returns the token Zoom is configured with, used to answer URL validation challenges
+func (a ZoomAccount) PrimaryWebhookSecret() string {
+	if len(a.WebhookSecrets) == 0 {
+		return ""
+	}
+	return a.WebhookSecrets[0].Token
+}
+
+// ActiveWebhookSecrets returns the secrets accepted at the given time, the primary secret first
+func (a ZoomAccount) ActiveWebhookSecrets(now time.Time) []WebhookSecret {
+	active := make([]WebhookSecret, 0, len(a.WebhookSecrets))
+	for _, secret := range a.WebhookSecrets {
+		if secret.Token != "" && !secret.IsExpired(now) {
+			active = append(active, secret)
+		}
+	}
+	return active
 }
 
 // RedisConfig holds Redis/Valkey configuration
@@ -32,15 +130,161 @@ type RedisConfig struct {
 	MeetingTTL time.Duration
 }
 
+// BoltConfig holds configuration for the embedded on-disk store, used on single-node deployments without Redis
+type BoltConfig struct {
+	// File the database is stored in (empty disables the embedded store)
+	File string
+	// TTL for meetings (0 means no expiration)
+	MeetingTTL time.Duration
+}
+
+// Enabled reports whether the embedded store is configured
+func (c BoltConfig) Enabled() bool {
+	return c.File != ""
+}
+
+// WebhookQueueConfig holds configuration for asynchronous webhook processing
+type WebhookQueueConfig struct {
+	// Number of workers applying events (0 processes events inline in the request)
+	Workers int
+	// Maximum number of events waiting to be processed
+	Size int
+	// Number of retries for an event that fails to apply
+	MaxRetries int
+	// Delay before the first retry, doubled for each further attempt
+	RetryBackoff time.Duration
+}
+
+// WebhookAllowlistConfig holds configuration for restricting which source addresses may deliver webhooks
+type WebhookAllowlistConfig struct {
+	// CIDR ranges or single addresses allowed to deliver webhooks
+	CIDRs []string
+	// File with further ranges, one per line, reloaded when it changes
+	File string
+	// Number of reverse proxies in front of the service appending to X-Forwarded-For (0 uses the connecting address)
+	TrustedProxyHops int
+}
+
+// Enabled reports whether any source restriction is configured
+func (c WebhookAllowlistConfig) Enabled() bool {
+	return len(c.CIDRs) > 0 || c.File != ""
+}
+
+// WatchdogConfig holds configuration for detecting that webhook events have stopped arriving,
+// for example because Zoom deactivated the event subscription
+type WatchdogConfig struct {
+	// How long no valid webhook event may arrive while traffic is expected before the data
+	// is reported as stale (0 disables the watchdog)
+	SilenceThreshold time.Duration
+	// Weekdays Zoom events are expected on
+	ExpectedDays []time.Weekday
+	// Local time of day, as an offset from midnight, the expected traffic window starts and ends
+	ExpectedFrom  time.Duration
+	ExpectedUntil time.Duration
+}
+
+// JournalConfig holds configuration for the webhook event journal
+type JournalConfig struct {
+	// File the in-memory backend appends events to (empty keeps the journal in memory only).
+	// The Redis and embedded backends always keep the journal in their own store.
+	File string
+}
+
 // GetZoomConfig loads Zoom configuration from environment variables
 func GetZoomConfig() ZoomConfig {
+	// Parse the webhook freshness window from environment variable (in seconds)
+	maxAgeSeconds := getEnvInt("ZOOM_WEBHOOK_MAX_AGE_SECONDS", 300) // Default 5 minutes
+
+	// Parse how long to remember processed events (in hours)
+	dedupHours := getEnvInt("ZOOM_WEBHOOK_DEDUP_TTL_HOURS", 24) // Default 1 day
+
+	// Parse how many recent webhook deliveries to keep
+	deliveryLogSize := getEnvInt("ZOOM_WEBHOOK_DELIVERY_LOG_SIZE", 100)
+
 	return ZoomConfig{
 		ClientID:           getEnv("ZOOM_CLIENT_ID", ""),
 		ClientSecret:       getEnv("ZOOM_CLIENT_SECRET", ""),
 		RedirectURI:        getEnv("ZOOM_REDIRECT_URI", ""),
 		WebhookURL:         getEnv("ZOOM_WEBHOOK_URL", ""),
 		WebhookSecretToken: getEnv("ZOOM_WEBHOOK_SECRET_TOKEN", ""),
+		PreviousWebhookSecrets: parsePreviousWebhookSecrets(
+			getEnv("ZOOM_WEBHOOK_PREVIOUS_SECRET_TOKENS", "")),
+		WebhookMaxRequestAge:   time.Duration(maxAgeSeconds) * time.Second,
+		WebhookDedupTTL:        time.Duration(dedupHours) * time.Hour,
+		WebhookDeliveryLogSize: deliveryLogSize,
+		Accounts:               getZoomAccounts(),
+	}
+}
+
+// getZoomAccounts loads the additional Zoom accounts named in ZOOM_ACCOUNTS (comma separated).
+// Each account NAME is configured with ZOOM_ACCOUNT_<NAME>_ID, _CLIENT_ID, _CLIENT_SECRET, _WEBHOOK_SECRET_TOKEN
+// and _WEBHOOK_PREVIOUS_SECRET_TOKENS.
+func getZoomAccounts() ZoomAccounts {
+	var accounts ZoomAccounts
+	for _, name := range strings.Split(getEnv("ZOOM_ACCOUNTS", ""), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || name == DefaultAccountName {
+			continue
+		}
+
+		prefix := "ZOOM_ACCOUNT_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		accounts = append(accounts, ZoomAccount{
+			Name:         name,
+			ID:           getEnv(prefix+"ID", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			WebhookSecrets: webhookSecrets(
+				getEnv(prefix+"WEBHOOK_SECRET_TOKEN", ""),
+				parsePreviousWebhookSecrets(getEnv(prefix+"WEBHOOK_PREVIOUS_SECRET_TOKENS", ""))),
+		})
 	}
+	return accounts
+}
+
+// parsePreviousWebhookSecrets parses a comma separated list of secret tokens, each optionally followed by
+// "@" and the time it expires, either as a date (2006-01-02) or in RFC 3339 format
+func parsePreviousWebhookSecrets(value string) []WebhookSecret {
+	var secrets []WebhookSecret
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		token, expiry, hasExpiry := strings.Cut(entry, "@")
+		secret := WebhookSecret{
+			Name:  fmt.Sprintf("previous-%d", len(secrets)+1),
+			Token: token,
+		}
+		if hasExpiry {
+			expiresAt, err := parseExpiry(expiry)
+			if err != nil {
+				// Accepting a secret for longer than intended is worse than dropping it
+				log.Printf("Ignoring webhook secret %s with invalid expiry %q: %v", secret.Name, expiry, err)
+				continue
+			}
+			secret.ExpiresAt = expiresAt
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets
+}
+
+// parseExpiry parses an expiry time given as a date or in RFC 3339 format
+func parseExpiry(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// webhookSecrets returns the primary secret token followed by the previous secrets
+func webhookSecrets(primary string, previous []WebhookSecret) []WebhookSecret {
+	secrets := make([]WebhookSecret, 0, len(previous)+1)
+	if primary != "" {
+		secrets = append(secrets, WebhookSecret{Name: "primary", Token: primary})
+	}
+	return append(secrets, previous...)
 }
 
 // GetRedisConfig loads Redis/Valkey configuration from environment variables
@@ -65,6 +309,149 @@ func GetRedisConfig() RedisConfig {
 	}
 }
 
+// GetBoltConfig loads embedded store configuration from environment variables
+func GetBoltConfig() BoltConfig {
+	// Parse TTL from environment variable (in hours)
+	ttlHours, _ := strconv.Atoi(getEnv("BOLT_MEETING_TTL_HOURS", "168")) // Default 7 days
+
+	return BoltConfig{
+		File:       getEnv("BOLT_FILE", ""),
+		MeetingTTL: time.Duration(ttlHours) * time.Hour,
+	}
+}
+
+// GetWebhookQueueConfig loads webhook queue configuration from environment variables
+func GetWebhookQueueConfig() WebhookQueueConfig {
+	workers, _ := strconv.Atoi(getEnv("WEBHOOK_QUEUE_WORKERS", "4"))
+	size, _ := strconv.Atoi(getEnv("WEBHOOK_QUEUE_SIZE", "1000"))
+	maxRetries, _ := strconv.Atoi(getEnv("WEBHOOK_MAX_RETRIES", "3"))
+	backoffMillis, _ := strconv.Atoi(getEnv("WEBHOOK_RETRY_BACKOFF_MS", "500"))
+
+	return WebhookQueueConfig{
+		Workers:      workers,
+		Size:         size,
+		MaxRetries:   maxRetries,
+		RetryBackoff: time.Duration(backoffMillis) * time.Millisecond,
+	}
+}
+
+// GetWebhookAllowlistConfig loads webhook source address restrictions from environment variables
+func GetWebhookAllowlistConfig() WebhookAllowlistConfig {
+	var cidrs []string
+	for _, cidr := range strings.Split(getEnv("ZOOM_WEBHOOK_ALLOWED_CIDRS", ""), ",") {
+		if cidr = strings.TrimSpace(cidr); cidr != "" {
+			cidrs = append(cidrs, cidr)
+		}
+	}
+
+	hops, _ := strconv.Atoi(getEnv("ZOOM_WEBHOOK_TRUSTED_PROXY_HOPS", "0"))
+
+	return WebhookAllowlistConfig{
+		CIDRs:            cidrs,
+		File:             getEnv("ZOOM_WEBHOOK_ALLOWLIST_FILE", ""),
+		TrustedProxyHops: hops,
+	}
+}
+
+// GetWatchdogConfig loads webhook silence watchdog configuration from environment variables
+func GetWatchdogConfig() WatchdogConfig {
+	thresholdMinutes, _ := strconv.Atoi(getEnv("WEBHOOK_SILENCE_THRESHOLD_MINUTES", "0")) // Disabled by default
+
+	days, err := parseWeekdays(getEnv("WEBHOOK_EXPECTED_TRAFFIC_DAYS", "Mon-Fri"))
+	if err != nil {
+		log.Printf("Invalid WEBHOOK_EXPECTED_TRAFFIC_DAYS, expecting traffic every day: %v", err)
+		days = []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}
+	}
+
+	from, until, err := parseTimeRange(getEnv("WEBHOOK_EXPECTED_TRAFFIC_HOURS", "08:00-16:00"))
+	if err != nil {
+		log.Printf("Invalid WEBHOOK_EXPECTED_TRAFFIC_HOURS, expecting traffic all day: %v", err)
+		from, until = 0, 24*time.Hour
+	}
+
+	return WatchdogConfig{
+		SilenceThreshold: time.Duration(thresholdMinutes) * time.Minute,
+		ExpectedDays:     days,
+		ExpectedFrom:     from,
+		ExpectedUntil:    until,
+	}
+}
+
+// weekdays maps three letter weekday abbreviations to weekdays
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseWeekdays parses a comma separated list of weekdays and weekday ranges, such as "Mon-Fri" or "Mon,Wed-Thu"
+func parseWeekdays(value string) ([]time.Weekday, error) {
+	var days []time.Weekday
+	for _, entry := range strings.Split(value, ",") {
+		first, last, isRange := strings.Cut(strings.TrimSpace(entry), "-")
+		if !isRange {
+			last = first
+		}
+
+		start, ok := weekdays[strings.ToLower(strings.TrimSpace(first))]
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday %q", first)
+		}
+		end, ok := weekdays[strings.ToLower(strings.TrimSpace(last))]
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday %q", last)
+		}
+
+		// Ranges may wrap around the end of the week, e.g. "Sat-Sun"
+		for day := start; ; day = (day + 1) % 7 {
+			days = append(days, day)
+			if day == end {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// parseTimeRange parses a time of day range such as "08:00-16:00" into offsets from midnight
+func parseTimeRange(value string) (time.Duration, time.Duration, error) {
+	first, last, ok := strings.Cut(value, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("expected a range such as 08:00-16:00, got %q", value)
+	}
+
+	from, err := parseTimeOfDay(strings.TrimSpace(first))
+	if err != nil {
+		return 0, 0, err
+	}
+	until, err := parseTimeOfDay(strings.TrimSpace(last))
+	if err != nil {
+		return 0, 0, err
+	}
+	if from >= until {
+		return 0, 0, fmt.Errorf("range %q must end after it starts", value)
+	}
+	return from, until, nil
+}
+
+// parseTimeOfDay parses a time of day such as "08:30" into an offset from midnight, allowing "24:00"
+func parseTimeOfDay(value string) (time.Duration, error) {
+	if value == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// GetJournalConfig loads event journal configuration from environment variables
+func GetJournalConfig() JournalConfig {
+	return JournalConfig{
+		File: getEnv("EVENT_JOURNAL_FILE", ""),
+	}
+}
+
 // getEnv retrieves an environment variable or returns a default value
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
@@ -88,6 +475,41 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return b
 }
 
+// getEnvInt retrieves a non-negative integer environment variable.
+// Invalid or negative values are logged and the default is used, so a typo does not silently turn a setting off.
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %d: %v", key, value, defaultValue, err)
+		return defaultValue
+	}
+	if n < 0 {
+		log.Printf("Invalid %s %q, using %d: must not be negative", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// DefaultAccount returns the account configured with the global Zoom credentials
+func (c ZoomConfig) DefaultAccount() ZoomAccount {
+	return ZoomAccount{
+		Name:           DefaultAccountName,
+		ClientID:       c.ClientID,
+		ClientSecret:   c.ClientSecret,
+		WebhookSecrets: webhookSecrets(c.WebhookSecretToken, c.PreviousWebhookSecrets),
+	}
+}
+
+// AllAccounts returns the default account followed by the additional accounts
+func (c ZoomConfig) AllAccounts() ZoomAccounts {
+	return append(ZoomAccounts{c.DefaultAccount()}, c.Accounts...)
+}
+
 // IsZoomConfigValid checks if all required Zoom configuration is present
 func (c ZoomConfig) IsZoomConfigValid() bool {
 	return c.ClientID != "" && c.ClientSecret != "" && c.RedirectURI != ""
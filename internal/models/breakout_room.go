@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// BreakoutRoom is a breakout session of a meeting, tracked as a child of the parent meeting
+type BreakoutRoom struct {
+	ID               string `json:"id"` // Breakout room UUID
+	ParticipantCount int    `json:"participant_count"`
+}
+
+// BreakoutRoomID returns the UUID of the breakout room a participant_joined_breakout_room or
+// participant_left_breakout_room event refers to, or an empty string if it has none
+func (e *WebhookEvent) BreakoutRoomID() string {
+	var payload StandardEventPayload
+	if err := json.Unmarshal(e.Payload, &payload); err != nil {
+		return ""
+	}
+	if payload.Object.BreakoutRoomUUID != "" {
+		return payload.Object.BreakoutRoomUUID
+	}
+	// The object UUID of breakout events is the breakout room's own UUID
+	return payload.Object.UUID
+}
+
+// IsLeftForBreakoutRoom reports whether a participant_left event was sent because the
+// participant moved from the main room into a breakout room, rather than leaving the meeting
+func (e *WebhookEvent) IsLeftForBreakoutRoom() bool {
+	var payload StandardEventPayload
+	if err := json.Unmarshal(e.Payload, &payload); err != nil || payload.Object.Participant == nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(payload.Object.Participant.LeaveReason), "breakout room")
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// DeadLetterCapacity is the number of dead letters kept; the oldest failures are dropped beyond it
+const DeadLetterCapacity = 1000
+
+// DeadLetter is a webhook event that could not be applied, kept so an admin can replay or discard it
+type DeadLetter struct {
+	ID       string       `json:"id"`
+	Event    WebhookEvent `json:"event"`
+	Reason   string       `json:"reason"`
+	Attempts int          `json:"attempts"`
+	FailedAt time.Time    `json:"failed_at"`
+}
+
+// NewDeadLetter creates a dead letter for an event that failed with the given reason.
+// Personal data is left out of the stored payload, as replaying the event only needs the IDs.
+func NewDeadLetter(event WebhookEvent, reason string, attempts int) *DeadLetter {
+	event.Payload = StripPayload(event.Payload)
+	return &DeadLetter{
+		ID:       newDeadLetterID(),
+		Event:    event,
+		Reason:   reason,
+		Attempts: attempts,
+		FailedAt: time.Now(),
+	}
+}
+
+// MeetingID returns the ID of the meeting the failed event refers to
+func (d *DeadLetter) MeetingID() string {
+	return d.Event.MeetingID()
+}
+
+// newDeadLetterID generates a random identifier for a dead letter
+func newDeadLetterID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand does not fail on supported platforms; fall back to a time-based ID
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// DeviceComponent identifies which device of a Zoom Room an alert concerns
+type DeviceComponent int
+
+const (
+	DeviceComponentRoom              DeviceComponent = 1
+	DeviceComponentController        DeviceComponent = 2
+	DeviceComponentSchedulingDisplay DeviceComponent = 3
+)
+
+// String returns a human-readable name of the device
+func (c DeviceComponent) String() string {
+	switch c {
+	case DeviceComponentRoom:
+		return "Zoom Room"
+	case DeviceComponentController:
+		return "Controller"
+	case DeviceComponentSchedulingDisplay:
+		return "Scheduling Display"
+	default:
+		return fmt.Sprintf("Device %d", int(c))
+	}
+}
+
+// Zoom alert_kind values
+const (
+	AlertKindRaised  = 1
+	AlertKindCleared = 2
+)
+
+// DeviceAlert represents a device health problem reported for a Zoom Room, such as an
+// offline controller, a disconnected camera or a device with low battery
+type DeviceAlert struct {
+	ID          string          `json:"id"` // Identifies the problem; a new alert for it replaces the old one
+	RoomID      string          `json:"room_id"`
+	RoomName    string          `json:"room_name"`
+	Component   DeviceComponent `json:"component"`
+	AlertType   int             `json:"alert_type"` // Zoom alert_type code
+	Issue       string          `json:"issue"`      // Description of the problem from Zoom
+	Delayed     bool            `json:"delayed"`    // Reported by zoomroom.delayed_alert
+	RaisedAt    time.Time       `json:"raised_at,omitempty"`
+	ClearedAt   time.Time       `json:"cleared_at,omitempty"` // Zero while the problem persists
+	LastEventTS int64           `json:"last_event_ts,omitempty"`
+}
+
+// DeviceAlertID returns the ID of the alert for a problem with a room's device
+func DeviceAlertID(roomID string, component DeviceComponent, alertType int) string {
+	return fmt.Sprintf("%s:%d:%d", roomID, component, alertType)
+}
+
+// IsActive reports whether the problem has not been cleared yet
+func (a *DeviceAlert) IsActive() bool {
+	return a.ClearedAt.IsZero()
+}
+
+// IsStaleEvent reports whether an event with the given Zoom event_ts (ms) is older than
+// the last event applied to the alert. Events without a timestamp are never stale.
+func (a *DeviceAlert) IsStaleEvent(eventTS int64) bool {
+	return eventTS != 0 && eventTS < a.LastEventTS
+}
+
+// MergePrevious keeps details of the previously stored alert for the same problem:
+// a clear keeps the time the alert was raised, and a repeated raise keeps the original time
+func (a *DeviceAlert) MergePrevious(previous *DeviceAlert) {
+	if a.RoomName == "" {
+		a.RoomName = previous.RoomName
+	}
+	if a.Issue == "" {
+		a.Issue = previous.Issue
+	}
+	if !a.IsActive() || previous.IsActive() {
+		a.RaisedAt = previous.RaisedAt
+	}
+}
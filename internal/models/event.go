@@ -2,6 +2,8 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -10,6 +12,10 @@ type WebhookEvent struct {
 	Event   string          `json:"event"`
 	Payload json.RawMessage `json:"payload"`  // Use RawMessage for flexibility with different payload types
 	EventTS int64           `json:"event_ts"` // Unix timestamp in milliseconds
+
+	// ID of the webhook delivery the event was received in, used to record its processing outcome.
+	// Not serialized, so events replayed from the journal or dead letters have none.
+	DeliveryID string `json:"-"`
 }
 
 // StandardEventPayload contains the common payload structure for regular Zoom webhook events
@@ -30,6 +36,9 @@ type EventObject struct {
 	Duration    int               `json:"duration"`
 	Timezone    string            `json:"timezone,omitempty"`
 	Participant *ParticipantEvent `json:"participant,omitempty"`
+
+	// Set for participant_joined_breakout_room and participant_left_breakout_room
+	BreakoutRoomUUID string `json:"breakout_room_uuid,omitempty"`
 }
 
 // ParticipantEvent contains details about a participant in participant-related events
@@ -40,6 +49,97 @@ type ParticipantEvent struct {
 	Email     string    `json:"email"`
 	JoinTime  time.Time `json:"join_time,omitempty"`
 	LeaveTime time.Time `json:"leave_time,omitempty"`
+
+	LeaveReason string `json:"leave_reason,omitempty"` // Set for participant_left events
+}
+
+// RoomEventPayload contains the payload structure for Zoom Rooms (zoomroom.*) webhook events
+type RoomEventPayload struct {
+	AccountID string          `json:"account_id"`
+	Object    RoomEventObject `json:"object"`
+}
+
+// RoomEventObject contains details about the room in a Zoom Rooms webhook event
+type RoomEventObject struct {
+	ID           string `json:"id"`
+	RoomName     string `json:"room_name"`
+	CalendarName string `json:"calendar_name,omitempty"`
+	MeetingID    string `json:"meeting_id,omitempty"` // Set for started_meeting and ended_meeting
+	Topic        string `json:"topic,omitempty"`
+
+	// Set for alert and delayed_alert
+	Issue     string `json:"issue,omitempty"`
+	AlertType int    `json:"alert_type,omitempty"`
+	Component int    `json:"component,omitempty"`
+	AlertKind int    `json:"alert_kind,omitempty"`
+}
+
+// IsRoomEvent reports whether the event is a Zoom Rooms event rather than a meeting event
+func (e *WebhookEvent) IsRoomEvent() bool {
+	return strings.HasPrefix(e.Event, "zoomroom.")
+}
+
+// IdempotencyKey returns a stable key identifying a single Zoom event, built from the
+// event name, object UUID, participant ID and event timestamp. Retried deliveries of
+// the same event share the key. An empty key is returned when the event carries no
+// timestamp, as distinct events could then not be told apart.
+func (e *WebhookEvent) IdempotencyKey() string {
+	if e.EventTS == 0 {
+		return ""
+	}
+
+	var payload StandardEventPayload
+	if err := json.Unmarshal(e.Payload, &payload); err != nil {
+		return ""
+	}
+
+	participantID := ""
+	if payload.Object.Participant != nil {
+		participantID = payload.Object.Participant.ID
+	}
+
+	// Room events carry no UUID, so fall back to the room ID
+	objectKey := payload.Object.UUID
+	if objectKey == "" {
+		objectKey = payload.Object.ID
+	}
+
+	return fmt.Sprintf("%s:%s:%s:%d", e.Event, objectKey, participantID, e.EventTS)
+}
+
+// MeetingID returns the ID of the meeting the event refers to, or an empty string if it has none
+func (e *WebhookEvent) MeetingID() string {
+	var payload StandardEventPayload
+	if err := json.Unmarshal(e.Payload, &payload); err != nil {
+		return ""
+	}
+	return payload.Object.ID
+}
+
+// AccountID returns the Zoom account the event was sent for, or an empty string if it has none
+func (e *WebhookEvent) AccountID() string {
+	var payload StandardEventPayload
+	if err := json.Unmarshal(e.Payload, &payload); err != nil {
+		return ""
+	}
+	return payload.AccountID
+}
+
+// meetingType returns the type of meeting the event refers to, based on the event name
+func (e *WebhookEvent) meetingType() MeetingType {
+	if strings.HasPrefix(e.Event, "webinar.") {
+		return MeetingTypeWebinar
+	}
+	return MeetingTypeMeeting
+}
+
+// Timestamp returns the time the event occurred according to Zoom.
+// Falls back to the current time when the event carries no timestamp.
+func (e *WebhookEvent) Timestamp() time.Time {
+	if e.EventTS == 0 {
+		return time.Now()
+	}
+	return time.UnixMilli(e.EventTS)
 }
 
 // ProcessMeetingCreated handles a meeting.created event
@@ -51,7 +151,9 @@ func (e *WebhookEvent) ProcessMeetingCreated() *Meeting {
 
 	meeting := &Meeting{
 		ID:            payload.Object.ID,
+		AccountID:     payload.AccountID,
 		Topic:         payload.Object.Topic,
+		Type:          e.meetingType(),
 		StartTime:     payload.Object.StartTime,
 		Duration:      payload.Object.Duration,
 		Status:        MeetingStatusCreated,
@@ -60,6 +162,7 @@ func (e *WebhookEvent) ProcessMeetingCreated() *Meeting {
 			ID: payload.Object.HostID,
 		},
 		Participants: []Participant{},
+		LastEventTS:  e.EventTS,
 	}
 
 	return meeting
@@ -74,14 +177,17 @@ func (e *WebhookEvent) ProcessMeetingStarted() *Meeting {
 
 	meeting := &Meeting{
 		ID:        payload.Object.ID,
+		AccountID: payload.AccountID,
 		Topic:     payload.Object.Topic,
-		StartTime: time.Now(),
+		Type:      e.meetingType(),
+		StartTime: e.Timestamp(),
 		Duration:  payload.Object.Duration,
 		Status:    MeetingStatusStarted,
 		Host: Participant{
 			ID: payload.Object.HostID,
 		},
 		Participants: []Participant{},
+		LastEventTS:  e.EventTS,
 	}
 
 	return meeting
@@ -95,7 +201,9 @@ func (e *WebhookEvent) ProcessMeetingUpdated() *Meeting {
 
 	meeting := &Meeting{
 		ID:            payload.Object.ID,
+		AccountID:     payload.AccountID,
 		Topic:         payload.Object.Topic,
+		Type:          e.meetingType(),
 		StartTime:     payload.Object.StartTime,
 		Duration:      payload.Object.Duration,
 		Status:        MeetingStatusUpdated,
@@ -104,6 +212,7 @@ func (e *WebhookEvent) ProcessMeetingUpdated() *Meeting {
 			ID: payload.Object.HostID,
 		},
 		Participants: []Participant{},
+		LastEventTS:  e.EventTS,
 	}
 
 	return meeting
@@ -118,13 +227,16 @@ func (e *WebhookEvent) ProcessMeetingEnded() *Meeting {
 
 	meeting := &Meeting{
 		ID:            payload.Object.ID,
+		AccountID:     payload.AccountID,
 		Topic:         payload.Object.Topic,
-		EndTime:       time.Now(),
+		Type:          e.meetingType(),
+		EndTime:       e.Timestamp(),
 		Status:        MeetingStatusEnded,
 		OperatorEmail: payload.Operator,
 		Host: Participant{
 			ID: payload.Object.HostID,
 		},
+		LastEventTS: e.EventTS,
 	}
 
 	return meeting
@@ -141,11 +253,17 @@ func (e *WebhookEvent) ProcessParticipantJoined() *Participant {
 		return nil
 	}
 
+	// Prefer the join time reported by Zoom, then the event timestamp
+	joinTime := payload.Object.Participant.JoinTime
+	if joinTime.IsZero() {
+		joinTime = e.Timestamp()
+	}
+
 	return &Participant{
 		ID:       payload.Object.Participant.ID, // Use ID instead of UserID
 		Name:     payload.Object.Participant.Name,
 		Email:    payload.Object.Participant.Email,
-		JoinTime: time.Now(),
+		JoinTime: joinTime,
 	}
 }
 
@@ -160,10 +278,101 @@ func (e *WebhookEvent) ProcessParticipantLeft() *Participant {
 		return nil
 	}
 
+	// Prefer the leave time reported by Zoom, then the event timestamp
+	leaveTime := payload.Object.Participant.LeaveTime
+	if leaveTime.IsZero() {
+		leaveTime = e.Timestamp()
+	}
+
 	return &Participant{
 		ID:        payload.Object.Participant.ID, // Use ID instead of UserID
 		Name:      payload.Object.Participant.Name,
 		Email:     payload.Object.Participant.Email,
-		LeaveTime: time.Now(),
+		LeaveTime: leaveTime,
+	}
+}
+
+// ProcessRoomEvent applies a zoomroom.* event to the previously stored state of the room,
+// which may be nil for a room not seen before. Returns nil for unparseable or unknown events.
+func (e *WebhookEvent) ProcessRoomEvent(previous *Room) *Room {
+	var payload RoomEventPayload
+	if err := json.Unmarshal(e.Payload, &payload); err != nil {
+		return nil
+	}
+
+	room := &Room{}
+	if previous != nil {
+		*room = *previous
+	}
+	room.ID = payload.Object.ID
+	if payload.Object.RoomName != "" {
+		room.Name = payload.Object.RoomName
 	}
+	room.UpdatedAt = e.Timestamp()
+	room.LastEventTS = e.EventTS
+
+	switch e.Event {
+	case "zoomroom.checked_in":
+		room.CheckedInAt = e.Timestamp()
+		// A meeting already running in the room takes precedence
+		if room.Status != RoomStatusInMeeting {
+			room.Status = RoomStatusCheckedIn
+		}
+	case "zoomroom.checked_out":
+		room.CheckedInAt = time.Time{}
+		if room.Status != RoomStatusInMeeting {
+			room.Status = RoomStatusAvailable
+		}
+	case "zoomroom.started_meeting":
+		room.Status = RoomStatusInMeeting
+		room.MeetingID = payload.Object.MeetingID
+		room.MeetingTopic = payload.Object.Topic
+		room.MeetingStartedAt = e.Timestamp()
+	case "zoomroom.ended_meeting":
+		room.MeetingID = ""
+		room.MeetingTopic = ""
+		room.MeetingStartedAt = time.Time{}
+		// The room stays occupied until whoever checked in checks out
+		if room.CheckedInAt.IsZero() {
+			room.Status = RoomStatusAvailable
+		} else {
+			room.Status = RoomStatusCheckedIn
+		}
+	default:
+		return nil
+	}
+
+	return room
+}
+
+// ProcessRoomAlert handles a zoomroom.alert or zoomroom.delayed_alert event.
+// Returns nil if the payload cannot be parsed or does not identify a room.
+func (e *WebhookEvent) ProcessRoomAlert() *DeviceAlert {
+	var payload RoomEventPayload
+	if err := json.Unmarshal(e.Payload, &payload); err != nil {
+		return nil
+	}
+	if payload.Object.ID == "" {
+		return nil
+	}
+
+	component := DeviceComponent(payload.Object.Component)
+	alert := &DeviceAlert{
+		ID:          DeviceAlertID(payload.Object.ID, component, payload.Object.AlertType),
+		RoomID:      payload.Object.ID,
+		RoomName:    payload.Object.RoomName,
+		Component:   component,
+		AlertType:   payload.Object.AlertType,
+		Issue:       payload.Object.Issue,
+		Delayed:     e.Event == "zoomroom.delayed_alert",
+		LastEventTS: e.EventTS,
+	}
+
+	if payload.Object.AlertKind == AlertKindCleared {
+		alert.ClearedAt = e.Timestamp()
+	} else {
+		alert.RaisedAt = e.Timestamp()
+	}
+
+	return alert
 }
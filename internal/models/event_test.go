@@ -2,6 +2,7 @@ package models_test
 
 import (
 	"encoding/json"
+	"fmt"
 	"testing"
 	"time"
 
@@ -79,7 +80,8 @@ func TestWebhookEventProcessing(t *testing.T) {
 		assert.Equal(t, 45, meeting.Duration)
 		assert.Equal(t, models.MeetingStatusStarted, meeting.Status)
 		assert.Equal(t, "host456", meeting.Host.ID)
-		assert.WithinDuration(t, time.Now(), meeting.StartTime, 2*time.Second)
+		assert.Equal(t, time.UnixMilli(1620123456789), meeting.StartTime, "Start time should come from event_ts")
+		assert.Equal(t, int64(1620123456789), meeting.LastEventTS)
 		assert.Empty(t, meeting.Participants)
 	})
 
@@ -112,7 +114,8 @@ func TestWebhookEventProcessing(t *testing.T) {
 		assert.Equal(t, "Completed Meeting", meeting.Topic)
 		assert.Equal(t, models.MeetingStatusEnded, meeting.Status)
 		assert.Equal(t, "host456", meeting.Host.ID)
-		assert.WithinDuration(t, time.Now(), meeting.EndTime, 2*time.Second)
+		assert.Equal(t, time.UnixMilli(1620123456789), meeting.EndTime, "End time should come from event_ts")
+		assert.Equal(t, int64(1620123456789), meeting.LastEventTS)
 	})
 
 	t.Run("ProcessParticipantJoined", func(t *testing.T) {
@@ -147,7 +150,7 @@ func TestWebhookEventProcessing(t *testing.T) {
 		assert.Equal(t, "part789", participant.ID)
 		assert.Equal(t, "Jane Doe", participant.Name)
 		assert.Equal(t, "jane@example.com", participant.Email)
-		assert.WithinDuration(t, time.Now(), participant.JoinTime, 2*time.Second)
+		assert.Equal(t, time.UnixMilli(1620123456789), participant.JoinTime, "Join time should come from event_ts")
 	})
 
 	t.Run("ProcessParticipantLeft", func(t *testing.T) {
@@ -182,6 +185,178 @@ func TestWebhookEventProcessing(t *testing.T) {
 		assert.Equal(t, "part789", participant.ID)
 		assert.Equal(t, "Jane Doe", participant.Name)
 		assert.Equal(t, "jane@example.com", participant.Email)
-		assert.WithinDuration(t, time.Now(), participant.LeaveTime, 2*time.Second)
+		assert.Equal(t, time.UnixMilli(1620123456789), participant.LeaveTime, "Leave time should come from event_ts")
+	})
+}
+
+// TestWebinarEvents tests that webinar events produce meetings of type webinar
+func TestWebinarEvents(t *testing.T) {
+	payload := json.RawMessage(`{"account_id": "abc123", "object": {"uuid": "uuid123", "id": "987654321", "topic": "All Hands"}}`)
+
+	started := (&models.WebhookEvent{Event: "webinar.started", Payload: payload, EventTS: 1620123456789}).ProcessMeetingStarted()
+	assert.Equal(t, models.MeetingTypeWebinar, started.Type)
+	assert.True(t, started.IsWebinar())
+	assert.Equal(t, "All Hands", started.Topic)
+
+	ended := (&models.WebhookEvent{Event: "webinar.ended", Payload: payload, EventTS: 1620123459999}).ProcessMeetingEnded()
+	assert.Equal(t, models.MeetingTypeWebinar, ended.Type)
+
+	meeting := (&models.WebhookEvent{Event: "meeting.started", Payload: payload, EventTS: 1620123456789}).ProcessMeetingStarted()
+	assert.Equal(t, models.MeetingTypeMeeting, meeting.Type)
+	assert.False(t, meeting.IsWebinar())
+}
+
+// TestWebhookEventIdempotencyKey tests the stable key used to deduplicate event deliveries
+func TestWebhookEventIdempotencyKey(t *testing.T) {
+	joined := models.WebhookEvent{
+		Event:   "meeting.participant_joined",
+		Payload: json.RawMessage(`{"object": {"uuid": "uuid123", "id": "987654321", "participant": {"id": "part123"}}}`),
+		EventTS: 1620123456789,
+	}
+
+	t.Run("IncludesEventDetails", func(t *testing.T) {
+		assert.Equal(t, "meeting.participant_joined:uuid123:part123:1620123456789", joined.IdempotencyKey())
+	})
+
+	t.Run("StableAcrossDeliveries", func(t *testing.T) {
+		retry := joined
+		assert.Equal(t, joined.IdempotencyKey(), retry.IdempotencyKey())
+	})
+
+	t.Run("DiffersByParticipant", func(t *testing.T) {
+		other := joined
+		other.Payload = json.RawMessage(`{"object": {"uuid": "uuid123", "id": "987654321", "participant": {"id": "part456"}}}`)
+		assert.NotEqual(t, joined.IdempotencyKey(), other.IdempotencyKey())
+	})
+
+	t.Run("RoomEventsUseRoomID", func(t *testing.T) {
+		roomA := models.WebhookEvent{Event: "zoomroom.checked_in", Payload: json.RawMessage(`{"object": {"id": "roomA"}}`), EventTS: 1620123456789}
+		roomB := models.WebhookEvent{Event: "zoomroom.checked_in", Payload: json.RawMessage(`{"object": {"id": "roomB"}}`), EventTS: 1620123456789}
+		assert.Equal(t, "zoomroom.checked_in:roomA::1620123456789", roomA.IdempotencyKey())
+		assert.NotEqual(t, roomA.IdempotencyKey(), roomB.IdempotencyKey())
+	})
+
+	t.Run("EmptyWithoutTimestamp", func(t *testing.T) {
+		noTS := joined
+		noTS.EventTS = 0
+		assert.Empty(t, noTS.IdempotencyKey())
+	})
+}
+
+// TestProcessRoomEvent tests how zoomroom.* events change the state of a room
+func TestProcessRoomEvent(t *testing.T) {
+	roomEvent := func(name string, eventTS int64, object string) *models.WebhookEvent {
+		return &models.WebhookEvent{
+			Event:   name,
+			Payload: json.RawMessage(`{"account_id": "abc123", "object": ` + object + `}`),
+			EventTS: eventTS,
+		}
+	}
+
+	t.Run("CheckedIn", func(t *testing.T) {
+		room := roomEvent("zoomroom.checked_in", 1000, `{"id": "room1", "room_name": "Oslo"}`).ProcessRoomEvent(nil)
+		assert.Equal(t, "room1", room.ID)
+		assert.Equal(t, "Oslo", room.Name)
+		assert.Equal(t, models.RoomStatusCheckedIn, room.Status)
+		assert.Equal(t, time.UnixMilli(1000), room.CheckedInAt)
+		assert.Equal(t, int64(1000), room.LastEventTS)
+	})
+
+	t.Run("MeetingDuringCheckIn", func(t *testing.T) {
+		room := roomEvent("zoomroom.checked_in", 1000, `{"id": "room1", "room_name": "Oslo"}`).ProcessRoomEvent(nil)
+		room = roomEvent("zoomroom.started_meeting", 2000, `{"id": "room1", "meeting_id": "987654321", "topic": "Standup"}`).ProcessRoomEvent(room)
+		assert.Equal(t, models.RoomStatusInMeeting, room.Status)
+		assert.Equal(t, "Oslo", room.Name, "Name should be kept when the event has none")
+		assert.Equal(t, "987654321", room.MeetingID)
+		assert.Equal(t, "Standup", room.MeetingTopic)
+		assert.Equal(t, time.UnixMilli(2000), room.MeetingStartedAt)
+
+		room = roomEvent("zoomroom.ended_meeting", 3000, `{"id": "room1", "meeting_id": "987654321"}`).ProcessRoomEvent(room)
+		assert.Equal(t, models.RoomStatusCheckedIn, room.Status, "Room should stay checked in after the meeting")
+		assert.Empty(t, room.MeetingID)
+		assert.Equal(t, time.UnixMilli(1000), room.CheckedInAt)
+
+		room = roomEvent("zoomroom.checked_out", 4000, `{"id": "room1"}`).ProcessRoomEvent(room)
+		assert.Equal(t, models.RoomStatusAvailable, room.Status)
+		assert.True(t, room.CheckedInAt.IsZero())
+	})
+
+	t.Run("MeetingWithoutCheckIn", func(t *testing.T) {
+		room := roomEvent("zoomroom.started_meeting", 1000, `{"id": "room1", "meeting_id": "987654321"}`).ProcessRoomEvent(nil)
+		assert.Equal(t, models.RoomStatusInMeeting, room.Status)
+
+		room = roomEvent("zoomroom.ended_meeting", 2000, `{"id": "room1"}`).ProcessRoomEvent(room)
+		assert.Equal(t, models.RoomStatusAvailable, room.Status)
+	})
+
+	t.Run("CheckOutDuringMeeting", func(t *testing.T) {
+		room := roomEvent("zoomroom.checked_in", 1000, `{"id": "room1"}`).ProcessRoomEvent(nil)
+		room = roomEvent("zoomroom.started_meeting", 2000, `{"id": "room1", "meeting_id": "987654321"}`).ProcessRoomEvent(room)
+		room = roomEvent("zoomroom.checked_out", 3000, `{"id": "room1"}`).ProcessRoomEvent(room)
+		assert.Equal(t, models.RoomStatusInMeeting, room.Status, "A running meeting outlives the check-out")
+		assert.True(t, room.CheckedInAt.IsZero())
+	})
+
+	t.Run("UnknownEvent", func(t *testing.T) {
+		assert.Nil(t, roomEvent("zoomroom.sensor_data", 1000, `{"id": "room1"}`).ProcessRoomEvent(nil))
+	})
+}
+
+// TestProcessRoomAlert tests parsing of zoomroom.alert events into device alerts
+func TestProcessRoomAlert(t *testing.T) {
+	alertEvent := func(name string, eventTS int64, alertKind int) *models.WebhookEvent {
+		return &models.WebhookEvent{
+			Event: name,
+			Payload: json.RawMessage(fmt.Sprintf(`{"account_id": "abc123", "object": {
+				"id": "room1", "room_name": "Oslo", "issue": "Controller is offline",
+				"alert_type": 3, "component": 2, "alert_kind": %d}}`, alertKind)),
+			EventTS: eventTS,
+		}
+	}
+
+	t.Run("Raised", func(t *testing.T) {
+		alert := alertEvent("zoomroom.alert", 1000, models.AlertKindRaised).ProcessRoomAlert()
+		assert.Equal(t, "room1:2:3", alert.ID)
+		assert.Equal(t, "Oslo", alert.RoomName)
+		assert.Equal(t, models.DeviceComponentController, alert.Component)
+		assert.Equal(t, "Controller", alert.Component.String())
+		assert.Equal(t, "Controller is offline", alert.Issue)
+		assert.False(t, alert.Delayed)
+		assert.True(t, alert.IsActive())
+		assert.Equal(t, time.UnixMilli(1000), alert.RaisedAt)
+	})
+
+	t.Run("ClearedKeepsRaisedTime", func(t *testing.T) {
+		raised := alertEvent("zoomroom.alert", 1000, models.AlertKindRaised).ProcessRoomAlert()
+		cleared := alertEvent("zoomroom.alert", 5000, models.AlertKindCleared).ProcessRoomAlert()
+		assert.False(t, cleared.IsActive())
+
+		cleared.MergePrevious(raised)
+		assert.Equal(t, time.UnixMilli(1000), cleared.RaisedAt)
+		assert.Equal(t, time.UnixMilli(5000), cleared.ClearedAt)
+	})
+
+	t.Run("RepeatedRaiseKeepsOriginalTime", func(t *testing.T) {
+		raised := alertEvent("zoomroom.alert", 1000, models.AlertKindRaised).ProcessRoomAlert()
+		delayed := alertEvent("zoomroom.delayed_alert", 3000, models.AlertKindRaised).ProcessRoomAlert()
+		assert.True(t, delayed.Delayed)
+
+		delayed.MergePrevious(raised)
+		assert.Equal(t, time.UnixMilli(1000), delayed.RaisedAt)
+	})
+
+	t.Run("RaiseAfterClearStartsOver", func(t *testing.T) {
+		cleared := alertEvent("zoomroom.alert", 5000, models.AlertKindCleared).ProcessRoomAlert()
+		cleared.RaisedAt = time.UnixMilli(1000)
+		raised := alertEvent("zoomroom.alert", 9000, models.AlertKindRaised).ProcessRoomAlert()
+
+		raised.MergePrevious(cleared)
+		assert.True(t, raised.IsActive())
+		assert.Equal(t, time.UnixMilli(9000), raised.RaisedAt)
+	})
+
+	t.Run("MissingRoom", func(t *testing.T) {
+		event := &models.WebhookEvent{Event: "zoomroom.alert", Payload: json.RawMessage(`{"object": {"issue": "Offline"}}`)}
+		assert.Nil(t, event.ProcessRoomAlert())
 	})
 }
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 )
 
@@ -19,6 +20,23 @@ func (s MeetingStatus) String() string {
 	return [...]string{"created", "updated", "started", "ended"}[s]
 }
 
+// MeetingType distinguishes regular meetings from webinars
+type MeetingType string
+
+const (
+	MeetingTypeMeeting MeetingType = "meeting"
+	MeetingTypeWebinar MeetingType = "webinar"
+)
+
+// RecordingStatus tells whether a meeting is being recorded
+type RecordingStatus string
+
+const (
+	RecordingStatusNone      RecordingStatus = ""
+	RecordingStatusRecording RecordingStatus = "recording"
+	RecordingStatusPaused    RecordingStatus = "paused"
+)
+
 // Participant represents a user participating in a meeting
 type Participant struct {
 	ID        string    `json:"id"`
@@ -30,8 +48,10 @@ type Participant struct {
 
 // Meeting represents a Zoom meeting
 type Meeting struct {
-	ID            string        `json:"id"`
+	ID            string        `json:"id"`                   // Zoom meeting ID, namespaced for additional accounts (see MeetingKey)
+	AccountID     string        `json:"account_id,omitempty"` // Zoom account the meeting belongs to
 	Topic         string        `json:"topic"`
+	Type          MeetingType   `json:"type,omitempty"` // Empty for meetings stored before webinars were supported
 	StartTime     time.Time     `json:"start_time"`
 	EndTime       time.Time     `json:"end_time,omitempty"`
 	Duration      int           `json:"duration"` // in minutes
@@ -39,6 +59,44 @@ type Meeting struct {
 	Host          Participant   `json:"host"`
 	Participants  []Participant `json:"participants"`
 	OperatorEmail string        `json:"operator_email,omitempty"` // Email of the user who created/updated the meeting
+	LastEventTS   int64         `json:"last_event_ts,omitempty"`  // Zoom event_ts (ms) of the last lifecycle event applied
+
+	// Live indicators, only meaningful while the meeting is in progress
+	Recording RecordingStatus `json:"recording,omitempty"`
+	Sharing   bool            `json:"sharing,omitempty"` // A participant is sharing their screen
+}
+
+// MeetingKey returns the ID a meeting is stored under when its account is namespaced,
+// so that meeting IDs from different Zoom accounts cannot collide
+func MeetingKey(accountID, zoomID string) string {
+	if accountID == "" {
+		return zoomID
+	}
+	return accountID + ":" + zoomID
+}
+
+// ZoomID returns the meeting ID known to Zoom, without the account namespace
+func (m *Meeting) ZoomID() string {
+	if m.AccountID == "" {
+		return m.ID
+	}
+	return strings.TrimPrefix(m.ID, m.AccountID+":")
+}
+
+// IsStaleEvent reports whether an event with the given Zoom event_ts (ms) is older than
+// the last lifecycle event applied to the meeting. Events without a timestamp are never stale.
+func (m *Meeting) IsStaleEvent(eventTS int64) bool {
+	return eventTS != 0 && eventTS < m.LastEventTS
+}
+
+// IsWebinar reports whether the meeting is a webinar
+func (m *Meeting) IsWebinar() bool {
+	return m.Type == MeetingTypeWebinar
+}
+
+// IsRecording reports whether the meeting is being recorded, including while the recording is paused
+func (m *Meeting) IsRecording() bool {
+	return m.Recording != RecordingStatusNone
 }
 
 // AddParticipant adds a participant to the meeting
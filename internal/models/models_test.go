@@ -121,3 +121,16 @@ func TestMeetingLifecycle(t *testing.T) {
 	assert.Equal(t, models.MeetingStatusEnded, m.Status)
 	assert.False(t, m.EndTime.IsZero())
 }
+
+func TestMeetingKey(t *testing.T) {
+	// Meetings of the default account are stored under their Zoom ID
+	assert.Equal(t, "123456789", models.MeetingKey("", "123456789"))
+	m := models.Meeting{ID: "123456789", AccountID: "main123"}
+	assert.Equal(t, "123456789", m.ZoomID())
+
+	// Meetings of additional accounts are namespaced by account
+	key := models.MeetingKey("sandbox123", "123456789")
+	assert.Equal(t, "sandbox123:123456789", key)
+	m = models.Meeting{ID: key, AccountID: "sandbox123"}
+	assert.Equal(t, "123456789", m.ZoomID())
+}
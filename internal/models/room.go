@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// RoomStatus represents the current state of a physical Zoom Room
+type RoomStatus int
+
+const (
+	RoomStatusAvailable RoomStatus = iota
+	RoomStatusCheckedIn
+	RoomStatusInMeeting
+)
+
+// String returns the string representation of a room status
+func (s RoomStatus) String() string {
+	return [...]string{"available", "checked_in", "in_meeting"}[s]
+}
+
+// Room represents a physical Zoom Room and what it is currently used for
+type Room struct {
+	ID               string     `json:"id"`
+	Name             string     `json:"name"`
+	Status           RoomStatus `json:"status"`
+	MeetingID        string     `json:"meeting_id,omitempty"`    // Meeting running in the room, if any
+	MeetingTopic     string     `json:"meeting_topic,omitempty"` // Topic of the meeting running in the room, if known
+	CheckedInAt      time.Time  `json:"checked_in_at,omitempty"`
+	MeetingStartedAt time.Time  `json:"meeting_started_at,omitempty"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	LastEventTS      int64      `json:"last_event_ts,omitempty"` // Zoom event_ts (ms) of the last room event applied
+}
+
+// IsStaleEvent reports whether an event with the given Zoom event_ts (ms) is older than
+// the last event applied to the room. Events without a timestamp are never stale.
+func (r *Room) IsStaleEvent(eventTS int64) bool {
+	return eventTS != 0 && eventTS < r.LastEventTS
+}
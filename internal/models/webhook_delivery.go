@@ -0,0 +1,163 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// SignatureResult is the result of verifying the signature of a webhook request
+type SignatureResult string
+
+const (
+	// SignatureValid means the request was signed with an active webhook secret
+	SignatureValid SignatureResult = "valid"
+	// SignatureInvalid means the signature was missing or matched no active webhook secret
+	SignatureInvalid SignatureResult = "invalid"
+	// SignatureNotChecked means no webhook secret is configured, so the request was not verified
+	SignatureNotChecked SignatureResult = "not_checked"
+)
+
+// DeliveryOutcome is what happened to a webhook delivery. Deliveries whose event was applied
+// carry the outcome reported by the meeting service, such as "applied" or "out_of_order".
+type DeliveryOutcome string
+
+const (
+	// DeliveryRejected means the request failed signature, freshness or replay checks
+	DeliveryRejected DeliveryOutcome = "rejected"
+	// DeliveryMalformed means the request body could not be parsed as a webhook event
+	DeliveryMalformed DeliveryOutcome = "malformed"
+	// DeliveryURLValidation means the request was a Zoom URL validation challenge
+	DeliveryURLValidation DeliveryOutcome = "url_validation"
+	// DeliveryDuplicate means the event had already been received and was not applied again
+	DeliveryDuplicate DeliveryOutcome = "duplicate"
+	// DeliveryQueued means the event is waiting to be applied by the event queue
+	DeliveryQueued DeliveryOutcome = "queued"
+	// DeliveryFailed means the event could not be accepted or applied
+	DeliveryFailed DeliveryOutcome = "failed"
+)
+
+// WebhookDelivery records a single webhook request received from Zoom, shown in the admin UI
+type WebhookDelivery struct {
+	ID         string
+	ReceivedAt time.Time
+	// Address the request came from, set when a webhook IP allowlist is configured
+	SourceAddress string
+	Event         string
+	MeetingID     string
+	AccountName   string
+	SecretName    string
+	Signature     SignatureResult
+	Outcome       DeliveryOutcome
+	Error         string
+	// Number of times the event was handed to the meeting service
+	Attempts int
+	// Time from receiving the request until the outcome was recorded
+	Latency time.Duration
+	// Request body with personal data redacted, empty when the body is not valid JSON
+	Payload json.RawMessage
+}
+
+// redactedValue replaces personal data in redacted payloads
+const redactedValue = "[redacted]"
+
+// piiFields are payload fields holding personal data about Zoom users and participants
+var piiFields = map[string]bool{
+	"user_name":           true,
+	"email":               true,
+	"host_email":          true,
+	"registrant_email":    true,
+	"operator":            true,
+	"user_id":             true,
+	"host_id":             true,
+	"participant_user_id": true,
+	"first_name":          true,
+	"last_name":           true,
+	"display_name":        true,
+	"phone_number":        true,
+	"ip_address":          true,
+	"public_ip":           true,
+	"private_ip":          true,
+	"customer_key":        true,
+}
+
+// RedactPayload returns a copy of a JSON document with the values of fields holding personal data
+// replaced, at any depth. Nil is returned when the document is not valid JSON.
+func RedactPayload(payload json.RawMessage) json.RawMessage {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber() // Keep IDs and timestamps exactly as received
+
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		return nil
+	}
+
+	redacted, err := json.Marshal(redactValue(document))
+	if err != nil {
+		return nil
+	}
+	return redacted
+}
+
+// StripPayload returns a copy of a JSON document with the fields holding personal data removed,
+// at any depth. Nil is returned when the document is not valid JSON.
+func StripPayload(payload json.RawMessage) json.RawMessage {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber() // Keep IDs and timestamps exactly as received
+
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		return nil
+	}
+
+	stripped, err := json.Marshal(stripValue(document))
+	if err != nil {
+		return nil
+	}
+	return stripped
+}
+
+// stripValue removes personal data from a decoded JSON value
+func stripValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if piiFields[key] {
+				delete(v, key)
+				continue
+			}
+			v[key] = stripValue(field)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = stripValue(item)
+		}
+	}
+	return value
+}
+
+// redactValue replaces personal data in a decoded JSON value
+func redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if piiFields[key] && field != nil && field != "" {
+				v[key] = redactedValue
+				continue
+			}
+			v[key] = redactValue(field)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+	}
+	return value
+}
+
+// RejectedSource counts webhook requests rejected because their source address is not in the allowlist
+type RejectedSource struct {
+	Address  string
+	Count    int64
+	LastSeen time.Time
+}
@@ -0,0 +1,51 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/navikt/zrooms/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactPayload(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected string
+	}{
+		{
+			name:     "ParticipantDetails",
+			payload:  `{"event":"meeting.participant_joined","payload":{"account_id":"abc","operator":"admin@example.com","object":{"id":"123","participant":{"id":"p1","user_id":"16778240","user_name":"Ola Nordmann","email":"ola@example.com","public_ip":"10.0.0.1"}}},"event_ts":1620123456789}`,
+			expected: `{"event":"meeting.participant_joined","event_ts":1620123456789,"payload":{"account_id":"abc","object":{"id":"123","participant":{"email":"[redacted]","id":"p1","public_ip":"[redacted]","user_id":"[redacted]","user_name":"[redacted]"}},"operator":"[redacted]"}}`,
+		},
+		{
+			name:     "FieldsInArrays",
+			payload:  `{"participants":[{"user_name":"Kari"},{"user_name":""}]}`,
+			expected: `{"participants":[{"user_name":"[redacted]"},{"user_name":""}]}`,
+		},
+		{
+			name:     "NumbersAreKeptExactly",
+			payload:  `{"id":85746065432123456}`,
+			expected: `{"id":85746065432123456}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.JSONEq(t, tt.expected, string(models.RedactPayload(json.RawMessage(tt.payload))))
+		})
+	}
+
+	t.Run("InvalidJSON", func(t *testing.T) {
+		assert.Nil(t, models.RedactPayload(json.RawMessage(`not json`)))
+	})
+}
+
+func TestStripPayload(t *testing.T) {
+	payload := `{"account_id":"abc","operator":"admin@example.com","object":{"id":85746065432123456,"host_id":"h1","participants":[{"id":"p1","user_id":"16778240","user_name":"Ola Nordmann","email":"ola@example.com"}]}}`
+	expected := `{"account_id":"abc","object":{"id":85746065432123456,"participants":[{"id":"p1"}]}}`
+	assert.JSONEq(t, expected, string(models.StripPayload(json.RawMessage(payload))))
+
+	assert.Nil(t, models.StripPayload(json.RawMessage(`not json`)))
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// WebhookSecretMatch records which webhook secret verified a received event,
+// so an admin can tell when a previous secret is no longer in use
+type WebhookSecretMatch struct {
+	ReceivedAt  time.Time
+	Event       string
+	MeetingID   string
+	AccountName string
+	SecretName  string
+}
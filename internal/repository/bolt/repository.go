@@ -0,0 +1,935 @@
+// Package bolt provides an embedded on-disk implementation of the repository interface, backed by bbolt.
+// It keeps state across restarts on single-node deployments without Redis.
+package bolt
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/navikt/zrooms/internal/config"
+	"github.com/navikt/zrooms/internal/models"
+	"github.com/navikt/zrooms/internal/repository/schema"
+	"go.etcd.io/bbolt"
+)
+
+// Common errors
+var (
+	ErrNotFound = schema.ErrNotFound
+)
+
+// openTimeout is how long to wait for the database file lock, held by another process using the same file
+const openTimeout = 30 * time.Second
+
+// journalBatchSize is the number of journal entries read per transaction during replay
+const journalBatchSize = 500
+
+// journalMaxLen bounds the journal when meetings never expire, so no age can be used to trim it
+const journalMaxLen = 100000
+
+// Top-level buckets
+var (
+	meetingsBucket           = []byte("meetings")
+	roomsBucket              = []byte("rooms")
+	deviceAlertsBucket       = []byte("devicealerts")
+	webhooksBucket           = []byte("webhooks")
+	webhookExpiriesBucket    = []byte("webhookexpiries") // Expiry followed by webhook key, so expired keys are found in order
+	deadLettersBucket        = []byte("deadletters")
+	deadLetterFailuresBucket = []byte("deadletterfailures") // Failure time followed by dead letter ID, so the oldest are found first
+	journalBucket            = []byte("journal")
+)
+
+// Each meeting has a bucket in the meetings bucket, holding its record, expiry and live indicators
+// next to buckets with the IDs of its participants. Deleting the meeting's bucket removes all of it at once.
+var (
+	recordKey          = []byte("record")
+	expiresKey         = []byte("expires")
+	recordingKey       = []byte("recording")
+	sharingKey         = []byte("sharing")
+	participantsBucket = []byte("participants")
+	waitingBucket      = []byte("waiting")
+	breakoutsBucket    = []byte("breakouts") // Participant ID -> ID of the breakout room they are in
+	// Participant ID -> event_ts of their latest join or leave. Created on first use, as older meetings lack it.
+	participantEventsBucket = []byte("participantevents")
+)
+
+// Repository implements the repository interface with an embedded bbolt database
+type Repository struct {
+	db  *bbolt.DB
+	ttl time.Duration
+}
+
+// NewRepository opens or creates the database file
+func NewRepository(cfg config.BoltConfig) (*Repository, error) {
+	db, err := bbolt.Open(cfg.File, 0o600, &bbolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{meetingsBucket, roomsBucket, deviceAlertsBucket, webhooksBucket, webhookExpiriesBucket, deadLettersBucket, deadLetterFailuresBucket, journalBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create buckets: %w", err)
+	}
+
+	return &Repository{
+		db:  db,
+		ttl: cfg.MeetingTTL,
+	}, nil
+}
+
+// Close closes the database
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+// liveMeeting returns the bucket of a meeting, or nil when it does not exist or has expired
+func liveMeeting(meetings *bbolt.Bucket, id []byte, now time.Time) *bbolt.Bucket {
+	b := meetings.Bucket(id)
+	if b == nil || expired(b, now) {
+		return nil
+	}
+	return b
+}
+
+// expired reports whether a meeting's bucket has expired
+func expired(b *bbolt.Bucket, now time.Time) bool {
+	value := b.Get(expiresKey)
+	return len(value) == 8 && now.UnixNano() >= int64(binary.BigEndian.Uint64(value))
+}
+
+// touch extends a meeting's expiry to the TTL from now
+func (r *Repository) touch(b *bbolt.Bucket, now time.Time) error {
+	if r.ttl <= 0 {
+		return nil
+	}
+	return b.Put(expiresKey, binary.BigEndian.AppendUint64(nil, uint64(now.Add(r.ttl).UnixNano())))
+}
+
+// updateMeeting runs fn on the bucket of a meeting in a write transaction, extending its expiry.
+// ErrNotFound is returned when the meeting does not exist.
+func (r *Repository) updateMeeting(id string, fn func(b *bbolt.Bucket) error) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		now := time.Now()
+		b := liveMeeting(tx.Bucket(meetingsBucket), []byte(id), now)
+		if b == nil {
+			return ErrNotFound
+		}
+		if err := fn(b); err != nil {
+			return err
+		}
+		return r.touch(b, now)
+	})
+}
+
+// viewMeeting runs fn on the bucket of a meeting in a read transaction.
+// ErrNotFound is returned when the meeting does not exist.
+func (r *Repository) viewMeeting(id string, fn func(b *bbolt.Bucket) error) error {
+	return r.db.View(func(tx *bbolt.Tx) error {
+		b := liveMeeting(tx.Bucket(meetingsBucket), []byte(id), time.Now())
+		if b == nil {
+			return ErrNotFound
+		}
+		return fn(b)
+	})
+}
+
+// readMeeting converts a meeting's bucket to a meeting model, with the live indicators but without participant details
+func readMeeting(b *bbolt.Bucket) (*models.Meeting, error) {
+	record, _, err := schema.DecodeMeetingRecord(b.Get(recordKey))
+	if err != nil {
+		return nil, err
+	}
+
+	meeting := record.Meeting()
+	meeting.Recording = models.RecordingStatus(b.Get(recordingKey))
+	meeting.Sharing = len(b.Get(sharingKey)) > 0
+	return meeting, nil
+}
+
+// SaveMeeting saves meeting state information to the repository, merging it into the stored record
+func (r *Repository) SaveMeeting(ctx context.Context, meeting *models.Meeting) error {
+	err := r.db.Update(func(tx *bbolt.Tx) error {
+		now := time.Now()
+		meetings := tx.Bucket(meetingsBucket)
+
+		record := schema.NewMeetingRecord(meeting)
+		b := liveMeeting(meetings, []byte(meeting.ID), now)
+		if b != nil {
+			stored, _, err := schema.DecodeMeetingRecord(b.Get(recordKey))
+			if err != nil {
+				return fmt.Errorf("failed to read stored meeting: %w", err)
+			}
+			stored.Merge(meeting)
+			record = stored
+		} else {
+			// Expired meetings are removed when a new meeting is created, so the database stays bounded by the TTL
+			if err := purgeExpired(meetings, now); err != nil {
+				return err
+			}
+			var err error
+			if b, err = newMeetingBucket(meetings, meeting.ID); err != nil {
+				return err
+			}
+		}
+
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to marshal meeting: %w", err)
+		}
+		if err := b.Put(recordKey, data); err != nil {
+			return err
+		}
+		return r.touch(b, now)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save meeting: %w", err)
+	}
+
+	return nil
+}
+
+// newMeetingBucket creates the bucket of a meeting with its participant buckets
+func newMeetingBucket(meetings *bbolt.Bucket, id string) (*bbolt.Bucket, error) {
+	b, err := meetings.CreateBucket([]byte(id))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range [][]byte{participantsBucket, waitingBucket, breakoutsBucket} {
+		if _, err := b.CreateBucket(name); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// purgeExpired removes the buckets of expired meetings
+func purgeExpired(meetings *bbolt.Bucket, now time.Time) error {
+	var ids [][]byte
+	err := meetings.ForEachBucket(func(id []byte) error {
+		if expired(meetings.Bucket(id), now) {
+			ids = append(ids, append([]byte(nil), id...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := meetings.DeleteBucket(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetMeeting retrieves a meeting by ID
+func (r *Repository) GetMeeting(ctx context.Context, id string) (*models.Meeting, error) {
+	var meeting *models.Meeting
+	err := r.viewMeeting(id, func(b *bbolt.Bucket) error {
+		var err error
+		meeting, err = readMeeting(b)
+		return err
+	})
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read meeting: %w", err)
+	}
+
+	return meeting, nil
+}
+
+// ListMeetings returns all active meetings (not ended)
+func (r *Repository) ListMeetings(ctx context.Context) ([]*models.Meeting, error) {
+	meetings, err := r.ListAllMeetings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	active := make([]*models.Meeting, 0, len(meetings))
+	for _, meeting := range meetings {
+		if meeting.Status != models.MeetingStatusEnded {
+			active = append(active, meeting)
+		}
+	}
+	return active, nil
+}
+
+// ListAllMeetings returns all meetings, including ended ones
+func (r *Repository) ListAllMeetings(ctx context.Context) ([]*models.Meeting, error) {
+	meetings := []*models.Meeting{}
+	err := r.db.View(func(tx *bbolt.Tx) error {
+		now := time.Now()
+		bucket := tx.Bucket(meetingsBucket)
+		return bucket.ForEachBucket(func(id []byte) error {
+			b := liveMeeting(bucket, id, now)
+			if b == nil {
+				return nil
+			}
+
+			// Unreadable records are reported by CheckMeetingRecords at startup
+			meeting, err := readMeeting(b)
+			if err != nil {
+				return nil
+			}
+			meetings = append(meetings, meeting)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list meetings: %w", err)
+	}
+
+	return meetings, nil
+}
+
+// DeleteMeeting removes a meeting by ID
+func (r *Repository) DeleteMeeting(ctx context.Context, id string) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		meetings := tx.Bucket(meetingsBucket)
+		if liveMeeting(meetings, []byte(id), time.Now()) == nil {
+			return ErrNotFound
+		}
+		return meetings.DeleteBucket([]byte(id))
+	})
+}
+
+// addMember adds an ID to one of a meeting's participant buckets
+func (r *Repository) addMember(meetingID string, name []byte, participantID, value string) error {
+	return r.updateMeeting(meetingID, func(b *bbolt.Bucket) error {
+		return b.Bucket(name).Put([]byte(participantID), []byte(value))
+	})
+}
+
+// removeMember removes an ID from one of a meeting's participant buckets
+func (r *Repository) removeMember(meetingID string, name []byte, participantID string) error {
+	return r.updateMeeting(meetingID, func(b *bbolt.Bucket) error {
+		return b.Bucket(name).Delete([]byte(participantID))
+	})
+}
+
+// countMembers counts the IDs in one of a meeting's participant buckets
+func (r *Repository) countMembers(meetingID string, name []byte) (int, error) {
+	count := 0
+	err := r.viewMeeting(meetingID, func(b *bbolt.Bucket) error {
+		return b.Bucket(name).ForEach(func(_, _ []byte) error {
+			count++
+			return nil
+		})
+	})
+	return count, err
+}
+
+// clearMembers empties one of a meeting's participant buckets
+func (r *Repository) clearMembers(meetingID string, name []byte) error {
+	return r.updateMeeting(meetingID, func(b *bbolt.Bucket) error {
+		if err := b.DeleteBucket(name); err != nil {
+			return err
+		}
+		_, err := b.CreateBucket(name)
+		return err
+	})
+}
+
+// AddParticipantToMeeting adds a participant ID to a meeting
+func (r *Repository) AddParticipantToMeeting(ctx context.Context, meetingID, participantID string) error {
+	return r.addMember(meetingID, participantsBucket, participantID, "")
+}
+
+// RemoveParticipantFromMeeting removes a participant ID from a meeting and from their breakout room
+func (r *Repository) RemoveParticipantFromMeeting(ctx context.Context, meetingID, participantID string) error {
+	return r.updateMeeting(meetingID, func(b *bbolt.Bucket) error {
+		if err := b.Bucket(participantsBucket).Delete([]byte(participantID)); err != nil {
+			return err
+		}
+		return b.Bucket(breakoutsBucket).Delete([]byte(participantID))
+	})
+}
+
+// RecordParticipantEvent records the event_ts of the latest join or leave applied for a participant.
+// Returns false when eventTS is older than the recorded one.
+func (r *Repository) RecordParticipantEvent(ctx context.Context, meetingID, participantID string, eventTS int64) (bool, error) {
+	recorded := false
+	err := r.updateMeeting(meetingID, func(b *bbolt.Bucket) error {
+		events, err := b.CreateBucketIfNotExists(participantEventsBucket)
+		if err != nil {
+			return err
+		}
+		if last := events.Get([]byte(participantID)); len(last) == 8 && eventTS < int64(binary.BigEndian.Uint64(last)) {
+			return nil
+		}
+		recorded = true
+		return events.Put([]byte(participantID), binary.BigEndian.AppendUint64(nil, uint64(eventTS)))
+	})
+	return recorded, err
+}
+
+// CountParticipantsInMeeting counts the number of participants in a meeting
+func (r *Repository) CountParticipantsInMeeting(ctx context.Context, meetingID string) (int, error) {
+	return r.countMembers(meetingID, participantsBucket)
+}
+
+// ClearPartipantsInMeeting removes all participants from a meeting
+func (r *Repository) ClearPartipantsInMeeting(ctx context.Context, meetingID string) error {
+	return r.clearMembers(meetingID, participantsBucket)
+}
+
+// AddParticipantToWaitingRoom adds a participant ID to a meeting's waiting room
+func (r *Repository) AddParticipantToWaitingRoom(ctx context.Context, meetingID, participantID string) error {
+	return r.addMember(meetingID, waitingBucket, participantID, "")
+}
+
+// RemoveParticipantFromWaitingRoom removes a participant ID from a meeting's waiting room
+func (r *Repository) RemoveParticipantFromWaitingRoom(ctx context.Context, meetingID, participantID string) error {
+	return r.removeMember(meetingID, waitingBucket, participantID)
+}
+
+// CountParticipantsInWaitingRoom counts the number of participants waiting to be admitted to a meeting
+func (r *Repository) CountParticipantsInWaitingRoom(ctx context.Context, meetingID string) (int, error) {
+	return r.countMembers(meetingID, waitingBucket)
+}
+
+// ClearWaitingRoom removes all participants from a meeting's waiting room
+func (r *Repository) ClearWaitingRoom(ctx context.Context, meetingID string) error {
+	return r.clearMembers(meetingID, waitingBucket)
+}
+
+// AddParticipantToBreakoutRoom records that a participant is in a breakout room of a meeting,
+// moving them out of any other breakout room
+func (r *Repository) AddParticipantToBreakoutRoom(ctx context.Context, meetingID, breakoutRoomID, participantID string) error {
+	return r.addMember(meetingID, breakoutsBucket, participantID, breakoutRoomID)
+}
+
+// RemoveParticipantFromBreakoutRoom removes a participant from a breakout room of a meeting.
+// Does nothing if the participant has since moved to another breakout room.
+func (r *Repository) RemoveParticipantFromBreakoutRoom(ctx context.Context, meetingID, breakoutRoomID, participantID string) error {
+	return r.updateMeeting(meetingID, func(b *bbolt.Bucket) error {
+		breakouts := b.Bucket(breakoutsBucket)
+		if string(breakouts.Get([]byte(participantID))) != breakoutRoomID {
+			return nil
+		}
+		return breakouts.Delete([]byte(participantID))
+	})
+}
+
+// ListBreakoutRooms returns the breakout rooms of a meeting that have participants, sorted by ID
+func (r *Repository) ListBreakoutRooms(ctx context.Context, meetingID string) ([]models.BreakoutRoom, error) {
+	counts := make(map[string]int)
+	err := r.viewMeeting(meetingID, func(b *bbolt.Bucket) error {
+		return b.Bucket(breakoutsBucket).ForEach(func(_, roomID []byte) error {
+			counts[string(roomID)]++
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rooms := make([]models.BreakoutRoom, 0, len(counts))
+	for roomID, count := range counts {
+		rooms = append(rooms, models.BreakoutRoom{ID: roomID, ParticipantCount: count})
+	}
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].ID < rooms[j].ID
+	})
+
+	return rooms, nil
+}
+
+// ClearBreakoutRooms removes all participants from the breakout rooms of a meeting
+func (r *Repository) ClearBreakoutRooms(ctx context.Context, meetingID string) error {
+	return r.clearMembers(meetingID, breakoutsBucket)
+}
+
+// setIndicator sets or, for an empty value, removes one of a meeting's indicators
+func (r *Repository) setIndicator(meetingID string, key []byte, value string) error {
+	return r.updateMeeting(meetingID, func(b *bbolt.Bucket) error {
+		if value == "" {
+			return b.Delete(key)
+		}
+		return b.Put(key, []byte(value))
+	})
+}
+
+// SetMeetingRecording sets whether a meeting is being recorded
+func (r *Repository) SetMeetingRecording(ctx context.Context, meetingID string, recording models.RecordingStatus) error {
+	return r.setIndicator(meetingID, recordingKey, string(recording))
+}
+
+// SetMeetingSharing sets whether a participant is sharing their screen in a meeting
+func (r *Repository) SetMeetingSharing(ctx context.Context, meetingID string, sharing bool) error {
+	value := ""
+	if sharing {
+		value = "1"
+	}
+	return r.setIndicator(meetingID, sharingKey, value)
+}
+
+// ClearMeetings removes all meetings and their participants
+func (r *Repository) ClearMeetings(ctx context.Context) error {
+	return r.clearBucket(meetingsBucket)
+}
+
+// CheckMeetingRecords reads every stored meeting record and reports the ones that are outdated or cannot be read.
+// With migrate set, outdated records are written back with the current layout.
+func (r *Repository) CheckMeetingRecords(ctx context.Context, migrate bool) (*schema.CheckReport, error) {
+	report := &schema.CheckReport{}
+	check := func(tx *bbolt.Tx) error {
+		meetings := tx.Bucket(meetingsBucket)
+		return meetings.ForEachBucket(func(id []byte) error {
+			b := meetings.Bucket(id)
+			report.Checked++
+
+			record, upgraded, err := schema.DecodeMeetingRecord(b.Get(recordKey))
+			if err != nil {
+				report.Unreadable = append(report.Unreadable, schema.UnreadableRecord{Key: string(id), Error: err.Error()})
+				return nil
+			}
+			if !upgraded {
+				return nil
+			}
+			report.Outdated++
+
+			if !migrate {
+				return nil
+			}
+			data, err := json.Marshal(record)
+			if err != nil {
+				return fmt.Errorf("failed to marshal meeting: %w", err)
+			}
+			if err := b.Put(recordKey, data); err != nil {
+				return err
+			}
+			report.Migrated++
+			return nil
+		})
+	}
+
+	var err error
+	if migrate {
+		err = r.db.Update(check)
+	} else {
+		err = r.db.View(check)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check meeting records: %w", err)
+	}
+
+	return report, nil
+}
+
+// putJSON stores a value as JSON under a key in a top-level bucket
+func (r *Repository) putJSON(bucket []byte, key string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(key), data)
+	})
+}
+
+// getJSON reads a JSON value stored under a key in a top-level bucket
+func (r *Repository) getJSON(bucket []byte, key string, value any) error {
+	return r.db.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(bucket).Get([]byte(key))
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, value)
+	})
+}
+
+// forEachJSON calls fn with each JSON value in a top-level bucket
+func (r *Repository) forEachJSON(bucket []byte, fn func(data []byte) error) error {
+	return r.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(_, data []byte) error {
+			return fn(data)
+		})
+	})
+}
+
+// clearBucket removes everything in a top-level bucket
+func (r *Repository) clearBucket(bucket []byte) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		if err := tx.DeleteBucket(bucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(bucket)
+		return err
+	})
+}
+
+// SaveRoom stores or replaces the state of a room.
+// Rooms are physical and long-lived, so unlike meetings they do not expire.
+func (r *Repository) SaveRoom(ctx context.Context, room *models.Room) error {
+	if err := r.putJSON(roomsBucket, room.ID, room); err != nil {
+		return fmt.Errorf("failed to save room: %w", err)
+	}
+	return nil
+}
+
+// GetRoom retrieves a room by ID
+func (r *Repository) GetRoom(ctx context.Context, id string) (*models.Room, error) {
+	var room models.Room
+	if err := r.getJSON(roomsBucket, id, &room); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get room: %w", err)
+	}
+	return &room, nil
+}
+
+// ListRooms returns all rooms
+func (r *Repository) ListRooms(ctx context.Context) ([]*models.Room, error) {
+	rooms := []*models.Room{}
+	err := r.forEachJSON(roomsBucket, func(data []byte) error {
+		var room models.Room
+		if err := json.Unmarshal(data, &room); err != nil {
+			return nil
+		}
+		rooms = append(rooms, &room)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rooms: %w", err)
+	}
+	return rooms, nil
+}
+
+// ClearRooms removes all rooms
+func (r *Repository) ClearRooms(ctx context.Context) error {
+	if err := r.clearBucket(roomsBucket); err != nil {
+		return fmt.Errorf("failed to delete rooms: %w", err)
+	}
+	return nil
+}
+
+// SaveDeviceAlert stores or replaces a device alert
+func (r *Repository) SaveDeviceAlert(ctx context.Context, alert *models.DeviceAlert) error {
+	if err := r.putJSON(deviceAlertsBucket, alert.ID, alert); err != nil {
+		return fmt.Errorf("failed to save device alert: %w", err)
+	}
+	return nil
+}
+
+// GetDeviceAlert retrieves a device alert by ID
+func (r *Repository) GetDeviceAlert(ctx context.Context, id string) (*models.DeviceAlert, error) {
+	var alert models.DeviceAlert
+	if err := r.getJSON(deviceAlertsBucket, id, &alert); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get device alert: %w", err)
+	}
+	return &alert, nil
+}
+
+// ListDeviceAlerts returns all device alerts, both active and cleared
+func (r *Repository) ListDeviceAlerts(ctx context.Context) ([]*models.DeviceAlert, error) {
+	alerts := []*models.DeviceAlert{}
+	err := r.forEachJSON(deviceAlertsBucket, func(data []byte) error {
+		var alert models.DeviceAlert
+		if err := json.Unmarshal(data, &alert); err != nil {
+			return nil
+		}
+		alerts = append(alerts, &alert)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list device alerts: %w", err)
+	}
+	return alerts, nil
+}
+
+// ClearDeviceAlerts removes all device alerts
+func (r *Repository) ClearDeviceAlerts(ctx context.Context) error {
+	if err := r.clearBucket(deviceAlertsBucket); err != nil {
+		return fmt.Errorf("failed to delete device alerts: %w", err)
+	}
+	return nil
+}
+
+// MarkWebhookSeen records a webhook key for the given TTL
+// Returns true if the key was not seen before (or its previous entry has expired)
+func (r *Repository) MarkWebhookSeen(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	first := false
+	err := r.db.Update(func(tx *bbolt.Tx) error {
+		now := time.Now()
+		bucket := tx.Bucket(webhooksBucket)
+		if err := pruneWebhooks(bucket, tx.Bucket(webhookExpiriesBucket), now); err != nil {
+			return err
+		}
+
+		if bucket.Get([]byte(key)) != nil {
+			return nil
+		}
+		first = true
+		expiry := binary.BigEndian.AppendUint64(nil, uint64(now.Add(ttl).UnixNano()))
+		if err := bucket.Put([]byte(key), expiry); err != nil {
+			return err
+		}
+		return tx.Bucket(webhookExpiriesBucket).Put(append(expiry, key...), nil)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to mark webhook as seen: %w", err)
+	}
+	return first, nil
+}
+
+// pruneWebhooks deletes expired webhook keys so the bucket stays bounded by the TTL window.
+// Only expired entries of the expiry index are visited; a key unmarked and marked again since
+// has a later expiry and is kept.
+func pruneWebhooks(bucket, expiries *bbolt.Bucket, now time.Time) error {
+	cursor := expiries.Cursor()
+	for k, _ := cursor.First(); len(k) >= 8; k, _ = cursor.First() {
+		if int64(binary.BigEndian.Uint64(k)) > now.UnixNano() {
+			return nil
+		}
+		key := append([]byte(nil), k[8:]...)
+		if value := bucket.Get(key); len(value) == 8 && now.UnixNano() >= int64(binary.BigEndian.Uint64(value)) {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		if err := cursor.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnmarkWebhookSeen removes a webhook key so a later delivery with the same key is accepted
+func (r *Repository) UnmarkWebhookSeen(ctx context.Context, key string) error {
+	err := r.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(webhooksBucket).Delete([]byte(key))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unmark webhook: %w", err)
+	}
+	return nil
+}
+
+// SaveDeadLetter stores or replaces a dead letter.
+// The oldest failures are dropped once models.DeadLetterCapacity dead letters are kept.
+func (r *Repository) SaveDeadLetter(ctx context.Context, deadLetter *models.DeadLetter) error {
+	data, err := json.Marshal(deadLetter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	err = r.db.Update(func(tx *bbolt.Tx) error {
+		bucket, failures := tx.Bucket(deadLettersBucket), tx.Bucket(deadLetterFailuresBucket)
+		if err := deleteDeadLetter(bucket, failures, deadLetter.ID); err != nil {
+			return err
+		}
+		if err := bucket.Put([]byte(deadLetter.ID), data); err != nil {
+			return err
+		}
+		if err := failures.Put(deadLetterFailureKey(deadLetter.FailedAt, deadLetter.ID), nil); err != nil {
+			return err
+		}
+		return trimDeadLetters(bucket, failures)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save dead letter: %w", err)
+	}
+	return nil
+}
+
+// deadLetterFailureKey returns the failure index key of a dead letter
+func deadLetterFailureKey(failedAt time.Time, id string) []byte {
+	return append(binary.BigEndian.AppendUint64(nil, uint64(failedAt.UnixNano())), id...)
+}
+
+// deleteDeadLetter removes a dead letter and its failure index entry, if it is stored
+func deleteDeadLetter(bucket, failures *bbolt.Bucket, id string) error {
+	data := bucket.Get([]byte(id))
+	if data == nil {
+		return nil
+	}
+
+	var stored models.DeadLetter
+	if err := json.Unmarshal(data, &stored); err == nil {
+		if err := failures.Delete(deadLetterFailureKey(stored.FailedAt, id)); err != nil {
+			return err
+		}
+	}
+	return bucket.Delete([]byte(id))
+}
+
+// trimDeadLetters deletes the oldest failures until models.DeadLetterCapacity dead letters are indexed
+func trimDeadLetters(bucket, failures *bbolt.Bucket) error {
+	count := 0
+	cursor := failures.Cursor()
+	for k, _ := cursor.First(); k != nil; k, _ = cursor.Next() {
+		count++
+	}
+
+	for k, _ := cursor.First(); len(k) >= 8 && count > models.DeadLetterCapacity; k, _ = cursor.First() {
+		id := append([]byte(nil), k[8:]...)
+		if err := bucket.Delete(id); err != nil {
+			return err
+		}
+		if err := cursor.Delete(); err != nil {
+			return err
+		}
+		count--
+	}
+	return nil
+}
+
+// GetDeadLetter retrieves a dead letter by ID
+func (r *Repository) GetDeadLetter(ctx context.Context, id string) (*models.DeadLetter, error) {
+	var deadLetter models.DeadLetter
+	if err := r.getJSON(deadLettersBucket, id, &deadLetter); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+	return &deadLetter, nil
+}
+
+// ListDeadLetters returns all dead letters, most recent failure first
+func (r *Repository) ListDeadLetters(ctx context.Context) ([]*models.DeadLetter, error) {
+	deadLetters := []*models.DeadLetter{}
+	err := r.forEachJSON(deadLettersBucket, func(data []byte) error {
+		var deadLetter models.DeadLetter
+		if err := json.Unmarshal(data, &deadLetter); err != nil {
+			return nil
+		}
+		deadLetters = append(deadLetters, &deadLetter)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	sort.Slice(deadLetters, func(i, j int) bool {
+		return deadLetters[i].FailedAt.After(deadLetters[j].FailedAt)
+	})
+
+	return deadLetters, nil
+}
+
+// DeleteDeadLetter removes a dead letter by ID
+func (r *Repository) DeleteDeadLetter(ctx context.Context, id string) error {
+	return r.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(deadLettersBucket)
+		if bucket.Get([]byte(id)) == nil {
+			return ErrNotFound
+		}
+		return deleteDeadLetter(bucket, tx.Bucket(deadLetterFailuresBucket), id)
+	})
+}
+
+// AppendEvent adds a webhook event to the end of the journal. Entries older than the meeting TTL are deleted,
+// as they can no longer describe a stored meeting, or the journal is capped by length without a TTL.
+func (r *Repository) AppendEvent(ctx context.Context, event *models.WebhookEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal event: %w", err)
+	}
+
+	err = r.db.Update(func(tx *bbolt.Tx) error {
+		now := time.Now()
+		bucket := tx.Bucket(journalBucket)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		if err := r.trimJournal(bucket, seq, now); err != nil {
+			return err
+		}
+		return bucket.Put(journalEntryKey(seq, now), data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to append journal event: %w", err)
+	}
+	return nil
+}
+
+// journalEntryKey returns the key of a journal entry: its sequence number, so entries are kept in the order
+// they were appended, followed by the time it was appended
+func journalEntryKey(seq uint64, appended time.Time) []byte {
+	key := binary.BigEndian.AppendUint64(nil, seq)
+	return binary.BigEndian.AppendUint64(key, uint64(appended.UnixNano()))
+}
+
+// trimJournal deletes the oldest journal entries before the entry with sequence number seq is added.
+// Only entries at the start of the journal are visited, so the cost is bounded by the number deleted.
+func (r *Repository) trimJournal(bucket *bbolt.Bucket, seq uint64, now time.Time) error {
+	cursor := bucket.Cursor()
+	for k, _ := cursor.First(); len(k) == 16; k, _ = cursor.First() {
+		if r.ttl > 0 {
+			if appended := int64(binary.BigEndian.Uint64(k[8:])); appended > now.Add(-r.ttl).UnixNano() {
+				return nil
+			}
+		} else if binary.BigEndian.Uint64(k)+journalMaxLen > seq {
+			return nil
+		}
+		if err := cursor.Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReplayEvents calls fn for each journaled event in the order they were appended.
+// Events are read in batches and no transaction is open while fn runs, so fn may use the repository.
+func (r *Repository) ReplayEvents(ctx context.Context, fn func(event *models.WebhookEvent) error) error {
+	var after []byte
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var batch []models.WebhookEvent
+		err := r.db.View(func(tx *bbolt.Tx) error {
+			cursor := tx.Bucket(journalBucket).Cursor()
+			k, v := cursor.First()
+			if after != nil {
+				if k, v = cursor.Seek(after); k != nil && string(k) == string(after) {
+					k, v = cursor.Next()
+				}
+			}
+			for ; k != nil && len(batch) < journalBatchSize; k, v = cursor.Next() {
+				var event models.WebhookEvent
+				if err := json.Unmarshal(v, &event); err != nil {
+					return fmt.Errorf("failed to parse journal entry %d: %w", binary.BigEndian.Uint64(k), err)
+				}
+				batch = append(batch, event)
+				after = append(after[:0], k...)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read event journal: %w", err)
+		}
+
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+		if len(batch) < journalBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package bolt_test
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/navikt/zrooms/internal/config"
+	"github.com/navikt/zrooms/internal/models"
+	"github.com/navikt/zrooms/internal/repository"
+	"github.com/navikt/zrooms/internal/repository/bolt"
+	"github.com/navikt/zrooms/internal/repository/repositorytest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func openTestRepository(t *testing.T, cfg config.BoltConfig) *bolt.Repository {
+	repo, err := bolt.NewRepository(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func testConfig(t *testing.T) config.BoltConfig {
+	return config.BoltConfig{
+		File:       filepath.Join(t.TempDir(), "zrooms.db"),
+		MeetingTTL: 24 * time.Hour,
+	}
+}
+
+func TestRepositorySuite(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.Repository {
+		return openTestRepository(t, testConfig(t))
+	}, bolt.ErrNotFound)
+}
+
+func TestStateSurvivesRestart(t *testing.T) {
+	cfg := testConfig(t)
+	ctx := context.Background()
+
+	repo, err := bolt.NewRepository(cfg)
+	require.NoError(t, err)
+	require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "100", Topic: "Standup", Status: models.MeetingStatusStarted, OperatorEmail: "operator@example.com"}))
+	require.NoError(t, repo.AddParticipantToMeeting(ctx, "100", "user1"))
+	require.NoError(t, repo.SetMeetingSharing(ctx, "100", true))
+	require.NoError(t, repo.SaveRoom(ctx, &models.Room{ID: "room1", Name: "Oslo"}))
+	require.NoError(t, repo.AppendEvent(ctx, &models.WebhookEvent{Event: "meeting.started", Payload: json.RawMessage(`{}`), EventTS: 1}))
+	require.NoError(t, repo.Close())
+
+	repo = openTestRepository(t, cfg)
+
+	meeting, err := repo.GetMeeting(ctx, "100")
+	require.NoError(t, err)
+	assert.Equal(t, "Standup", meeting.Topic)
+	assert.Equal(t, "operator@example.com", meeting.OperatorEmail)
+	assert.True(t, meeting.Sharing)
+
+	count, err := repo.CountParticipantsInMeeting(ctx, "100")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	room, err := repo.GetRoom(ctx, "room1")
+	require.NoError(t, err)
+	assert.Equal(t, "Oslo", room.Name)
+
+	replayed := 0
+	require.NoError(t, repo.ReplayEvents(ctx, func(event *models.WebhookEvent) error {
+		replayed++
+		return nil
+	}))
+	assert.Equal(t, 1, replayed)
+}
+
+func TestMeetingExpiry(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.MeetingTTL = 50 * time.Millisecond
+	repo := openTestRepository(t, cfg)
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "100", Topic: "Old", Status: models.MeetingStatusEnded}))
+	require.NoError(t, repo.AddParticipantToMeeting(ctx, "100", "user1"))
+	time.Sleep(100 * time.Millisecond)
+
+	_, err := repo.GetMeeting(ctx, "100")
+	assert.ErrorIs(t, err, bolt.ErrNotFound)
+	assert.ErrorIs(t, repo.AddParticipantToMeeting(ctx, "100", "user2"), bolt.ErrNotFound)
+	meetings, err := repo.ListAllMeetings(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, meetings)
+
+	// A meeting saved after expiry starts afresh, without the expired participants
+	require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "100", Status: models.MeetingStatusStarted}))
+	meeting, err := repo.GetMeeting(ctx, "100")
+	require.NoError(t, err)
+	assert.Empty(t, meeting.Topic)
+	count, err := repo.CountParticipantsInMeeting(ctx, "100")
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestEventJournalIsTrimmedByAge(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.MeetingTTL = 50 * time.Millisecond
+	repo := openTestRepository(t, cfg)
+	ctx := context.Background()
+
+	// An event journaled longer ago than the meeting TTL is deleted when the next one is appended
+	require.NoError(t, repo.AppendEvent(ctx, &models.WebhookEvent{Event: "meeting.started", EventTS: 1}))
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, repo.AppendEvent(ctx, &models.WebhookEvent{Event: "meeting.ended", EventTS: 2}))
+
+	var replayed []string
+	require.NoError(t, repo.ReplayEvents(ctx, func(event *models.WebhookEvent) error {
+		replayed = append(replayed, event.Event)
+		return nil
+	}))
+	assert.Equal(t, []string{"meeting.ended"}, replayed)
+}
+
+func TestMarkWebhookSeenPrunesExpiredKeys(t *testing.T) {
+	cfg := testConfig(t)
+	ctx := context.Background()
+
+	repo, err := bolt.NewRepository(cfg)
+	require.NoError(t, err)
+	_, err = repo.MarkWebhookSeen(ctx, "short", 20*time.Millisecond)
+	require.NoError(t, err)
+	_, err = repo.MarkWebhookSeen(ctx, "remarked", 20*time.Millisecond)
+	require.NoError(t, err)
+	require.NoError(t, repo.UnmarkWebhookSeen(ctx, "remarked"))
+	_, err = repo.MarkWebhookSeen(ctx, "remarked", time.Minute)
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+
+	_, err = repo.MarkWebhookSeen(ctx, "other", time.Minute)
+	require.NoError(t, err)
+	first, err := repo.MarkWebhookSeen(ctx, "remarked", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, first, "Key marked again should keep its later expiry")
+	require.NoError(t, repo.Close())
+
+	// The expired key was deleted when the next key was marked
+	db, err := bbolt.Open(cfg.File, 0o600, nil)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.View(func(tx *bbolt.Tx) error {
+		assert.Nil(t, tx.Bucket([]byte("webhooks")).Get([]byte("short")))
+		assert.Equal(t, 2, tx.Bucket([]byte("webhooks")).Stats().KeyN)
+		assert.Equal(t, 2, tx.Bucket([]byte("webhookexpiries")).Stats().KeyN)
+		return nil
+	}))
+}
+
+func TestReplayEventsInBatches(t *testing.T) {
+	repo := openTestRepository(t, testConfig(t))
+	ctx := context.Background()
+
+	const total = 1234
+	for i := range total {
+		require.NoError(t, repo.AppendEvent(ctx, &models.WebhookEvent{Event: "meeting.started", Payload: json.RawMessage(`{}`), EventTS: int64(i)}))
+	}
+
+	next := int64(0)
+	require.NoError(t, repo.ReplayEvents(ctx, func(event *models.WebhookEvent) error {
+		assert.Equal(t, next, event.EventTS)
+		next++
+		return nil
+	}))
+	assert.Equal(t, int64(total), next)
+}
+
+func TestCheckMeetingRecords(t *testing.T) {
+	cfg := testConfig(t)
+	ctx := context.Background()
+
+	repo, err := bolt.NewRepository(cfg)
+	require.NoError(t, err)
+	require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "current", Status: models.MeetingStatusStarted}))
+	require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "legacy", Status: models.MeetingStatusStarted}))
+	require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "broken", Status: models.MeetingStatusStarted}))
+	require.NoError(t, repo.Close())
+
+	// Replace records with an unversioned and an unreadable one
+	db, err := bbolt.Open(cfg.File, 0o600, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(func(tx *bbolt.Tx) error {
+		meetings := tx.Bucket([]byte("meetings"))
+		if err := meetings.Bucket([]byte("legacy")).Put([]byte("record"), []byte(`{"ID":"legacy","Topic":"Legacy","Status":2,"ParticipantIDs":null}`)); err != nil {
+			return err
+		}
+		return meetings.Bucket([]byte("broken")).Put([]byte("record"), []byte(`{"ID":`))
+	}))
+	require.NoError(t, db.Close())
+
+	repo = openTestRepository(t, cfg)
+
+	report, err := repo.CheckMeetingRecords(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Checked)
+	assert.Equal(t, 1, report.Outdated)
+	require.Len(t, report.Unreadable, 1)
+	assert.Equal(t, "broken", report.Unreadable[0].Key)
+
+	meeting, err := repo.GetMeeting(ctx, "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, "Legacy", meeting.Topic)
+
+	report, err = repo.CheckMeetingRecords(ctx, true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Migrated)
+
+	report, err = repo.CheckMeetingRecords(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Outdated)
+}
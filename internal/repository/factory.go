@@ -3,6 +3,7 @@ package repository
 
 import (
 	"github.com/navikt/zrooms/internal/config"
+	"github.com/navikt/zrooms/internal/repository/bolt"
 	"github.com/navikt/zrooms/internal/repository/memory"
 	"github.com/navikt/zrooms/internal/repository/redis"
 )
@@ -14,8 +15,17 @@ func init() {
 		return redis.NewRepository(cfg)
 	}
 
+	// Register the embedded repository constructor
+	newBoltRepository = func(cfg config.BoltConfig) (Repository, error) {
+		return bolt.NewRepository(cfg)
+	}
+
 	// Register the memory repository constructor
-	newMemoryRepository = func() Repository {
-		return memory.NewRepository()
+	newMemoryRepository = func(journalFile string) (Repository, error) {
+		// Without a journal file the journal is only kept in memory
+		if journalFile == "" {
+			return memory.NewRepository(), nil
+		}
+		return memory.NewRepositoryWithJournal(journalFile)
 	}
 }
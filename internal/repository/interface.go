@@ -5,11 +5,16 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/navikt/zrooms/internal/config"
 	"github.com/navikt/zrooms/internal/models"
+	"github.com/navikt/zrooms/internal/repository/schema"
 )
 
+// ErrNotFound is returned by every backend when a requested entity is not found
+var ErrNotFound = schema.ErrNotFound
+
 // Repository defines the interface for storing and retrieving meeting data
 type Repository interface {
 	// Meeting operations
@@ -24,10 +29,70 @@ type Repository interface {
 	RemoveParticipantFromMeeting(ctx context.Context, meetingID string, participantID string) error
 	CountParticipantsInMeeting(ctx context.Context, meetingID string) (int, error)
 	ClearPartipantsInMeeting(ctx context.Context, meetingID string) error
+	// RecordParticipantEvent records the event_ts of the latest join or leave applied for a participant, so a
+	// join or leave delivered out of order is noticed. Returns false, recording nothing, when eventTS is older.
+	RecordParticipantEvent(ctx context.Context, meetingID string, participantID string, eventTS int64) (bool, error)
+
+	// Waiting room operations - participants waiting to be admitted, only stores IDs
+	AddParticipantToWaitingRoom(ctx context.Context, meetingID string, participantID string) error
+	RemoveParticipantFromWaitingRoom(ctx context.Context, meetingID string, participantID string) error
+	CountParticipantsInWaitingRoom(ctx context.Context, meetingID string) (int, error)
+	ClearWaitingRoom(ctx context.Context, meetingID string) error
+
+	// Breakout room operations - which breakout room of a meeting each participant is in, only stores IDs.
+	// A participant is in at most one breakout room; adding them to another moves them, and removing them
+	// from the meeting also removes them from their breakout room.
+	AddParticipantToBreakoutRoom(ctx context.Context, meetingID string, breakoutRoomID string, participantID string) error
+	RemoveParticipantFromBreakoutRoom(ctx context.Context, meetingID string, breakoutRoomID string, participantID string) error
+	ListBreakoutRooms(ctx context.Context, meetingID string) ([]models.BreakoutRoom, error)
+	ClearBreakoutRooms(ctx context.Context, meetingID string) error
+
+	// Indicator operations - whether a meeting is being recorded or has a screen shared.
+	// Kept apart from the meeting lifecycle, so saving the meeting does not reset them.
+	SetMeetingRecording(ctx context.Context, meetingID string, recording models.RecordingStatus) error
+	SetMeetingSharing(ctx context.Context, meetingID string, sharing bool) error
+
+	// Room operations - state of physical Zoom Rooms
+	SaveRoom(ctx context.Context, room *models.Room) error
+	GetRoom(ctx context.Context, id string) (*models.Room, error)
+	ListRooms(ctx context.Context) ([]*models.Room, error)
+	ClearRooms(ctx context.Context) error
+
+	// Device health operations - alerts for Zoom Room devices
+	SaveDeviceAlert(ctx context.Context, alert *models.DeviceAlert) error
+	GetDeviceAlert(ctx context.Context, id string) (*models.DeviceAlert, error)
+	ListDeviceAlerts(ctx context.Context) ([]*models.DeviceAlert, error)
+	ClearDeviceAlerts(ctx context.Context) error
+
+	// Webhook delivery tracking - used to detect replayed requests
+	// MarkWebhookSeen records the key for the given TTL and reports whether it was seen for the first time
+	MarkWebhookSeen(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	UnmarkWebhookSeen(ctx context.Context, key string) error
+
+	// Dead-letter operations - webhook events that could not be applied
+	SaveDeadLetter(ctx context.Context, deadLetter *models.DeadLetter) error
+	GetDeadLetter(ctx context.Context, id string) (*models.DeadLetter, error)
+	ListDeadLetters(ctx context.Context) ([]*models.DeadLetter, error)
+	DeleteDeadLetter(ctx context.Context, id string) error
+
+	// Event journal - append-only log of every accepted webhook event, used to rebuild state
+	AppendEvent(ctx context.Context, event *models.WebhookEvent) error
+	// ReplayEvents calls fn for each journaled event in the order they were appended
+	ReplayEvents(ctx context.Context, fn func(event *models.WebhookEvent) error) error
+	// ClearMeetings removes all meetings and their participants so they can be rebuilt from the journal
+	ClearMeetings(ctx context.Context) error
+}
+
+// MeetingRecordChecker is implemented by backends that persist meeting records across restarts
+type MeetingRecordChecker interface {
+	// CheckMeetingRecords reports stored meeting records that are outdated or cannot be read,
+	// writing outdated ones back with the current layout when migrate is set
+	CheckMeetingRecords(ctx context.Context, migrate bool) (*schema.CheckReport, error)
 }
 
-// NewRepository creates a repository based on configuration
-func NewRepository(cfg config.RedisConfig) (Repository, error) {
+// NewRepository creates a repository based on configuration. Redis is used when enabled, then the
+// embedded on-disk store when a file is configured, and otherwise state is only kept in memory.
+func NewRepository(cfg config.RedisConfig, boltCfg config.BoltConfig, journalCfg config.JournalConfig) (Repository, error) {
 	if cfg.Enabled {
 		// Format the address based on host and port if not using URI
 		connectionInfo := cfg.URI
@@ -43,8 +108,25 @@ func NewRepository(cfg config.RedisConfig) (Repository, error) {
 		return repo, nil
 	}
 
-	log.Printf("Using in-memory repository")
-	return newMemoryRepository(), nil
+	if boltCfg.Enabled() {
+		log.Printf("Using embedded repository at %s", boltCfg.File)
+		repo, err := newBoltRepository(boltCfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create embedded repository: %w", err)
+		}
+		return repo, nil
+	}
+
+	if journalCfg.File != "" {
+		log.Printf("Using in-memory repository with event journal at %s", journalCfg.File)
+	} else {
+		log.Printf("Using in-memory repository")
+	}
+	repo, err := newMemoryRepository(journalCfg.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create in-memory repository: %w", err)
+	}
+	return repo, nil
 }
 
 // Implementation constructors are imported dynamically to avoid circular dependencies
@@ -55,7 +137,12 @@ var newRedisRepository = func(cfg config.RedisConfig) (Repository, error) {
 	return nil, fmt.Errorf("Redis repository not implemented")
 }
 
-var newMemoryRepository = func() Repository {
+var newBoltRepository = func(cfg config.BoltConfig) (Repository, error) {
+	// This function will be replaced by the actual implementation from bolt package
+	return nil, fmt.Errorf("embedded repository not implemented")
+}
+
+var newMemoryRepository = func(journalFile string) (Repository, error) {
 	// This function will be replaced by the actual implementation from memory package
-	return nil
+	return nil, fmt.Errorf("in-memory repository not implemented")
 }
@@ -2,39 +2,142 @@
 package memory
 
 import (
+	"bufio"
+	"container/heap"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/navikt/zrooms/internal/models"
+	"github.com/navikt/zrooms/internal/repository/schema"
 )
 
 // ErrNotFound is returned when a requested entity is not found
-var ErrNotFound = errors.New("entity not found")
+var ErrNotFound = schema.ErrNotFound
+
+// JournalCapacity is the number of most recent events kept when the journal is only held in memory
+const JournalCapacity = 1000
+
+// JournalFileCapacity is the number of events written to a journal file before it is rotated. The previous
+// file is kept with a ".1" suffix, so between one and two times this many of the most recent events are kept.
+const JournalFileCapacity = 100000
 
 // MeetingState contains information about a meeting's state
 type MeetingState struct {
-	ID             string // Meeting ID
-	Topic          string // Meeting Topic
-	Status         models.MeetingStatus
-	StartTime      time.Time
-	EndTime        time.Time
-	ParticipantIDs map[string]struct{} // Store only participant IDs
-	OperatorEmail  string              // Email of the user who created/updated the meeting
+	schema.MeetingRecord                        // Persisted meeting state shared with the other backends
+	ParticipantIDs       map[string]struct{}    // Store only participant IDs
+	WaitingIDs           map[string]struct{}    // Participants in the waiting room, only IDs
+	BreakoutIDs          map[string]string      // Participant ID -> ID of the breakout room they are in
+	ParticipantEventTS   map[string]int64       // Participant ID -> event_ts of their latest join or leave
+	Recording            models.RecordingStatus // Whether the meeting is being recorded
+	Sharing              bool                   // Whether a participant is sharing their screen
+}
+
+// meeting converts the state to a meeting model, with the live indicators but without participant details
+func (s *MeetingState) meeting() *models.Meeting {
+	meeting := s.MeetingRecord.Meeting()
+	meeting.Recording = s.Recording
+	meeting.Sharing = s.Sharing
+	return meeting
 }
 
 // Repository implements the repository interface with in-memory storage
 type Repository struct {
-	meetingStates map[string]*MeetingState // Stores meeting state data
+	meetingStates map[string]*MeetingState       // Stores meeting state data
+	rooms         map[string]*models.Room        // Stores Zoom Room state
+	deviceAlerts  map[string]*models.DeviceAlert // Stores Zoom Room device alerts
+	seenWebhooks  map[string]time.Time           // Recently seen webhook keys and their expiry
+	seenExpiries  expiryHeap                     // Seen webhook keys ordered by expiry, to prune expired keys
+	deadLetters   map[string]*models.DeadLetter
 	mu            sync.RWMutex
+
+	// Event journal, kept in memory unless a journal file is configured.
+	// The in-memory journal is a ring of the most recent events, overwritten from journalNext once full.
+	journal      []models.WebhookEvent
+	journalNext  int
+	journalFile  *os.File
+	journalPath  string
+	journalLines int // Events in the current journal file
+	journalMu    sync.Mutex
 }
 
 // NewRepository creates a new in-memory repository
 func NewRepository() *Repository {
 	return &Repository{
 		meetingStates: make(map[string]*MeetingState),
+		rooms:         make(map[string]*models.Room),
+		deviceAlerts:  make(map[string]*models.DeviceAlert),
+		seenWebhooks:  make(map[string]time.Time),
+		deadLetters:   make(map[string]*models.DeadLetter),
+	}
+}
+
+// NewRepositoryWithJournal creates a new in-memory repository that appends the event journal
+// to a file, one JSON-encoded event per line, so state can be rebuilt after a restart
+func NewRepositoryWithJournal(path string) (*Repository, error) {
+	lines, err := countJournalLines(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event journal: %w", err)
+	}
+
+	r := NewRepository()
+	r.journalFile = file
+	r.journalPath = path
+	r.journalLines = lines
+	return r, nil
+}
+
+// countJournalLines counts the events in an existing journal file, so rotation continues where it left off
+func countJournalLines(path string) (int, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open event journal: %w", err)
+	}
+	defer file.Close()
+
+	lines := 0
+	reader := bufio.NewReader(file)
+	for {
+		_, err := reader.ReadSlice('\n')
+		if err == nil {
+			lines++
+			continue
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return lines, nil
+		}
+		return 0, fmt.Errorf("failed to read event journal: %w", err)
+	}
+}
+
+// Close closes the journal file if one is open
+func (r *Repository) Close() error {
+	r.journalMu.Lock()
+	defer r.journalMu.Unlock()
+
+	if r.journalFile == nil {
+		return nil
 	}
+	err := r.journalFile.Close()
+	r.journalFile = nil
+	return err
 }
 
 // SaveMeeting saves meeting state information to the repository
@@ -42,37 +145,18 @@ func (r *Repository) SaveMeeting(ctx context.Context, meeting *models.Meeting) e
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	// Check if the meeting state already exists
-	state, exists := r.meetingStates[meeting.ID]
-	if !exists {
-		// Create a new meeting state with minimal data
-		state = &MeetingState{
-			ID:             meeting.ID,
-			Topic:          meeting.Topic,
-			Status:         meeting.Status,
-			StartTime:      meeting.StartTime,
-			ParticipantIDs: make(map[string]struct{}),
-			OperatorEmail:  meeting.OperatorEmail,
-		}
-		r.meetingStates[meeting.ID] = state
-	} else {
-		// Update existing meeting state
-		state.Status = meeting.Status
-
-		// Only update topic if it's provided and not empty
-		if meeting.Topic != "" {
-			state.Topic = meeting.Topic
-		}
-
-		// Update operator email if provided
-		if meeting.OperatorEmail != "" {
-			state.OperatorEmail = meeting.OperatorEmail
-		}
+	// Merge into the existing meeting state, as events only carry some of the meeting's fields
+	if state, exists := r.meetingStates[meeting.ID]; exists {
+		state.Merge(meeting)
+		return nil
+	}
 
-		// Set end time if the meeting has ended
-		if meeting.Status == models.MeetingStatusEnded {
-			state.EndTime = meeting.EndTime
-		}
+	r.meetingStates[meeting.ID] = &MeetingState{
+		MeetingRecord:      *schema.NewMeetingRecord(meeting),
+		ParticipantIDs:     make(map[string]struct{}),
+		WaitingIDs:         make(map[string]struct{}),
+		BreakoutIDs:        make(map[string]string),
+		ParticipantEventTS: make(map[string]int64),
 	}
 
 	return nil
@@ -88,18 +172,7 @@ func (r *Repository) GetMeeting(ctx context.Context, id string) (*models.Meeting
 		return nil, ErrNotFound
 	}
 
-	// Convert state back to a Meeting model with only the necessary data
-	meeting := &models.Meeting{
-		ID:            state.ID,
-		Topic:         state.Topic,
-		Status:        state.Status,
-		StartTime:     state.StartTime,
-		EndTime:       state.EndTime,
-		OperatorEmail: state.OperatorEmail,
-		Participants:  []models.Participant{}, // Empty slice, we don't store participant details
-	}
-
-	return meeting, nil
+	return state.meeting(), nil
 }
 
 // ListMeetings returns all active meetings with minimal information
@@ -112,16 +185,7 @@ func (r *Repository) ListMeetings(ctx context.Context) ([]*models.Meeting, error
 	for _, state := range r.meetingStates {
 		// Only include active meetings (not ended) for backward compatibility
 		if state.Status != models.MeetingStatusEnded {
-			meeting := &models.Meeting{
-				ID:            state.ID,
-				Topic:         state.Topic,
-				Status:        state.Status,
-				StartTime:     state.StartTime,
-				EndTime:       state.EndTime,
-				OperatorEmail: state.OperatorEmail,
-				Participants:  []models.Participant{}, // Empty slice, we don't store participant details
-			}
-			meetings = append(meetings, meeting)
+			meetings = append(meetings, state.meeting())
 		}
 	}
 
@@ -136,16 +200,7 @@ func (r *Repository) ListAllMeetings(ctx context.Context) ([]*models.Meeting, er
 	meetings := make([]*models.Meeting, 0, len(r.meetingStates))
 	for _, state := range r.meetingStates {
 		// Include all meetings, including ended ones
-		meeting := &models.Meeting{
-			ID:            state.ID,
-			Topic:         state.Topic,
-			Status:        state.Status,
-			StartTime:     state.StartTime,
-			EndTime:       state.EndTime,
-			OperatorEmail: state.OperatorEmail,
-			Participants:  []models.Participant{}, // Empty slice, we don't store participant details
-		}
-		meetings = append(meetings, meeting)
+		meetings = append(meetings, state.meeting())
 	}
 
 	return meetings, nil
@@ -185,7 +240,7 @@ func (r *Repository) AddParticipantToMeeting(ctx context.Context, meetingID stri
 	return nil
 }
 
-// RemoveParticipantFromMeeting removes a participant ID from a meeting
+// RemoveParticipantFromMeeting removes a participant ID from a meeting and from their breakout room
 func (r *Repository) RemoveParticipantFromMeeting(ctx context.Context, meetingID string, participantID string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -196,12 +251,31 @@ func (r *Repository) RemoveParticipantFromMeeting(ctx context.Context, meetingID
 		return ErrNotFound
 	}
 
-	// Remove participant ID from the meeting
+	// Remove participant ID from the meeting, including any breakout room they left the meeting from
 	delete(state.ParticipantIDs, participantID)
+	delete(state.BreakoutIDs, participantID)
 
 	return nil
 }
 
+// RecordParticipantEvent records the event_ts of the latest join or leave applied for a participant.
+// Returns false when eventTS is older than the recorded one.
+func (r *Repository) RecordParticipantEvent(ctx context.Context, meetingID string, participantID string, eventTS int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	state, ok := r.meetingStates[meetingID]
+	if !ok {
+		return false, ErrNotFound
+	}
+
+	if eventTS < state.ParticipantEventTS[participantID] {
+		return false, nil
+	}
+	state.ParticipantEventTS[participantID] = eventTS
+	return true, nil
+}
+
 // CountParticipantsInMeeting counts the number of participants in a meeting
 func (r *Repository) CountParticipantsInMeeting(ctx context.Context, meetingID string) (int, error) {
 	r.mu.RLock()
@@ -231,3 +305,526 @@ func (r *Repository) ClearPartipantsInMeeting(ctx context.Context, meetingID str
 
 	return nil
 }
+
+// AddParticipantToWaitingRoom adds a participant ID to a meeting's waiting room
+func (r *Repository) AddParticipantToWaitingRoom(ctx context.Context, meetingID string, participantID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Check if meeting exists
+	state, ok := r.meetingStates[meetingID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	state.WaitingIDs[participantID] = struct{}{}
+
+	return nil
+}
+
+// RemoveParticipantFromWaitingRoom removes a participant ID from a meeting's waiting room
+func (r *Repository) RemoveParticipantFromWaitingRoom(ctx context.Context, meetingID string, participantID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Check if meeting exists
+	state, ok := r.meetingStates[meetingID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	delete(state.WaitingIDs, participantID)
+
+	return nil
+}
+
+// CountParticipantsInWaitingRoom counts the number of participants waiting to be admitted to a meeting
+func (r *Repository) CountParticipantsInWaitingRoom(ctx context.Context, meetingID string) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Check if meeting exists
+	state, ok := r.meetingStates[meetingID]
+	if !ok {
+		return 0, ErrNotFound
+	}
+
+	return len(state.WaitingIDs), nil
+}
+
+// ClearWaitingRoom removes all participants from a meeting's waiting room
+func (r *Repository) ClearWaitingRoom(ctx context.Context, meetingID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Check if meeting exists
+	state, ok := r.meetingStates[meetingID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	state.WaitingIDs = make(map[string]struct{})
+
+	return nil
+}
+
+// SetMeetingRecording sets whether a meeting is being recorded
+func (r *Repository) SetMeetingRecording(ctx context.Context, meetingID string, recording models.RecordingStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Check if meeting exists
+	state, ok := r.meetingStates[meetingID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	state.Recording = recording
+
+	return nil
+}
+
+// SetMeetingSharing sets whether a participant is sharing their screen in a meeting
+func (r *Repository) SetMeetingSharing(ctx context.Context, meetingID string, sharing bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Check if meeting exists
+	state, ok := r.meetingStates[meetingID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	state.Sharing = sharing
+
+	return nil
+}
+
+// AddParticipantToBreakoutRoom records that a participant is in a breakout room of a meeting,
+// moving them out of any other breakout room
+func (r *Repository) AddParticipantToBreakoutRoom(ctx context.Context, meetingID string, breakoutRoomID string, participantID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Check if meeting exists
+	state, ok := r.meetingStates[meetingID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	state.BreakoutIDs[participantID] = breakoutRoomID
+
+	return nil
+}
+
+// RemoveParticipantFromBreakoutRoom removes a participant from a breakout room of a meeting.
+// Does nothing if the participant has since moved to another breakout room.
+func (r *Repository) RemoveParticipantFromBreakoutRoom(ctx context.Context, meetingID string, breakoutRoomID string, participantID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Check if meeting exists
+	state, ok := r.meetingStates[meetingID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if state.BreakoutIDs[participantID] == breakoutRoomID {
+		delete(state.BreakoutIDs, participantID)
+	}
+
+	return nil
+}
+
+// ListBreakoutRooms returns the breakout rooms of a meeting that have participants, sorted by ID
+func (r *Repository) ListBreakoutRooms(ctx context.Context, meetingID string) ([]models.BreakoutRoom, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Check if meeting exists
+	state, ok := r.meetingStates[meetingID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return breakoutRooms(state.BreakoutIDs), nil
+}
+
+// ClearBreakoutRooms removes all participants from the breakout rooms of a meeting
+func (r *Repository) ClearBreakoutRooms(ctx context.Context, meetingID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Check if meeting exists
+	state, ok := r.meetingStates[meetingID]
+	if !ok {
+		return ErrNotFound
+	}
+
+	state.BreakoutIDs = make(map[string]string)
+
+	return nil
+}
+
+// breakoutRooms counts the participants in each breakout room from a participant -> room mapping
+func breakoutRooms(breakoutIDs map[string]string) []models.BreakoutRoom {
+	counts := make(map[string]int)
+	for _, roomID := range breakoutIDs {
+		counts[roomID]++
+	}
+
+	rooms := make([]models.BreakoutRoom, 0, len(counts))
+	for roomID, count := range counts {
+		rooms = append(rooms, models.BreakoutRoom{ID: roomID, ParticipantCount: count})
+	}
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].ID < rooms[j].ID
+	})
+
+	return rooms
+}
+
+// SaveRoom stores or replaces the state of a room
+func (r *Repository) SaveRoom(ctx context.Context, room *models.Room) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *room
+	r.rooms[room.ID] = &stored
+	return nil
+}
+
+// GetRoom retrieves a room by ID
+func (r *Repository) GetRoom(ctx context.Context, id string) (*models.Room, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	room, ok := r.rooms[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	result := *room
+	return &result, nil
+}
+
+// ListRooms returns all rooms
+func (r *Repository) ListRooms(ctx context.Context) ([]*models.Room, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rooms := make([]*models.Room, 0, len(r.rooms))
+	for _, room := range r.rooms {
+		result := *room
+		rooms = append(rooms, &result)
+	}
+
+	return rooms, nil
+}
+
+// ClearRooms removes all rooms
+func (r *Repository) ClearRooms(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rooms = make(map[string]*models.Room)
+	return nil
+}
+
+// SaveDeviceAlert stores or replaces a device alert
+func (r *Repository) SaveDeviceAlert(ctx context.Context, alert *models.DeviceAlert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *alert
+	r.deviceAlerts[alert.ID] = &stored
+	return nil
+}
+
+// GetDeviceAlert retrieves a device alert by ID
+func (r *Repository) GetDeviceAlert(ctx context.Context, id string) (*models.DeviceAlert, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	alert, ok := r.deviceAlerts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	result := *alert
+	return &result, nil
+}
+
+// ListDeviceAlerts returns all device alerts, both active and cleared
+func (r *Repository) ListDeviceAlerts(ctx context.Context) ([]*models.DeviceAlert, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	alerts := make([]*models.DeviceAlert, 0, len(r.deviceAlerts))
+	for _, alert := range r.deviceAlerts {
+		result := *alert
+		alerts = append(alerts, &result)
+	}
+
+	return alerts, nil
+}
+
+// ClearDeviceAlerts removes all device alerts
+func (r *Repository) ClearDeviceAlerts(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.deviceAlerts = make(map[string]*models.DeviceAlert)
+	return nil
+}
+
+// seenWebhook is a webhook key with the expiry it was marked with
+type seenWebhook struct {
+	key    string
+	expiry time.Time
+}
+
+// expiryHeap is a min-heap of seen webhook keys, soonest expiry first, implementing heap.Interface
+type expiryHeap []seenWebhook
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].expiry.Before(h[j].expiry) }
+func (h expiryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *expiryHeap) Push(x any)        { *h = append(*h, x.(seenWebhook)) }
+func (h *expiryHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return entry
+}
+
+// MarkWebhookSeen records a webhook key for the given TTL
+// Returns true if the key was not seen before (or its previous entry has expired)
+func (r *Repository) MarkWebhookSeen(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+
+	// Prune expired entries so the map stays bounded by the TTL window. Only expired entries are visited;
+	// a key unmarked and marked again since has a later expiry in the map and is kept.
+	for len(r.seenExpiries) > 0 && now.After(r.seenExpiries[0].expiry) {
+		entry := heap.Pop(&r.seenExpiries).(seenWebhook)
+		if expiry, ok := r.seenWebhooks[entry.key]; ok && now.After(expiry) {
+			delete(r.seenWebhooks, entry.key)
+		}
+	}
+
+	if _, seen := r.seenWebhooks[key]; seen {
+		return false, nil
+	}
+
+	r.seenWebhooks[key] = now.Add(ttl)
+	heap.Push(&r.seenExpiries, seenWebhook{key: key, expiry: now.Add(ttl)})
+	return true, nil
+}
+
+// UnmarkWebhookSeen removes a webhook key so a later delivery with the same key is accepted
+func (r *Repository) UnmarkWebhookSeen(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.seenWebhooks, key)
+	return nil
+}
+
+// SaveDeadLetter stores or replaces a dead letter.
+// The oldest failure is dropped once models.DeadLetterCapacity dead letters are kept.
+func (r *Repository) SaveDeadLetter(ctx context.Context, deadLetter *models.DeadLetter) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.deadLetters[deadLetter.ID]; !exists && len(r.deadLetters) >= models.DeadLetterCapacity {
+		var oldest *models.DeadLetter
+		for _, stored := range r.deadLetters {
+			if oldest == nil || stored.FailedAt.Before(oldest.FailedAt) {
+				oldest = stored
+			}
+		}
+		delete(r.deadLetters, oldest.ID)
+	}
+
+	stored := *deadLetter
+	r.deadLetters[deadLetter.ID] = &stored
+	return nil
+}
+
+// GetDeadLetter retrieves a dead letter by ID
+func (r *Repository) GetDeadLetter(ctx context.Context, id string) (*models.DeadLetter, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deadLetter, ok := r.deadLetters[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	result := *deadLetter
+	return &result, nil
+}
+
+// ListDeadLetters returns all dead letters, most recent failure first
+func (r *Repository) ListDeadLetters(ctx context.Context) ([]*models.DeadLetter, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deadLetters := make([]*models.DeadLetter, 0, len(r.deadLetters))
+	for _, deadLetter := range r.deadLetters {
+		result := *deadLetter
+		deadLetters = append(deadLetters, &result)
+	}
+
+	sort.Slice(deadLetters, func(i, j int) bool {
+		return deadLetters[i].FailedAt.After(deadLetters[j].FailedAt)
+	})
+
+	return deadLetters, nil
+}
+
+// DeleteDeadLetter removes a dead letter by ID
+func (r *Repository) DeleteDeadLetter(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.deadLetters[id]; !ok {
+		return ErrNotFound
+	}
+
+	delete(r.deadLetters, id)
+	return nil
+}
+
+// ClearMeetings removes all meetings and their participants
+func (r *Repository) ClearMeetings(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.meetingStates = make(map[string]*MeetingState)
+	return nil
+}
+
+// AppendEvent adds a webhook event to the end of the journal.
+// Without a journal file, the oldest event is dropped once JournalCapacity events are kept.
+// A journal file is rotated once it holds JournalFileCapacity events.
+func (r *Repository) AppendEvent(ctx context.Context, event *models.WebhookEvent) error {
+	r.journalMu.Lock()
+	defer r.journalMu.Unlock()
+
+	if r.journalPath == "" {
+		if len(r.journal) < JournalCapacity {
+			r.journal = append(r.journal, *event)
+			return nil
+		}
+		r.journal[r.journalNext] = *event
+		r.journalNext = (r.journalNext + 1) % JournalCapacity
+		return nil
+	}
+
+	if r.journalFile == nil {
+		return errors.New("event journal is closed")
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal event: %w", err)
+	}
+	if _, err := r.journalFile.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal event: %w", err)
+	}
+
+	r.journalLines++
+	if r.journalLines >= JournalFileCapacity {
+		return r.rotateJournal()
+	}
+	return nil
+}
+
+// rotateJournal replaces the previous journal file with the current one and starts a new, empty file.
+// The caller must hold journalMu.
+func (r *Repository) rotateJournal() error {
+	if err := r.journalFile.Close(); err != nil {
+		return fmt.Errorf("failed to close event journal: %w", err)
+	}
+	r.journalFile = nil
+
+	if err := os.Rename(r.journalPath, r.journalPath+".1"); err != nil {
+		return fmt.Errorf("failed to rotate event journal: %w", err)
+	}
+
+	file, err := os.OpenFile(r.journalPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open event journal: %w", err)
+	}
+	r.journalFile = file
+	r.journalLines = 0
+	return nil
+}
+
+// ReplayEvents calls fn for each journaled event in the order they were appended.
+// The journal is not locked while fn runs, so fn may use the repository.
+func (r *Repository) ReplayEvents(ctx context.Context, fn func(event *models.WebhookEvent) error) error {
+	if r.journalPath == "" {
+		r.journalMu.Lock()
+		events := make([]models.WebhookEvent, 0, len(r.journal))
+		events = append(events, r.journal[r.journalNext:]...)
+		events = append(events, r.journal[:r.journalNext]...)
+		r.journalMu.Unlock()
+
+		for i := range events {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(&events[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// The rotated file holds the events written before the current one
+	if _, err := os.Stat(r.journalPath + ".1"); err == nil {
+		if err := replayJournalFile(ctx, r.journalPath+".1", fn); err != nil {
+			return err
+		}
+	}
+	return replayJournalFile(ctx, r.journalPath, fn)
+}
+
+// replayJournalFile calls fn for each event in a journal file
+func replayJournalFile(ctx context.Context, path string, fn func(event *models.WebhookEvent) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open event journal: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 2*1024*1024) // Webhook bodies are limited to 1MB
+	line := 0
+	for scanner.Scan() {
+		line++
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var event models.WebhookEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return fmt.Errorf("failed to parse line %d of %s: %w", line, path, err)
+		}
+		if err := fn(&event); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read event journal: %w", err)
+	}
+	return nil
+}
@@ -2,12 +2,18 @@ package memory_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/navikt/zrooms/internal/models"
+	"github.com/navikt/zrooms/internal/repository"
 	"github.com/navikt/zrooms/internal/repository/memory"
+	"github.com/navikt/zrooms/internal/repository/repositorytest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMeetingRepository(t *testing.T) {
@@ -137,3 +143,496 @@ func TestMeetingWithParticipants(t *testing.T) {
 		}
 	})
 }
+
+func TestMarkWebhookSeen(t *testing.T) {
+	repo := memory.NewRepository()
+	ctx := context.Background()
+
+	t.Run("FirstAndRepeatedKey", func(t *testing.T) {
+		firstSeen, err := repo.MarkWebhookSeen(ctx, "signature:abc", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, firstSeen)
+
+		firstSeen, err = repo.MarkWebhookSeen(ctx, "signature:abc", time.Minute)
+		assert.NoError(t, err)
+		assert.False(t, firstSeen, "Repeated key should be reported as seen")
+
+		firstSeen, err = repo.MarkWebhookSeen(ctx, "signature:def", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, firstSeen, "Different key should be accepted")
+	})
+
+	t.Run("ExpiredKeyIsAcceptedAgain", func(t *testing.T) {
+		_, err := repo.MarkWebhookSeen(ctx, "signature:short", 20*time.Millisecond)
+		require.NoError(t, err)
+		_, err = repo.MarkWebhookSeen(ctx, "signature:remarked", 20*time.Millisecond)
+		require.NoError(t, err)
+		require.NoError(t, repo.UnmarkWebhookSeen(ctx, "signature:remarked"))
+		_, err = repo.MarkWebhookSeen(ctx, "signature:remarked", time.Minute)
+		require.NoError(t, err)
+		time.Sleep(50 * time.Millisecond)
+
+		firstSeen, err := repo.MarkWebhookSeen(ctx, "signature:short", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, firstSeen, "Expired key should be accepted again")
+
+		firstSeen, err = repo.MarkWebhookSeen(ctx, "signature:remarked", time.Minute)
+		assert.NoError(t, err)
+		assert.False(t, firstSeen, "Key marked again should keep its later expiry")
+	})
+}
+
+func TestDeadLetterOperations(t *testing.T) {
+	repo := memory.NewRepository()
+	ctx := context.Background()
+
+	older := models.NewDeadLetter(models.WebhookEvent{
+		Event:   "meeting.participant_joined",
+		Payload: json.RawMessage(`{"object": {"id": "meeting123", "participant": {"id": "part1"}}}`),
+		EventTS: 1620123456789,
+	}, "meeting not found", 4)
+	older.FailedAt = time.Now().Add(-time.Minute)
+	newer := models.NewDeadLetter(models.WebhookEvent{Event: "meeting.started"}, "invalid payload", 1)
+
+	t.Run("SaveAndGet", func(t *testing.T) {
+		assert.NoError(t, repo.SaveDeadLetter(ctx, older))
+		assert.NoError(t, repo.SaveDeadLetter(ctx, newer))
+
+		stored, err := repo.GetDeadLetter(ctx, older.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "meeting.participant_joined", stored.Event.Event)
+		assert.Equal(t, "meeting123", stored.MeetingID())
+		assert.Equal(t, "meeting not found", stored.Reason)
+		assert.Equal(t, 4, stored.Attempts)
+	})
+
+	t.Run("ListMostRecentFirst", func(t *testing.T) {
+		deadLetters, err := repo.ListDeadLetters(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, deadLetters, 2)
+		assert.Equal(t, newer.ID, deadLetters[0].ID)
+		assert.Equal(t, older.ID, deadLetters[1].ID)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		assert.NoError(t, repo.DeleteDeadLetter(ctx, older.ID))
+
+		_, err := repo.GetDeadLetter(ctx, older.ID)
+		assert.ErrorIs(t, err, memory.ErrNotFound)
+		assert.ErrorIs(t, repo.DeleteDeadLetter(ctx, older.ID), memory.ErrNotFound)
+	})
+}
+
+func TestEventJournal(t *testing.T) {
+	repo := memory.NewRepository()
+	ctx := context.Background()
+
+	events := []*models.WebhookEvent{
+		{Event: "meeting.started", Payload: json.RawMessage(`{"object": {"id": "meeting123"}}`), EventTS: 1},
+		{Event: "meeting.participant_joined", Payload: json.RawMessage(`{"object": {"id": "meeting123", "participant": {"id": "part1"}}}`), EventTS: 2},
+		{Event: "meeting.ended", Payload: json.RawMessage(`{"object": {"id": "meeting123"}}`), EventTS: 3},
+	}
+	for _, event := range events {
+		assert.NoError(t, repo.AppendEvent(ctx, event))
+	}
+
+	t.Run("ReplayInOrder", func(t *testing.T) {
+		var replayed []string
+		err := repo.ReplayEvents(ctx, func(event *models.WebhookEvent) error {
+			replayed = append(replayed, event.Event)
+			assert.Equal(t, "meeting123", event.MeetingID())
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"meeting.started", "meeting.participant_joined", "meeting.ended"}, replayed)
+	})
+
+	t.Run("ReplayStopsOnError", func(t *testing.T) {
+		stop := errors.New("stop")
+		count := 0
+		err := repo.ReplayEvents(ctx, func(event *models.WebhookEvent) error {
+			count++
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("ClearMeetingsKeepsJournal", func(t *testing.T) {
+		assert.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "meeting123", Status: models.MeetingStatusStarted}))
+		assert.NoError(t, repo.AddParticipantToMeeting(ctx, "meeting123", "part1"))
+		assert.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "meeting456", Status: models.MeetingStatusEnded}))
+
+		assert.NoError(t, repo.ClearMeetings(ctx))
+
+		meetings, err := repo.ListAllMeetings(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, meetings)
+		_, err = repo.CountParticipantsInMeeting(ctx, "meeting123")
+		assert.ErrorIs(t, err, memory.ErrNotFound)
+
+		replayed := 0
+		assert.NoError(t, repo.ReplayEvents(ctx, func(event *models.WebhookEvent) error {
+			replayed++
+			return nil
+		}))
+		assert.Equal(t, 3, replayed)
+	})
+}
+
+func TestEventJournalIsBounded(t *testing.T) {
+	repo := memory.NewRepository()
+	ctx := context.Background()
+
+	const extra = 5
+	for i := range memory.JournalCapacity + extra {
+		require.NoError(t, repo.AppendEvent(ctx, &models.WebhookEvent{Event: "meeting.started", EventTS: int64(i)}))
+	}
+
+	// Only the most recent events are kept, still in the order they were appended
+	next := int64(extra)
+	require.NoError(t, repo.ReplayEvents(ctx, func(event *models.WebhookEvent) error {
+		assert.Equal(t, next, event.EventTS)
+		next++
+		return nil
+	}))
+	assert.Equal(t, int64(memory.JournalCapacity+extra), next)
+}
+
+func TestEventJournalFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	ctx := context.Background()
+
+	repo, err := memory.NewRepositoryWithJournal(path)
+	require.NoError(t, err)
+	require.NoError(t, repo.AppendEvent(ctx, &models.WebhookEvent{
+		Event:   "meeting.started",
+		Payload: json.RawMessage(`{"object": {"id": "meeting123"}}`),
+		EventTS: 1620123456789,
+	}))
+	require.NoError(t, repo.Close())
+
+	// A new repository on the same file sees the events written before the restart
+	reopened, err := memory.NewRepositoryWithJournal(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+	require.NoError(t, reopened.AppendEvent(ctx, &models.WebhookEvent{Event: "meeting.ended", EventTS: 1620123456999}))
+
+	var replayed []*models.WebhookEvent
+	err = reopened.ReplayEvents(ctx, func(event *models.WebhookEvent) error {
+		replayed = append(replayed, event)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, replayed, 2)
+	assert.Equal(t, "meeting.started", replayed[0].Event)
+	assert.Equal(t, "meeting123", replayed[0].MeetingID())
+	assert.Equal(t, int64(1620123456789), replayed[0].EventTS)
+	assert.Equal(t, "meeting.ended", replayed[1].Event)
+}
+
+func TestEventJournalFileIsRotated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	ctx := context.Background()
+
+	repo, err := memory.NewRepositoryWithJournal(path)
+	require.NoError(t, err)
+	for i := range memory.JournalFileCapacity - 1 {
+		require.NoError(t, repo.AppendEvent(ctx, &models.WebhookEvent{Event: "meeting.started", EventTS: int64(i)}))
+	}
+	require.NoError(t, repo.Close())
+
+	// The count of events in the file carries over a restart, so the next event fills it and rotates it
+	reopened, err := memory.NewRepositoryWithJournal(path)
+	require.NoError(t, err)
+	defer reopened.Close()
+	for i := memory.JournalFileCapacity - 1; i < 2*memory.JournalFileCapacity+3; i++ {
+		require.NoError(t, reopened.AppendEvent(ctx, &models.WebhookEvent{Event: "meeting.started", EventTS: int64(i)}))
+	}
+
+	// Only the rotated file and the current one are kept, still replayed in the order they were appended
+	next := int64(memory.JournalFileCapacity)
+	require.NoError(t, reopened.ReplayEvents(ctx, func(event *models.WebhookEvent) error {
+		assert.Equal(t, next, event.EventTS)
+		next++
+		return nil
+	}))
+	assert.Equal(t, int64(2*memory.JournalFileCapacity+3), next)
+}
+
+func TestRoomOperations(t *testing.T) {
+	repo := memory.NewRepository()
+	ctx := context.Background()
+
+	_, err := repo.GetRoom(ctx, "room1")
+	assert.ErrorIs(t, err, memory.ErrNotFound)
+
+	rooms, err := repo.ListRooms(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, rooms)
+
+	room := &models.Room{
+		ID:           "room1",
+		Name:         "Oslo",
+		Status:       models.RoomStatusInMeeting,
+		MeetingID:    "meeting123",
+		MeetingTopic: "Standup",
+		CheckedInAt:  time.UnixMilli(1620123456789).UTC(),
+		LastEventTS:  1620123456789,
+	}
+	assert.NoError(t, repo.SaveRoom(ctx, room))
+	assert.NoError(t, repo.SaveRoom(ctx, &models.Room{ID: "room2", Name: "Bergen"}))
+
+	stored, err := repo.GetRoom(ctx, "room1")
+	assert.NoError(t, err)
+	assert.Equal(t, room, stored)
+
+	// Saving again replaces the room state
+	room.Status = models.RoomStatusAvailable
+	room.MeetingID = ""
+	assert.NoError(t, repo.SaveRoom(ctx, room))
+	stored, err = repo.GetRoom(ctx, "room1")
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoomStatusAvailable, stored.Status)
+	assert.Empty(t, stored.MeetingID)
+
+	rooms, err = repo.ListRooms(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, rooms, 2)
+
+	assert.NoError(t, repo.ClearRooms(ctx))
+	rooms, err = repo.ListRooms(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, rooms)
+}
+
+func TestDeviceAlertOperations(t *testing.T) {
+	repo := memory.NewRepository()
+	ctx := context.Background()
+
+	_, err := repo.GetDeviceAlert(ctx, "room1:2:3")
+	assert.ErrorIs(t, err, memory.ErrNotFound)
+
+	alert := &models.DeviceAlert{
+		ID:          "room1:2:3",
+		RoomID:      "room1",
+		RoomName:    "Oslo",
+		Component:   models.DeviceComponentController,
+		AlertType:   3,
+		Issue:       "Controller is offline",
+		RaisedAt:    time.UnixMilli(1620123456789).UTC(),
+		LastEventTS: 1620123456789,
+	}
+	assert.NoError(t, repo.SaveDeviceAlert(ctx, alert))
+
+	stored, err := repo.GetDeviceAlert(ctx, alert.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, alert, stored)
+
+	// Clearing the alert replaces it
+	alert.ClearedAt = time.UnixMilli(1620123459999).UTC()
+	assert.NoError(t, repo.SaveDeviceAlert(ctx, alert))
+	assert.NoError(t, repo.SaveDeviceAlert(ctx, &models.DeviceAlert{ID: "room2:1:0", RoomID: "room2"}))
+
+	alerts, err := repo.ListDeviceAlerts(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, alerts, 2)
+
+	stored, err = repo.GetDeviceAlert(ctx, alert.ID)
+	assert.NoError(t, err)
+	assert.False(t, stored.IsActive())
+
+	assert.NoError(t, repo.ClearDeviceAlerts(ctx))
+	alerts, err = repo.ListDeviceAlerts(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, alerts)
+}
+
+func TestMeetingType(t *testing.T) {
+	repo := memory.NewRepository()
+	ctx := context.Background()
+
+	webinar := &models.Meeting{ID: "webinar123", Topic: "All Hands", Type: models.MeetingTypeWebinar, Status: models.MeetingStatusStarted}
+	assert.NoError(t, repo.SaveMeeting(ctx, webinar))
+
+	saved, err := repo.GetMeeting(ctx, webinar.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.MeetingTypeWebinar, saved.Type)
+	assert.True(t, saved.IsWebinar())
+
+	// Ending the webinar keeps its type
+	assert.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: webinar.ID, Topic: "All Hands", Type: models.MeetingTypeWebinar, Status: models.MeetingStatusEnded}))
+	meetings, err := repo.ListAllMeetings(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, meetings, 1)
+	assert.True(t, meetings[0].IsWebinar())
+}
+
+func TestWaitingRoomOperations(t *testing.T) {
+	repo := memory.NewRepository()
+	ctx := context.Background()
+
+	// Waiting room operations need the meeting to exist
+	_, err := repo.CountParticipantsInWaitingRoom(ctx, "missing")
+	assert.ErrorIs(t, err, memory.ErrNotFound)
+
+	meeting := &models.Meeting{ID: "meeting789", Status: models.MeetingStatusCreated}
+	assert.NoError(t, repo.SaveMeeting(ctx, meeting))
+
+	assert.NoError(t, repo.AddParticipantToWaitingRoom(ctx, meeting.ID, "user123"))
+	assert.NoError(t, repo.AddParticipantToWaitingRoom(ctx, meeting.ID, "user456"))
+	// Adding the same participant twice counts once
+	assert.NoError(t, repo.AddParticipantToWaitingRoom(ctx, meeting.ID, "user456"))
+
+	count, err := repo.CountParticipantsInWaitingRoom(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// Waiting participants are not counted as in the meeting
+	count, err = repo.CountParticipantsInMeeting(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	assert.NoError(t, repo.RemoveParticipantFromWaitingRoom(ctx, meeting.ID, "user123"))
+	count, err = repo.CountParticipantsInWaitingRoom(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	assert.NoError(t, repo.ClearWaitingRoom(ctx, meeting.ID))
+	count, err = repo.CountParticipantsInWaitingRoom(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestBreakoutRoomOperations(t *testing.T) {
+	repo := memory.NewRepository()
+	ctx := context.Background()
+
+	// Breakout room operations need the meeting to exist
+	_, err := repo.ListBreakoutRooms(ctx, "missing")
+	assert.ErrorIs(t, err, memory.ErrNotFound)
+
+	meeting := &models.Meeting{ID: "meeting321", Status: models.MeetingStatusStarted}
+	assert.NoError(t, repo.SaveMeeting(ctx, meeting))
+
+	rooms, err := repo.ListBreakoutRooms(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, rooms)
+
+	assert.NoError(t, repo.AddParticipantToBreakoutRoom(ctx, meeting.ID, "roomA", "user1"))
+	assert.NoError(t, repo.AddParticipantToBreakoutRoom(ctx, meeting.ID, "roomA", "user2"))
+	assert.NoError(t, repo.AddParticipantToBreakoutRoom(ctx, meeting.ID, "roomB", "user3"))
+
+	rooms, err = repo.ListBreakoutRooms(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.BreakoutRoom{{ID: "roomA", ParticipantCount: 2}, {ID: "roomB", ParticipantCount: 1}}, rooms)
+
+	// Joining another breakout room moves the participant
+	assert.NoError(t, repo.AddParticipantToBreakoutRoom(ctx, meeting.ID, "roomB", "user2"))
+	// A late leave of the previous room does not remove them from the new one
+	assert.NoError(t, repo.RemoveParticipantFromBreakoutRoom(ctx, meeting.ID, "roomA", "user2"))
+
+	rooms, err = repo.ListBreakoutRooms(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.BreakoutRoom{{ID: "roomA", ParticipantCount: 1}, {ID: "roomB", ParticipantCount: 2}}, rooms)
+
+	// Empty breakout rooms are not listed
+	assert.NoError(t, repo.RemoveParticipantFromBreakoutRoom(ctx, meeting.ID, "roomA", "user1"))
+	rooms, err = repo.ListBreakoutRooms(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.BreakoutRoom{{ID: "roomB", ParticipantCount: 2}}, rooms)
+
+	assert.NoError(t, repo.ClearBreakoutRooms(ctx, meeting.ID))
+	rooms, err = repo.ListBreakoutRooms(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, rooms)
+}
+
+func TestMeetingIndicators(t *testing.T) {
+	repo := memory.NewRepository()
+	ctx := context.Background()
+
+	// Indicators need the meeting to exist
+	assert.ErrorIs(t, repo.SetMeetingRecording(ctx, "missing", models.RecordingStatusRecording), memory.ErrNotFound)
+	assert.ErrorIs(t, repo.SetMeetingSharing(ctx, "missing", true), memory.ErrNotFound)
+
+	meeting := &models.Meeting{ID: "meeting654", Topic: "Demo", Status: models.MeetingStatusStarted}
+	assert.NoError(t, repo.SaveMeeting(ctx, meeting))
+
+	assert.NoError(t, repo.SetMeetingRecording(ctx, meeting.ID, models.RecordingStatusRecording))
+	assert.NoError(t, repo.SetMeetingSharing(ctx, meeting.ID, true))
+
+	saved, err := repo.GetMeeting(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RecordingStatusRecording, saved.Recording)
+	assert.True(t, saved.Sharing)
+
+	// Saving the meeting again does not reset the indicators
+	assert.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: meeting.ID, Topic: "Demo (renamed)", Status: models.MeetingStatusStarted}))
+	meetings, err := repo.ListMeetings(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, meetings, 1)
+	assert.True(t, meetings[0].IsRecording())
+	assert.True(t, meetings[0].Sharing)
+
+	assert.NoError(t, repo.SetMeetingRecording(ctx, meeting.ID, models.RecordingStatusPaused))
+	assert.NoError(t, repo.SetMeetingSharing(ctx, meeting.ID, false))
+	meetings, err = repo.ListAllMeetings(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, meetings, 1)
+	assert.Equal(t, models.RecordingStatusPaused, meetings[0].Recording)
+	assert.False(t, meetings[0].Sharing)
+
+	assert.NoError(t, repo.SetMeetingRecording(ctx, meeting.ID, models.RecordingStatusNone))
+	saved, err = repo.GetMeeting(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.False(t, saved.IsRecording())
+}
+
+func TestMeetingAccount(t *testing.T) {
+	repo := memory.NewRepository()
+	ctx := context.Background()
+
+	meeting := &models.Meeting{ID: models.MeetingKey("sandbox123", "meeting1"), AccountID: "sandbox123", Topic: "Sandbox Test", Status: models.MeetingStatusStarted}
+	assert.NoError(t, repo.SaveMeeting(ctx, meeting))
+	assert.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "meeting1", AccountID: "main123", Topic: "Main Test", Status: models.MeetingStatusStarted}))
+
+	saved, err := repo.GetMeeting(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "sandbox123", saved.AccountID)
+	assert.Equal(t, "Sandbox Test", saved.Topic)
+	assert.Equal(t, "meeting1", saved.ZoomID())
+
+	meetings, err := repo.ListMeetings(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, meetings, 2)
+}
+
+func TestMeetingStateIsMerged(t *testing.T) {
+	repo := memory.NewRepository()
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{
+		ID:            "merged1",
+		Topic:         "Planning",
+		Status:        models.MeetingStatusCreated,
+		Duration:      60,
+		Host:          models.Participant{ID: "host1"},
+		OperatorEmail: "operator@example.com",
+	}))
+	require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "merged1", Status: models.MeetingStatusStarted, StartTime: time.Now()}))
+
+	saved, err := repo.GetMeeting(ctx, "merged1")
+	require.NoError(t, err)
+	assert.Equal(t, models.MeetingStatusStarted, saved.Status)
+	assert.Equal(t, "Planning", saved.Topic)
+	assert.Equal(t, 60, saved.Duration)
+	assert.Equal(t, "host1", saved.Host.ID)
+	assert.Equal(t, "operator@example.com", saved.OperatorEmail)
+}
+
+func TestRepositorySuite(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.Repository {
+		return memory.NewRepository()
+	}, memory.ErrNotFound)
+}
@@ -6,28 +6,24 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/navikt/zrooms/internal/config"
 	"github.com/navikt/zrooms/internal/models"
+	"github.com/navikt/zrooms/internal/repository/schema"
 	"github.com/redis/go-redis/v9"
 )
 
 // Common errors
 var (
-	ErrNotFound = errors.New("entity not found")
+	ErrNotFound = schema.ErrNotFound
 )
 
-// meetingState is the internal model for storing meeting state in Redis
-type meetingState struct {
-	ID             string // Meeting ID
-	Topic          string // Meeting Topic
-	Status         models.MeetingStatus
-	StartTime      time.Time
-	EndTime        time.Time
-	ParticipantIDs []string // Store only participant IDs
-}
-
 // Repository implements the repository interface with Redis storage
 type Repository struct {
 	client    *redis.Client
@@ -91,11 +87,18 @@ func NewRepository(cfg config.RedisConfig) (*Repository, error) {
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	return &Repository{
+	repo := &Repository{
 		client:    client,
 		keyPrefix: cfg.KeyPrefix,
 		ttl:       cfg.MeetingTTL,
-	}, nil
+	}
+
+	// Index meetings stored before the status index existed
+	if err := repo.migrateMeetingIndex(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
 }
 
 // Close closes the Redis connection
@@ -113,30 +116,145 @@ func (r *Repository) participantSetKey(meetingID string) string {
 	return fmt.Sprintf("%smeetings:%s:participants", r.keyPrefix, meetingID)
 }
 
-// SaveMeeting saves meeting state information to the repository
+// breakoutHashKey returns the Redis key for the hash mapping a meeting's participants to their breakout room
+func (r *Repository) breakoutHashKey(meetingID string) string {
+	return fmt.Sprintf("%smeetings:%s:breakouts", r.keyPrefix, meetingID)
+}
+
+// indicatorsHashKey returns the Redis key for the hash holding a meeting's recording and sharing indicators
+func (r *Repository) indicatorsHashKey(meetingID string) string {
+	return fmt.Sprintf("%smeetings:%s:indicators", r.keyPrefix, meetingID)
+}
+
+// participantEventsHashKey returns the Redis key for the hash of the latest join or leave event_ts of a meeting's participants
+func (r *Repository) participantEventsHashKey(meetingID string) string {
+	return fmt.Sprintf("%smeetings:%s:participantevents", r.keyPrefix, meetingID)
+}
+
+// waitingSetKey returns the Redis key for a meeting's waiting room set
+func (r *Repository) waitingSetKey(meetingID string) string {
+	return fmt.Sprintf("%smeetings:%s:waiting", r.keyPrefix, meetingID)
+}
+
+// meetingSubKeySuffixes are the suffixes of keys stored alongside a meeting under its key
+var meetingSubKeySuffixes = []string{":participants", ":waiting", ":breakouts", ":indicators", ":participantevents"}
+
+// meetingStatuses are all meeting statuses, each with its own index set
+var meetingStatuses = []models.MeetingStatus{
+	models.MeetingStatusCreated,
+	models.MeetingStatusUpdated,
+	models.MeetingStatusStarted,
+	models.MeetingStatusEnded,
+}
+
+// meetingIndexKey returns the Redis key for the set of IDs of meetings with the given status.
+// It is kept outside the meetings: namespace so scans for meeting keys do not match it.
+func (r *Repository) meetingIndexKey(status models.MeetingStatus) string {
+	return fmt.Sprintf("%smeetingindex:%s", r.keyPrefix, status)
+}
+
+// meetingIndexMigratedKey returns the Redis key marking that existing meetings have been indexed
+func (r *Repository) meetingIndexMigratedKey() string {
+	return fmt.Sprintf("%smeetingindex:migrated", r.keyPrefix)
+}
+
+// roomKey returns the Redis key for a room
+func (r *Repository) roomKey(id string) string {
+	return fmt.Sprintf("%srooms:%s", r.keyPrefix, id)
+}
+
+// roomIndexKey returns the Redis key for the set of known room IDs
+func (r *Repository) roomIndexKey() string {
+	return fmt.Sprintf("%srooms", r.keyPrefix)
+}
+
+// deviceAlertsKey returns the Redis key for the hash of device alerts
+func (r *Repository) deviceAlertsKey() string {
+	return fmt.Sprintf("%sdevicealerts", r.keyPrefix)
+}
+
+// webhookSeenKey returns the Redis key used to track a seen webhook delivery
+func (r *Repository) webhookSeenKey(key string) string {
+	return fmt.Sprintf("%swebhooks:seen:%s", r.keyPrefix, key)
+}
+
+// deadLettersKey returns the Redis key for the hash of dead letters
+func (r *Repository) deadLettersKey() string {
+	return fmt.Sprintf("%sdeadletters", r.keyPrefix)
+}
+
+// deadLetterFailuresKey returns the Redis key for the sorted set of dead letter IDs, scored by failure time
+func (r *Repository) deadLetterFailuresKey() string {
+	return fmt.Sprintf("%sdeadletters:failed", r.keyPrefix)
+}
+
+// journalKey returns the Redis key for the event journal stream
+func (r *Repository) journalKey() string {
+	return fmt.Sprintf("%sjournal", r.keyPrefix)
+}
+
+// saveMeetingRetries is how many times saving a meeting is retried when it changes while being merged
+const saveMeetingRetries = 5
+
+// SaveMeeting saves meeting state information to the repository, merging it into the stored record.
+// The meeting key is watched so a concurrent save of the same meeting is not lost.
 func (r *Repository) SaveMeeting(ctx context.Context, meeting *models.Meeting) error {
-	state := meetingState{
-		ID:        meeting.ID,
-		Topic:     meeting.Topic,
-		Status:    meeting.Status,
-		StartTime: meeting.StartTime,
-		EndTime:   meeting.EndTime,
-	}
+	key := r.meetingKey(meeting.ID)
 
-	// Convert state to JSON
-	data, err := json.Marshal(&state)
-	if err != nil {
-		return fmt.Errorf("failed to marshal meeting: %w", err)
+	save := func(tx *redis.Tx) error {
+		record := schema.NewMeetingRecord(meeting)
+
+		data, err := tx.Get(ctx, key).Bytes()
+		switch {
+		case err == nil:
+			if record, _, err = schema.DecodeMeetingRecord(data); err != nil {
+				return fmt.Errorf("failed to read stored meeting: %w", err)
+			}
+			record.Merge(meeting)
+		case !errors.Is(err, redis.Nil):
+			return fmt.Errorf("failed to get meeting: %w", err)
+		}
+
+		data, err = json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to marshal meeting: %w", err)
+		}
+
+		// Save with TTL, moving the meeting to the index set of its status in the same transaction
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, r.ttl)
+			for _, status := range meetingStatuses {
+				if status != record.Status {
+					pipe.SRem(ctx, r.meetingIndexKey(status), meeting.ID)
+				}
+			}
+			pipe.SAdd(ctx, r.meetingIndexKey(record.Status), meeting.ID)
+
+			// Keep the keys stored alongside the meeting expiring together with it
+			if r.ttl > 0 {
+				pipe.Expire(ctx, r.participantSetKey(meeting.ID), r.ttl)
+				pipe.Expire(ctx, r.waitingSetKey(meeting.ID), r.ttl)
+				pipe.Expire(ctx, r.breakoutHashKey(meeting.ID), r.ttl)
+				pipe.Expire(ctx, r.indicatorsHashKey(meeting.ID), r.ttl)
+				pipe.Expire(ctx, r.participantEventsHashKey(meeting.ID), r.ttl)
+			}
+			return nil
+		})
+		return err
 	}
 
-	// Save to Redis with TTL
-	key := r.meetingKey(meeting.ID)
-	cmd := r.client.Set(ctx, key, data, r.ttl)
-	if err := cmd.Err(); err != nil {
-		return fmt.Errorf("failed to save meeting: %w", err)
+	for range saveMeetingRetries {
+		err := r.client.Watch(ctx, save, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save meeting: %w", err)
+		}
+		return nil
 	}
 
-	return nil
+	return fmt.Errorf("failed to save meeting: changed concurrently %d times", saveMeetingRetries)
 }
 
 // GetMeeting retrieves a meeting by ID
@@ -150,19 +268,15 @@ func (r *Repository) GetMeeting(ctx context.Context, id string) (*models.Meeting
 		return nil, fmt.Errorf("failed to get meeting: %w", err)
 	}
 
-	var state meetingState
-	if err := json.Unmarshal(data, &state); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal meeting: %w", err)
+	record, _, err := schema.DecodeMeetingRecord(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read meeting: %w", err)
 	}
 
-	// Convert state back to a Meeting model
-	meeting := &models.Meeting{
-		ID:           state.ID,
-		Topic:        state.Topic,
-		Status:       state.Status,
-		StartTime:    state.StartTime,
-		EndTime:      state.EndTime,
-		Participants: []models.Participant{}, // Empty slice, we don't store participant details
+	meeting := record.Meeting()
+
+	if err := r.loadIndicators(ctx, []*models.Meeting{meeting}); err != nil {
+		return nil, err
 	}
 
 	return meeting, nil
@@ -170,17 +284,36 @@ func (r *Repository) GetMeeting(ctx context.Context, id string) (*models.Meeting
 
 // ListMeetings returns all active meetings (not ended)
 func (r *Repository) ListMeetings(ctx context.Context) ([]*models.Meeting, error) {
-	// Get all meeting keys
-	pattern := r.meetingKey("*")
-	keys, err := r.client.Keys(ctx, pattern).Result()
+	return r.listMeetingsWithStatus(ctx, models.MeetingStatusCreated, models.MeetingStatusUpdated, models.MeetingStatusStarted)
+}
+
+// ListAllMeetings returns all meetings, including ended ones
+func (r *Repository) ListAllMeetings(ctx context.Context) ([]*models.Meeting, error) {
+	return r.listMeetingsWithStatus(ctx, meetingStatuses...)
+}
+
+// listMeetingsWithStatus returns the meetings in the index sets of the given statuses.
+// Meetings expire without being removed from the index, so IDs of expired meetings are pruned here.
+func (r *Repository) listMeetingsWithStatus(ctx context.Context, statuses ...models.MeetingStatus) ([]*models.Meeting, error) {
+	indexKeys := make([]string, len(statuses))
+	for i, status := range statuses {
+		indexKeys[i] = r.meetingIndexKey(status)
+	}
+
+	ids, err := r.client.SUnion(ctx, indexKeys...).Result()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list meetings: %w", err)
 	}
 
-	if len(keys) == 0 {
+	if len(ids) == 0 {
 		return []*models.Meeting{}, nil
 	}
 
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = r.meetingKey(id)
+	}
+
 	// Use MGET to retrieve all meeting data in a single roundtrip
 	values, err := r.client.MGet(ctx, keys...).Result()
 	if err != nil {
@@ -188,93 +321,259 @@ func (r *Repository) ListMeetings(ctx context.Context) ([]*models.Meeting, error
 	}
 
 	meetings := make([]*models.Meeting, 0, len(values))
+	var expired []any
 
 	// Process each meeting
-	for _, v := range values {
-		if v == nil {
-			continue
-		}
-
+	for i, v := range values {
 		strData, ok := v.(string)
 		if !ok {
+			expired = append(expired, ids[i])
 			continue
 		}
 
-		var state meetingState
-		if err := json.Unmarshal([]byte(strData), &state); err != nil {
+		// Unreadable records are reported by CheckMeetingRecords at startup
+		record, _, err := schema.DecodeMeetingRecord([]byte(strData))
+		if err != nil {
 			continue
 		}
 
-		// Skip ended meetings for backward compatibility
-		if state.Status == models.MeetingStatusEnded {
+		// An index set may briefly hold a meeting whose status changed while the index was migrated
+		if !slices.Contains(statuses, record.Status) {
 			continue
 		}
 
-		meeting := &models.Meeting{
-			ID:           state.ID,
-			Topic:        state.Topic,
-			Status:       state.Status,
-			StartTime:    state.StartTime,
-			EndTime:      state.EndTime,
-			Participants: []models.Participant{}, // Empty slice, we don't store participant details
+		meetings = append(meetings, record.Meeting())
+	}
+
+	if len(expired) > 0 {
+		pipe := r.client.Pipeline()
+		for _, key := range indexKeys {
+			pipe.SRem(ctx, key, expired...)
 		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			log.Printf("Error pruning expired meetings from the index: %v", err)
+		}
+	}
 
-		meetings = append(meetings, meeting)
+	if err := r.loadIndicators(ctx, meetings); err != nil {
+		return nil, err
 	}
 
 	return meetings, nil
 }
 
-// ListAllMeetings returns all meetings, including ended ones
-func (r *Repository) ListAllMeetings(ctx context.Context) ([]*models.Meeting, error) {
-	// Get all meeting keys
-	pattern := r.meetingKey("*")
-	keys, err := r.client.Keys(ctx, pattern).Result()
+// migrateMeetingIndex adds meetings stored before the status index existed to the index.
+// Meeting keys are found with SCAN so the server is not blocked; once done, a marker key skips it on later starts.
+func (r *Repository) migrateMeetingIndex(ctx context.Context) error {
+	migrated, err := r.client.Exists(ctx, r.meetingIndexMigratedKey()).Result()
 	if err != nil {
-		return nil, fmt.Errorf("failed to list meetings: %w", err)
+		return fmt.Errorf("failed to check meeting index: %w", err)
 	}
-
-	if len(keys) == 0 {
-		return []*models.Meeting{}, nil
+	if migrated > 0 {
+		return nil
 	}
 
-	// Use MGET to retrieve all meeting data in a single roundtrip
-	values, err := r.client.MGet(ctx, keys...).Result()
+	ids, err := r.scanMeetingIDs(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get meeting data: %w", err)
+		return err
 	}
 
-	meetings := make([]*models.Meeting, 0, len(values))
+	indexed := 0
+	for start := 0; start < len(ids); start += journalBatchSize {
+		batch := ids[start:min(start+journalBatchSize, len(ids))]
+		keys := make([]string, len(batch))
+		for i, id := range batch {
+			keys[i] = r.meetingKey(id)
+		}
 
-	// Process each meeting
-	for _, v := range values {
-		if v == nil {
-			continue
+		values, err := r.client.MGet(ctx, keys...).Result()
+		if err != nil {
+			return fmt.Errorf("failed to get meeting data: %w", err)
 		}
 
-		strData, ok := v.(string)
-		if !ok {
-			continue
+		pipe := r.client.Pipeline()
+		for i, v := range values {
+			strData, ok := v.(string)
+			if !ok {
+				continue
+			}
+			record, _, err := schema.DecodeMeetingRecord([]byte(strData))
+			if err != nil {
+				continue
+			}
+			pipe.SAdd(ctx, r.meetingIndexKey(record.Status), batch[i])
+			indexed++
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("failed to index meetings: %w", err)
 		}
+	}
+
+	if err := r.client.Set(ctx, r.meetingIndexMigratedKey(), time.Now().Unix(), 0).Err(); err != nil {
+		return fmt.Errorf("failed to mark meeting index as migrated: %w", err)
+	}
 
-		var state meetingState
-		if err := json.Unmarshal([]byte(strData), &state); err != nil {
+	if indexed > 0 {
+		log.Printf("Indexed %d existing meetings by status", indexed)
+	}
+	return nil
+}
+
+// scanMeetingIDs returns the IDs of all stored meetings, found with SCAN so the server is not blocked
+func (r *Repository) scanMeetingIDs(ctx context.Context) ([]string, error) {
+	var ids []string
+	prefix := r.meetingKey("")
+	iter := r.client.Scan(ctx, 0, r.meetingKey("*"), journalBatchSize).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		if isMeetingSubKey(key) {
 			continue
 		}
+		ids = append(ids, strings.TrimPrefix(key, prefix))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan meetings: %w", err)
+	}
+	return ids, nil
+}
+
+// replaceRecordScript replaces the value of KEYS[1] with ARGV[2] when it still holds ARGV[1], keeping its
+// expiry, so a record is not overwritten when it was saved after it was read. Returns 0 when it was not replaced.
+var replaceRecordScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl > 0 then
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ttl)
+else
+	redis.call('SET', KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// CheckMeetingRecords reads every stored meeting record and reports the ones that are outdated or cannot be read.
+// With migrate set, outdated records are written back with the current layout.
+func (r *Repository) CheckMeetingRecords(ctx context.Context, migrate bool) (*schema.CheckReport, error) {
+	ids, err := r.scanMeetingIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &schema.CheckReport{}
+	for start := 0; start < len(ids); start += journalBatchSize {
+		batch := ids[start:min(start+journalBatchSize, len(ids))]
+		keys := make([]string, len(batch))
+		for i, id := range batch {
+			keys[i] = r.meetingKey(id)
+		}
 
-		meeting := &models.Meeting{
-			ID:           state.ID,
-			Topic:        state.Topic,
-			Status:       state.Status,
-			StartTime:    state.StartTime,
-			EndTime:      state.EndTime,
-			Participants: []models.Participant{}, // Empty slice, we don't store participant details
+		values, err := r.client.MGet(ctx, keys...).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get meeting data: %w", err)
 		}
 
-		meetings = append(meetings, meeting)
+		for i, v := range values {
+			strData, ok := v.(string)
+			if !ok {
+				continue // Expired since the scan
+			}
+			report.Checked++
+
+			record, upgraded, err := schema.DecodeMeetingRecord([]byte(strData))
+			if err != nil {
+				report.Unreadable = append(report.Unreadable, schema.UnreadableRecord{Key: keys[i], Error: err.Error()})
+				continue
+			}
+			if !upgraded {
+				continue
+			}
+			report.Outdated++
+
+			if !migrate {
+				continue
+			}
+			data, err := json.Marshal(record)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal meeting: %w", err)
+			}
+			replaced, err := replaceRecordScript.Run(ctx, r.client, []string{keys[i]}, strData, data).Int()
+			if err != nil {
+				return nil, fmt.Errorf("failed to migrate meeting: %w", err)
+			}
+			if replaced == 1 {
+				report.Migrated++
+			}
+		}
 	}
 
-	return meetings, nil
+	return report, nil
+}
+
+// isMeetingSubKey reports whether a key matching the meeting key pattern holds data stored alongside a meeting
+func isMeetingSubKey(key string) bool {
+	for _, suffix := range meetingSubKeySuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadIndicators sets the recording and sharing indicators of meetings, reading them in a single roundtrip
+func (r *Repository) loadIndicators(ctx context.Context, meetings []*models.Meeting) error {
+	if len(meetings) == 0 {
+		return nil
+	}
+
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(meetings))
+	for i, meeting := range meetings {
+		cmds[i] = pipe.HGetAll(ctx, r.indicatorsHashKey(meeting.ID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to get meeting indicators: %w", err)
+	}
+
+	for i, meeting := range meetings {
+		indicators := cmds[i].Val()
+		meeting.Recording = models.RecordingStatus(indicators["recording"])
+		meeting.Sharing = indicators["sharing"] == "1"
+	}
+
+	return nil
+}
+
+// setIndicator sets or, for an empty value, removes one of a meeting's indicators
+func (r *Repository) setIndicator(ctx context.Context, meetingID, field, value string) error {
+	key := r.indicatorsHashKey(meetingID)
+	if value == "" {
+		err := r.runMeetingScript(ctx, deleteFieldScript, meetingID, key, field)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("failed to clear meeting %s: %w", field, err)
+		}
+		return err
+	}
+
+	err := r.runMeetingScript(ctx, setFieldScript, meetingID, key, field, value)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("failed to set meeting %s: %w", field, err)
+	}
+	return err
+}
+
+// SetMeetingRecording sets whether a meeting is being recorded
+func (r *Repository) SetMeetingRecording(ctx context.Context, meetingID string, recording models.RecordingStatus) error {
+	return r.setIndicator(ctx, meetingID, "recording", string(recording))
+}
+
+// SetMeetingSharing sets whether a participant is sharing their screen in a meeting
+func (r *Repository) SetMeetingSharing(ctx context.Context, meetingID string, sharing bool) error {
+	value := ""
+	if sharing {
+		value = "1"
+	}
+	return r.setIndicator(ctx, meetingID, "sharing", value)
 }
 
 // DeleteMeeting removes a meeting by ID
@@ -291,10 +590,17 @@ func (r *Repository) DeleteMeeting(ctx context.Context, id string) error {
 		return ErrNotFound
 	}
 
-	// Use a pipeline to delete both keys in one operation
-	pipe := r.client.Pipeline()
+	// Use a transaction to delete the meeting, its sets and its index entry in one operation
+	pipe := r.client.TxPipeline()
 	pipe.Del(ctx, key)
 	pipe.Del(ctx, participantsKey)
+	pipe.Del(ctx, r.waitingSetKey(id))
+	pipe.Del(ctx, r.breakoutHashKey(id))
+	pipe.Del(ctx, r.indicatorsHashKey(id))
+	pipe.Del(ctx, r.participantEventsHashKey(id))
+	for _, status := range meetingStatuses {
+		pipe.SRem(ctx, r.meetingIndexKey(status), id)
+	}
 	_, err = pipe.Exec(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to delete meeting: %w", err)
@@ -303,53 +609,168 @@ func (r *Repository) DeleteMeeting(ctx context.Context, id string) error {
 	return nil
 }
 
-// AddParticipantToMeeting adds a participant ID to a meeting
-func (r *Repository) AddParticipantToMeeting(ctx context.Context, meetingID, participantID string) error {
-	// Check if the meeting exists
-	exists, err := r.client.Exists(ctx, r.meetingKey(meetingID)).Result()
+// Scripts changing a set or hash stored alongside a meeting. Each checks that the meeting exists and changes
+// the set or hash in one atomic step, so a concurrent DeleteMeeting cannot leave an orphaned key behind.
+// Adding or removing a member, or setting a field, gives the meeting and the key the same expiry, so they
+// expire together. KEYS[1] is the meeting key and KEYS[2] the set or hash; they return 0 when the meeting
+// does not exist.
+var (
+	// addMemberScript adds ARGV[1] to the set and applies the TTL in milliseconds in ARGV[2], if positive
+	addMemberScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('SADD', KEYS[2], ARGV[1])
+local ttl = tonumber(ARGV[2])
+if ttl > 0 then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+	redis.call('PEXPIRE', KEYS[2], ttl)
+end
+return 1
+`)
+
+	// removeMemberScript removes ARGV[1] from the set and applies the TTL in milliseconds in ARGV[2], if positive
+	removeMemberScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('SREM', KEYS[2], ARGV[1])
+local ttl = tonumber(ARGV[2])
+if ttl > 0 then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+	redis.call('PEXPIRE', KEYS[2], ttl)
+end
+return 1
+`)
+
+	// removeParticipantScript removes ARGV[1] from the participant set and from the breakout hash in KEYS[3],
+	// and applies the TTL in milliseconds in ARGV[2], if positive, to all three keys
+	removeParticipantScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('SREM', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+local ttl = tonumber(ARGV[2])
+if ttl > 0 then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+	redis.call('PEXPIRE', KEYS[2], ttl)
+	redis.call('PEXPIRE', KEYS[3], ttl)
+end
+return 1
+`)
+
+	// setFieldScript sets field ARGV[1] of the hash to ARGV[2] and applies the TTL in milliseconds in ARGV[3], if positive
+	setFieldScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[2])
+local ttl = tonumber(ARGV[3])
+if ttl > 0 then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+	redis.call('PEXPIRE', KEYS[2], ttl)
+end
+return 1
+`)
+
+	// deleteFieldScript deletes field ARGV[1] of the hash, ignoring the TTL in ARGV[2]
+	deleteFieldScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('HDEL', KEYS[2], ARGV[1])
+return 1
+`)
+
+	// deleteFieldIfScript deletes field ARGV[1] of the hash if its value is ARGV[2], ignoring the TTL in ARGV[3]
+	deleteFieldIfScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+if redis.call('HGET', KEYS[2], ARGV[1]) == ARGV[2] then
+	redis.call('HDEL', KEYS[2], ARGV[1])
+end
+return 1
+`)
+
+	// recordEventScript sets field ARGV[1] of the hash to the event_ts in ARGV[2] unless the stored event_ts is later,
+	// and applies the TTL in milliseconds in ARGV[3], if positive. Returns 1 when the event_ts is older, 2 when recorded.
+	recordEventScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+local last = redis.call('HGET', KEYS[2], ARGV[1])
+if last and tonumber(last) > tonumber(ARGV[2]) then
+	return 1
+end
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[2])
+local ttl = tonumber(ARGV[3])
+if ttl > 0 then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+	redis.call('PEXPIRE', KEYS[2], ttl)
+end
+return 2
+`)
+
+	// clearScript deletes the set or hash, ignoring the TTL in ARGV[1]
+	clearScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('DEL', KEYS[2])
+return 1
+`)
+)
+
+// runMeetingScript runs one of the meeting set scripts with the member or field, if any, and the meeting TTL as arguments.
+// ErrNotFound is returned when the meeting does not exist.
+func (r *Repository) runMeetingScript(ctx context.Context, script *redis.Script, meetingID, setKey string, member ...any) error {
+	args := append(member, r.ttl.Milliseconds())
+	found, err := script.Run(ctx, r.client, []string{r.meetingKey(meetingID), setKey}, args...).Int()
 	if err != nil {
-		return fmt.Errorf("failed to check if meeting exists: %w", err)
+		return err
 	}
-	if exists == 0 {
+	if found == 0 {
 		return ErrNotFound
 	}
+	return nil
+}
 
-	// Add participant to the set
-	key := r.participantSetKey(meetingID)
-	err = r.client.SAdd(ctx, key, participantID).Err()
-	if err != nil {
+// AddParticipantToMeeting adds a participant ID to a meeting
+func (r *Repository) AddParticipantToMeeting(ctx context.Context, meetingID, participantID string) error {
+	err := r.runMeetingScript(ctx, addMemberScript, meetingID, r.participantSetKey(meetingID), participantID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
 		return fmt.Errorf("failed to add participant: %w", err)
 	}
-
-	// Set TTL on the participants set to match the meeting TTL
-	if r.ttl > 0 {
-		err = r.client.Expire(ctx, key, r.ttl).Err()
-		if err != nil {
-			return fmt.Errorf("failed to set expiry on participants: %w", err)
-		}
-	}
-
-	return nil
+	return err
 }
 
-// RemoveParticipantFromMeeting removes a participant ID from a meeting
+// RemoveParticipantFromMeeting removes a participant ID from a meeting and from their breakout room
 func (r *Repository) RemoveParticipantFromMeeting(ctx context.Context, meetingID, participantID string) error {
-	// Check if the meeting exists
-	exists, err := r.client.Exists(ctx, r.meetingKey(meetingID)).Result()
+	keys := []string{r.meetingKey(meetingID), r.participantSetKey(meetingID), r.breakoutHashKey(meetingID)}
+	found, err := removeParticipantScript.Run(ctx, r.client, keys, participantID, r.ttl.Milliseconds()).Int()
 	if err != nil {
-		return fmt.Errorf("failed to check if meeting exists: %w", err)
+		return fmt.Errorf("failed to remove participant: %w", err)
 	}
-	if exists == 0 {
+	if found == 0 {
 		return ErrNotFound
 	}
+	return nil
+}
 
-	// Remove participant from the set
-	err = r.client.SRem(ctx, r.participantSetKey(meetingID), participantID).Err()
+// RecordParticipantEvent records the event_ts of the latest join or leave applied for a participant.
+// Returns false when eventTS is older than the recorded one.
+func (r *Repository) RecordParticipantEvent(ctx context.Context, meetingID, participantID string, eventTS int64) (bool, error) {
+	keys := []string{r.meetingKey(meetingID), r.participantEventsHashKey(meetingID)}
+	result, err := recordEventScript.Run(ctx, r.client, keys, participantID, eventTS, r.ttl.Milliseconds()).Int()
 	if err != nil {
-		return fmt.Errorf("failed to remove participant: %w", err)
+		return false, fmt.Errorf("failed to record participant event: %w", err)
 	}
-
-	return nil
+	if result == 0 {
+		return false, ErrNotFound
+	}
+	return result == 2, nil
 }
 
 // CountParticipantsInMeeting counts the number of participants in a meeting
@@ -372,22 +793,473 @@ func (r *Repository) CountParticipantsInMeeting(ctx context.Context, meetingID s
 	return int(count), nil
 }
 
+// ClearPartipantsInMeeting removes all participants from a meeting
 func (r *Repository) ClearPartipantsInMeeting(ctx context.Context, meetingID string) error {
+	err := r.runMeetingScript(ctx, clearScript, meetingID, r.participantSetKey(meetingID))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("failed to clear participants: %w", err)
+	}
+	return err
+}
+
+// AddParticipantToWaitingRoom adds a participant ID to a meeting's waiting room
+func (r *Repository) AddParticipantToWaitingRoom(ctx context.Context, meetingID, participantID string) error {
+	err := r.runMeetingScript(ctx, addMemberScript, meetingID, r.waitingSetKey(meetingID), participantID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("failed to add waiting participant: %w", err)
+	}
+	return err
+}
+
+// RemoveParticipantFromWaitingRoom removes a participant ID from a meeting's waiting room
+func (r *Repository) RemoveParticipantFromWaitingRoom(ctx context.Context, meetingID, participantID string) error {
+	err := r.runMeetingScript(ctx, removeMemberScript, meetingID, r.waitingSetKey(meetingID), participantID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("failed to remove waiting participant: %w", err)
+	}
+	return err
+}
+
+// CountParticipantsInWaitingRoom counts the number of participants waiting to be admitted to a meeting
+func (r *Repository) CountParticipantsInWaitingRoom(ctx context.Context, meetingID string) (int, error) {
 	// Check if the meeting exists
 	exists, err := r.client.Exists(ctx, r.meetingKey(meetingID)).Result()
 	if err != nil {
-		return fmt.Errorf("failed to check if meeting exists: %w", err)
+		return 0, fmt.Errorf("failed to check if meeting exists: %w", err)
 	}
 	if exists == 0 {
+		return 0, ErrNotFound
+	}
+
+	count, err := r.client.SCard(ctx, r.waitingSetKey(meetingID)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count waiting participants: %w", err)
+	}
+
+	return int(count), nil
+}
+
+// ClearWaitingRoom removes all participants from a meeting's waiting room
+func (r *Repository) ClearWaitingRoom(ctx context.Context, meetingID string) error {
+	err := r.runMeetingScript(ctx, clearScript, meetingID, r.waitingSetKey(meetingID))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("failed to clear waiting room: %w", err)
+	}
+	return err
+}
+
+// AddParticipantToBreakoutRoom records that a participant is in a breakout room of a meeting,
+// moving them out of any other breakout room
+func (r *Repository) AddParticipantToBreakoutRoom(ctx context.Context, meetingID, breakoutRoomID, participantID string) error {
+	err := r.runMeetingScript(ctx, setFieldScript, meetingID, r.breakoutHashKey(meetingID), participantID, breakoutRoomID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("failed to add breakout room participant: %w", err)
+	}
+	return err
+}
+
+// RemoveParticipantFromBreakoutRoom removes a participant from a breakout room of a meeting.
+// Does nothing if the participant has since moved to another breakout room.
+func (r *Repository) RemoveParticipantFromBreakoutRoom(ctx context.Context, meetingID, breakoutRoomID, participantID string) error {
+	err := r.runMeetingScript(ctx, deleteFieldIfScript, meetingID, r.breakoutHashKey(meetingID), participantID, breakoutRoomID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("failed to remove breakout room participant: %w", err)
+	}
+	return err
+}
+
+// ListBreakoutRooms returns the breakout rooms of a meeting that have participants, sorted by ID
+func (r *Repository) ListBreakoutRooms(ctx context.Context, meetingID string) ([]models.BreakoutRoom, error) {
+	// Check if the meeting exists
+	exists, err := r.client.Exists(ctx, r.meetingKey(meetingID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if meeting exists: %w", err)
+	}
+	if exists == 0 {
+		return nil, ErrNotFound
+	}
+
+	breakoutIDs, err := r.client.HGetAll(ctx, r.breakoutHashKey(meetingID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list breakout rooms: %w", err)
+	}
+
+	counts := make(map[string]int)
+	for _, roomID := range breakoutIDs {
+		counts[roomID]++
+	}
+
+	rooms := make([]models.BreakoutRoom, 0, len(counts))
+	for roomID, count := range counts {
+		rooms = append(rooms, models.BreakoutRoom{ID: roomID, ParticipantCount: count})
+	}
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].ID < rooms[j].ID
+	})
+
+	return rooms, nil
+}
+
+// ClearBreakoutRooms removes all participants from the breakout rooms of a meeting
+func (r *Repository) ClearBreakoutRooms(ctx context.Context, meetingID string) error {
+	err := r.runMeetingScript(ctx, clearScript, meetingID, r.breakoutHashKey(meetingID))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("failed to clear breakout rooms: %w", err)
+	}
+	return err
+}
+
+// SaveRoom stores or replaces the state of a room.
+// Rooms are physical and long-lived, so unlike meetings they do not expire.
+func (r *Repository) SaveRoom(ctx context.Context, room *models.Room) error {
+	data, err := json.Marshal(room)
+	if err != nil {
+		return fmt.Errorf("failed to marshal room: %w", err)
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, r.roomKey(room.ID), data, 0)
+	pipe.SAdd(ctx, r.roomIndexKey(), room.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save room: %w", err)
+	}
+
+	return nil
+}
+
+// GetRoom retrieves a room by ID
+func (r *Repository) GetRoom(ctx context.Context, id string) (*models.Room, error) {
+	data, err := r.client.Get(ctx, r.roomKey(id)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get room: %w", err)
+	}
+
+	var room models.Room
+	if err := json.Unmarshal([]byte(data), &room); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal room: %w", err)
+	}
+
+	return &room, nil
+}
+
+// ListRooms returns all rooms
+func (r *Repository) ListRooms(ctx context.Context) ([]*models.Room, error) {
+	ids, err := r.client.SMembers(ctx, r.roomIndexKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rooms: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return []*models.Room{}, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = r.roomKey(id)
+	}
+
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get room data: %w", err)
+	}
+
+	rooms := make([]*models.Room, 0, len(values))
+	for _, v := range values {
+		strData, ok := v.(string)
+		if !ok {
+			continue
+		}
+
+		var room models.Room
+		if err := json.Unmarshal([]byte(strData), &room); err != nil {
+			continue
+		}
+		rooms = append(rooms, &room)
+	}
+
+	return rooms, nil
+}
+
+// ClearRooms removes all rooms
+func (r *Repository) ClearRooms(ctx context.Context) error {
+	ids, err := r.client.SMembers(ctx, r.roomIndexKey()).Result()
+	if err != nil {
+		return fmt.Errorf("failed to list rooms: %w", err)
+	}
+
+	keys := []string{r.roomIndexKey()}
+	for _, id := range ids {
+		keys = append(keys, r.roomKey(id))
+	}
+
+	if err := r.client.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("failed to delete rooms: %w", err)
+	}
+
+	return nil
+}
+
+// SaveDeviceAlert stores or replaces a device alert.
+// Alerts are kept in a single hash; there is one per room device problem, so it stays small.
+func (r *Repository) SaveDeviceAlert(ctx context.Context, alert *models.DeviceAlert) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("failed to marshal device alert: %w", err)
+	}
+
+	if err := r.client.HSet(ctx, r.deviceAlertsKey(), alert.ID, data).Err(); err != nil {
+		return fmt.Errorf("failed to save device alert: %w", err)
+	}
+
+	return nil
+}
+
+// GetDeviceAlert retrieves a device alert by ID
+func (r *Repository) GetDeviceAlert(ctx context.Context, id string) (*models.DeviceAlert, error) {
+	data, err := r.client.HGet(ctx, r.deviceAlertsKey(), id).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get device alert: %w", err)
+	}
+
+	var alert models.DeviceAlert
+	if err := json.Unmarshal([]byte(data), &alert); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal device alert: %w", err)
+	}
+
+	return &alert, nil
+}
+
+// ListDeviceAlerts returns all device alerts, both active and cleared
+func (r *Repository) ListDeviceAlerts(ctx context.Context) ([]*models.DeviceAlert, error) {
+	values, err := r.client.HGetAll(ctx, r.deviceAlertsKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list device alerts: %w", err)
+	}
+
+	alerts := make([]*models.DeviceAlert, 0, len(values))
+	for _, data := range values {
+		var alert models.DeviceAlert
+		if err := json.Unmarshal([]byte(data), &alert); err != nil {
+			continue
+		}
+		alerts = append(alerts, &alert)
+	}
+
+	return alerts, nil
+}
+
+// ClearDeviceAlerts removes all device alerts
+func (r *Repository) ClearDeviceAlerts(ctx context.Context) error {
+	if err := r.client.Del(ctx, r.deviceAlertsKey()).Err(); err != nil {
+		return fmt.Errorf("failed to delete device alerts: %w", err)
+	}
+	return nil
+}
+
+// MarkWebhookSeen records a webhook key for the given TTL
+// Returns true if the key was not seen before (or its previous entry has expired)
+func (r *Repository) MarkWebhookSeen(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	// SETNX is atomic, so concurrent deliveries of the same request cannot both be accepted
+	firstSeen, err := r.client.SetNX(ctx, r.webhookSeenKey(key), 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to mark webhook as seen: %w", err)
+	}
+
+	return firstSeen, nil
+}
+
+// UnmarkWebhookSeen removes a webhook key so a later delivery with the same key is accepted
+func (r *Repository) UnmarkWebhookSeen(ctx context.Context, key string) error {
+	if err := r.client.Del(ctx, r.webhookSeenKey(key)).Err(); err != nil {
+		return fmt.Errorf("failed to unmark webhook: %w", err)
+	}
+	return nil
+}
+
+// saveDeadLetterScript stores a dead letter and indexes it by failure time, then drops the oldest
+// failures beyond the capacity. KEYS: dead letter hash, failure index. ARGV: ID, data, failure time, capacity.
+var saveDeadLetterScript = redis.NewScript(`
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+local excess = redis.call('ZCARD', KEYS[2]) - tonumber(ARGV[4])
+if excess > 0 then
+	local ids = redis.call('ZRANGE', KEYS[2], 0, excess - 1)
+	redis.call('ZREM', KEYS[2], unpack(ids))
+	redis.call('HDEL', KEYS[1], unpack(ids))
+end
+return 1
+`)
+
+// SaveDeadLetter stores or replaces a dead letter.
+// Dead letters have no TTL; they are kept until an admin replays or discards them, or until
+// models.DeadLetterCapacity later failures push them out.
+func (r *Repository) SaveDeadLetter(ctx context.Context, deadLetter *models.DeadLetter) error {
+	data, err := json.Marshal(deadLetter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	keys := []string{r.deadLettersKey(), r.deadLetterFailuresKey()}
+	args := []any{deadLetter.ID, data, deadLetter.FailedAt.UnixMilli(), models.DeadLetterCapacity}
+	if err := saveDeadLetterScript.Run(ctx, r.client, keys, args...).Err(); err != nil {
+		return fmt.Errorf("failed to save dead letter: %w", err)
+	}
+
+	return nil
+}
+
+// GetDeadLetter retrieves a dead letter by ID
+func (r *Repository) GetDeadLetter(ctx context.Context, id string) (*models.DeadLetter, error) {
+	data, err := r.client.HGet(ctx, r.deadLettersKey(), id).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+
+	var deadLetter models.DeadLetter
+	if err := json.Unmarshal(data, &deadLetter); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal dead letter: %w", err)
+	}
+
+	return &deadLetter, nil
+}
+
+// ListDeadLetters returns all dead letters, most recent failure first
+func (r *Repository) ListDeadLetters(ctx context.Context) ([]*models.DeadLetter, error) {
+	values, err := r.client.HGetAll(ctx, r.deadLettersKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	deadLetters := make([]*models.DeadLetter, 0, len(values))
+	for _, data := range values {
+		var deadLetter models.DeadLetter
+		if err := json.Unmarshal([]byte(data), &deadLetter); err != nil {
+			continue
+		}
+		deadLetters = append(deadLetters, &deadLetter)
+	}
+
+	sort.Slice(deadLetters, func(i, j int) bool {
+		return deadLetters[i].FailedAt.After(deadLetters[j].FailedAt)
+	})
+
+	return deadLetters, nil
+}
+
+// DeleteDeadLetter removes a dead letter by ID
+func (r *Repository) DeleteDeadLetter(ctx context.Context, id string) error {
+	var deleted *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		deleted = pipe.HDel(ctx, r.deadLettersKey(), id)
+		pipe.ZRem(ctx, r.deadLetterFailuresKey(), id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letter: %w", err)
+	}
+	if deleted.Val() == 0 {
 		return ErrNotFound
 	}
 
-	// Clear all participants from the meeting's participant set
-	key := r.participantSetKey(meetingID)
-	err = r.client.Del(ctx, key).Err()
+	return nil
+}
+
+// journalBatchSize is the number of journal entries read per round trip during replay
+const journalBatchSize = 500
+
+// journalMaxLen bounds the journal stream when meetings never expire, so no age can be used to trim it
+const journalMaxLen = 100000
+
+// ClearMeetings removes all meetings and their participants
+func (r *Repository) ClearMeetings(ctx context.Context) error {
+	// The pattern matches both meeting keys and their participant sets
+	iter := r.client.Scan(ctx, 0, r.meetingKey("*"), journalBatchSize).Iterator()
+	var keys []string
+	for _, status := range meetingStatuses {
+		keys = append(keys, r.meetingIndexKey(status))
+	}
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed to scan meetings: %w", err)
+	}
+
+	for start := 0; start < len(keys); start += journalBatchSize {
+		end := min(start+journalBatchSize, len(keys))
+		if err := r.client.Del(ctx, keys[start:end]...).Err(); err != nil {
+			return fmt.Errorf("failed to delete meetings: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// AppendEvent adds a webhook event to the end of the journal stream. Entries older than the meeting TTL
+// are trimmed, as they can no longer describe a stored meeting, or the stream is capped by length without a TTL.
+func (r *Repository) AppendEvent(ctx context.Context, event *models.WebhookEvent) error {
+	data, err := json.Marshal(event)
 	if err != nil {
-		return fmt.Errorf("failed to clear participants: %w", err)
+		return fmt.Errorf("failed to marshal journal event: %w", err)
+	}
+
+	args := &redis.XAddArgs{
+		Stream: r.journalKey(),
+		Approx: true,
+		Values: map[string]interface{}{"event": data},
+	}
+	if r.ttl > 0 {
+		// Stream IDs start with the time in milliseconds the entry was added
+		args.MinID = strconv.FormatInt(time.Now().Add(-r.ttl).UnixMilli(), 10)
+	} else {
+		args.MaxLen = journalMaxLen
+	}
+	err = r.client.XAdd(ctx, args).Err()
+	if err != nil {
+		return fmt.Errorf("failed to append journal event: %w", err)
 	}
 
 	return nil
 }
+
+// ReplayEvents calls fn for each journaled event in the order they were appended
+func (r *Repository) ReplayEvents(ctx context.Context, fn func(event *models.WebhookEvent) error) error {
+	start := "-"
+	for {
+		entries, err := r.client.XRangeN(ctx, r.journalKey(), start, "+", journalBatchSize).Result()
+		if err != nil {
+			return fmt.Errorf("failed to read event journal: %w", err)
+		}
+
+		for _, entry := range entries {
+			data, ok := entry.Values["event"].(string)
+			if !ok {
+				return fmt.Errorf("journal entry %s has no event", entry.ID)
+			}
+
+			var event models.WebhookEvent
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				return fmt.Errorf("failed to parse journal entry %s: %w", entry.ID, err)
+			}
+			if err := fn(&event); err != nil {
+				return err
+			}
+		}
+
+		if len(entries) < journalBatchSize {
+			return nil
+		}
+
+		// Continue after the last entry read
+		start = "(" + entries[len(entries)-1].ID
+	}
+}
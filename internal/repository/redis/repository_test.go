@@ -3,14 +3,19 @@ package redis_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/alicebob/miniredis/v2"
 	"github.com/navikt/zrooms/internal/config"
 	"github.com/navikt/zrooms/internal/models"
+	"github.com/navikt/zrooms/internal/repository"
 	"github.com/navikt/zrooms/internal/repository/redis"
+	"github.com/navikt/zrooms/internal/repository/repositorytest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -231,3 +236,731 @@ func TestMeetingWithParticipants(t *testing.T) {
 		assert.True(t, found, "Ended meeting should be included in ListAllMeetings")
 	})
 }
+
+func TestMarkWebhookSeen(t *testing.T) {
+	repo, mr, cleanup := setupTestRedis(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	t.Run("FirstAndRepeatedKey", func(t *testing.T) {
+		firstSeen, err := repo.MarkWebhookSeen(ctx, "signature:abc", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, firstSeen)
+
+		firstSeen, err = repo.MarkWebhookSeen(ctx, "signature:abc", time.Minute)
+		assert.NoError(t, err)
+		assert.False(t, firstSeen, "Repeated key should be reported as seen")
+
+		firstSeen, err = repo.MarkWebhookSeen(ctx, "signature:def", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, firstSeen, "Different key should be accepted")
+		// Entry expires after its TTL
+		mr.FastForward(2 * time.Minute)
+		firstSeen, err = repo.MarkWebhookSeen(ctx, "signature:abc", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, firstSeen, "Expired key should be accepted again")
+	})
+}
+
+func TestDeadLetterOperations(t *testing.T) {
+	repo, _, cleanup := setupTestRedis(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	older := models.NewDeadLetter(models.WebhookEvent{
+		Event:   "meeting.participant_joined",
+		Payload: json.RawMessage(`{"object": {"id": "meeting123", "participant": {"id": "part1"}}}`),
+		EventTS: 1620123456789,
+	}, "meeting not found", 4)
+	older.FailedAt = time.Now().Add(-time.Minute)
+	newer := models.NewDeadLetter(models.WebhookEvent{Event: "meeting.started"}, "invalid payload", 1)
+
+	t.Run("SaveAndGet", func(t *testing.T) {
+		assert.NoError(t, repo.SaveDeadLetter(ctx, older))
+		assert.NoError(t, repo.SaveDeadLetter(ctx, newer))
+
+		stored, err := repo.GetDeadLetter(ctx, older.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "meeting.participant_joined", stored.Event.Event)
+		assert.Equal(t, "meeting123", stored.MeetingID())
+		assert.Equal(t, "meeting not found", stored.Reason)
+		assert.Equal(t, 4, stored.Attempts)
+	})
+
+	t.Run("ListMostRecentFirst", func(t *testing.T) {
+		deadLetters, err := repo.ListDeadLetters(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, deadLetters, 2)
+		assert.Equal(t, newer.ID, deadLetters[0].ID)
+		assert.Equal(t, older.ID, deadLetters[1].ID)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		assert.NoError(t, repo.DeleteDeadLetter(ctx, older.ID))
+
+		_, err := repo.GetDeadLetter(ctx, older.ID)
+		assert.ErrorIs(t, err, redis.ErrNotFound)
+		assert.ErrorIs(t, repo.DeleteDeadLetter(ctx, older.ID), redis.ErrNotFound)
+	})
+}
+
+func TestEventJournal(t *testing.T) {
+	repo, _, cleanup := setupTestRedis(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	events := []*models.WebhookEvent{
+		{Event: "meeting.started", Payload: json.RawMessage(`{"object": {"id": "meeting123"}}`), EventTS: 1},
+		{Event: "meeting.participant_joined", Payload: json.RawMessage(`{"object": {"id": "meeting123", "participant": {"id": "part1"}}}`), EventTS: 2},
+		{Event: "meeting.ended", Payload: json.RawMessage(`{"object": {"id": "meeting123"}}`), EventTS: 3},
+	}
+	for _, event := range events {
+		assert.NoError(t, repo.AppendEvent(ctx, event))
+	}
+
+	t.Run("ReplayInOrder", func(t *testing.T) {
+		var replayed []string
+		err := repo.ReplayEvents(ctx, func(event *models.WebhookEvent) error {
+			replayed = append(replayed, event.Event)
+			assert.Equal(t, "meeting123", event.MeetingID())
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"meeting.started", "meeting.participant_joined", "meeting.ended"}, replayed)
+	})
+
+	t.Run("ReplayStopsOnError", func(t *testing.T) {
+		stop := errors.New("stop")
+		count := 0
+		err := repo.ReplayEvents(ctx, func(event *models.WebhookEvent) error {
+			count++
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("ClearMeetingsKeepsJournal", func(t *testing.T) {
+		assert.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "meeting123", Status: models.MeetingStatusStarted}))
+		assert.NoError(t, repo.AddParticipantToMeeting(ctx, "meeting123", "part1"))
+		assert.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "meeting456", Status: models.MeetingStatusEnded}))
+
+		assert.NoError(t, repo.ClearMeetings(ctx))
+
+		meetings, err := repo.ListAllMeetings(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, meetings)
+		_, err = repo.CountParticipantsInMeeting(ctx, "meeting123")
+		assert.ErrorIs(t, err, redis.ErrNotFound)
+
+		replayed := 0
+		assert.NoError(t, repo.ReplayEvents(ctx, func(event *models.WebhookEvent) error {
+			replayed++
+			return nil
+		}))
+		assert.Equal(t, 3, replayed)
+	})
+}
+
+func TestEventJournalIsTrimmedByAge(t *testing.T) {
+	repo, mr, cleanup := setupTestRedis(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	// An event journaled longer ago than the meeting TTL is trimmed when the next one is appended
+	mr.SetTime(time.Now().Add(-48 * time.Hour))
+	require.NoError(t, repo.AppendEvent(ctx, &models.WebhookEvent{Event: "meeting.started", EventTS: 1}))
+	mr.SetTime(time.Now())
+	require.NoError(t, repo.AppendEvent(ctx, &models.WebhookEvent{Event: "meeting.ended", EventTS: 2}))
+
+	var replayed []string
+	require.NoError(t, repo.ReplayEvents(ctx, func(event *models.WebhookEvent) error {
+		replayed = append(replayed, event.Event)
+		return nil
+	}))
+	assert.Equal(t, []string{"meeting.ended"}, replayed)
+}
+
+func TestRoomOperations(t *testing.T) {
+	repo, _, cleanup := setupTestRedis(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	_, err := repo.GetRoom(ctx, "room1")
+	assert.ErrorIs(t, err, redis.ErrNotFound)
+
+	rooms, err := repo.ListRooms(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, rooms)
+
+	room := &models.Room{
+		ID:           "room1",
+		Name:         "Oslo",
+		Status:       models.RoomStatusInMeeting,
+		MeetingID:    "meeting123",
+		MeetingTopic: "Standup",
+		CheckedInAt:  time.UnixMilli(1620123456789).UTC(),
+		LastEventTS:  1620123456789,
+	}
+	assert.NoError(t, repo.SaveRoom(ctx, room))
+	assert.NoError(t, repo.SaveRoom(ctx, &models.Room{ID: "room2", Name: "Bergen"}))
+
+	stored, err := repo.GetRoom(ctx, "room1")
+	assert.NoError(t, err)
+	assert.Equal(t, room, stored)
+
+	// Saving again replaces the room state
+	room.Status = models.RoomStatusAvailable
+	room.MeetingID = ""
+	assert.NoError(t, repo.SaveRoom(ctx, room))
+	stored, err = repo.GetRoom(ctx, "room1")
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoomStatusAvailable, stored.Status)
+	assert.Empty(t, stored.MeetingID)
+
+	rooms, err = repo.ListRooms(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, rooms, 2)
+
+	assert.NoError(t, repo.ClearRooms(ctx))
+	rooms, err = repo.ListRooms(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, rooms)
+}
+
+func TestDeviceAlertOperations(t *testing.T) {
+	repo, _, cleanup := setupTestRedis(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	_, err := repo.GetDeviceAlert(ctx, "room1:2:3")
+	assert.ErrorIs(t, err, redis.ErrNotFound)
+
+	alert := &models.DeviceAlert{
+		ID:          "room1:2:3",
+		RoomID:      "room1",
+		RoomName:    "Oslo",
+		Component:   models.DeviceComponentController,
+		AlertType:   3,
+		Issue:       "Controller is offline",
+		RaisedAt:    time.UnixMilli(1620123456789).UTC(),
+		LastEventTS: 1620123456789,
+	}
+	assert.NoError(t, repo.SaveDeviceAlert(ctx, alert))
+
+	stored, err := repo.GetDeviceAlert(ctx, alert.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, alert, stored)
+
+	// Clearing the alert replaces it
+	alert.ClearedAt = time.UnixMilli(1620123459999).UTC()
+	assert.NoError(t, repo.SaveDeviceAlert(ctx, alert))
+	assert.NoError(t, repo.SaveDeviceAlert(ctx, &models.DeviceAlert{ID: "room2:1:0", RoomID: "room2"}))
+
+	alerts, err := repo.ListDeviceAlerts(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, alerts, 2)
+
+	stored, err = repo.GetDeviceAlert(ctx, alert.ID)
+	assert.NoError(t, err)
+	assert.False(t, stored.IsActive())
+
+	assert.NoError(t, repo.ClearDeviceAlerts(ctx))
+	alerts, err = repo.ListDeviceAlerts(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, alerts)
+}
+
+func TestMeetingType(t *testing.T) {
+	repo, _, cleanup := setupTestRedis(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	webinar := &models.Meeting{ID: "webinar123", Topic: "All Hands", Type: models.MeetingTypeWebinar, Status: models.MeetingStatusStarted}
+	assert.NoError(t, repo.SaveMeeting(ctx, webinar))
+
+	saved, err := repo.GetMeeting(ctx, webinar.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.MeetingTypeWebinar, saved.Type)
+	assert.True(t, saved.IsWebinar())
+
+	// Ending the webinar keeps its type
+	assert.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: webinar.ID, Topic: "All Hands", Type: models.MeetingTypeWebinar, Status: models.MeetingStatusEnded}))
+	meetings, err := repo.ListAllMeetings(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, meetings, 1)
+	assert.True(t, meetings[0].IsWebinar())
+}
+
+func TestWaitingRoomOperations(t *testing.T) {
+	repo, _, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Waiting room operations need the meeting to exist
+	_, err := repo.CountParticipantsInWaitingRoom(ctx, "missing")
+	assert.ErrorIs(t, err, redis.ErrNotFound)
+
+	meeting := &models.Meeting{ID: "meeting789", Status: models.MeetingStatusCreated}
+	assert.NoError(t, repo.SaveMeeting(ctx, meeting))
+
+	assert.NoError(t, repo.AddParticipantToWaitingRoom(ctx, meeting.ID, "user123"))
+	assert.NoError(t, repo.AddParticipantToWaitingRoom(ctx, meeting.ID, "user456"))
+	// Adding the same participant twice counts once
+	assert.NoError(t, repo.AddParticipantToWaitingRoom(ctx, meeting.ID, "user456"))
+
+	count, err := repo.CountParticipantsInWaitingRoom(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// Waiting participants are not counted as in the meeting
+	count, err = repo.CountParticipantsInMeeting(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	assert.NoError(t, repo.RemoveParticipantFromWaitingRoom(ctx, meeting.ID, "user123"))
+	count, err = repo.CountParticipantsInWaitingRoom(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	assert.NoError(t, repo.ClearWaitingRoom(ctx, meeting.ID))
+	count, err = repo.CountParticipantsInWaitingRoom(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestBreakoutRoomOperations(t *testing.T) {
+	repo, _, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Breakout room operations need the meeting to exist
+	_, err := repo.ListBreakoutRooms(ctx, "missing")
+	assert.ErrorIs(t, err, redis.ErrNotFound)
+
+	meeting := &models.Meeting{ID: "meeting321", Status: models.MeetingStatusStarted}
+	assert.NoError(t, repo.SaveMeeting(ctx, meeting))
+
+	rooms, err := repo.ListBreakoutRooms(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, rooms)
+
+	assert.NoError(t, repo.AddParticipantToBreakoutRoom(ctx, meeting.ID, "roomA", "user1"))
+	assert.NoError(t, repo.AddParticipantToBreakoutRoom(ctx, meeting.ID, "roomA", "user2"))
+	assert.NoError(t, repo.AddParticipantToBreakoutRoom(ctx, meeting.ID, "roomB", "user3"))
+
+	rooms, err = repo.ListBreakoutRooms(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.BreakoutRoom{{ID: "roomA", ParticipantCount: 2}, {ID: "roomB", ParticipantCount: 1}}, rooms)
+
+	// Joining another breakout room moves the participant
+	assert.NoError(t, repo.AddParticipantToBreakoutRoom(ctx, meeting.ID, "roomB", "user2"))
+	// A late leave of the previous room does not remove them from the new one
+	assert.NoError(t, repo.RemoveParticipantFromBreakoutRoom(ctx, meeting.ID, "roomA", "user2"))
+
+	rooms, err = repo.ListBreakoutRooms(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.BreakoutRoom{{ID: "roomA", ParticipantCount: 1}, {ID: "roomB", ParticipantCount: 2}}, rooms)
+
+	// Empty breakout rooms are not listed
+	assert.NoError(t, repo.RemoveParticipantFromBreakoutRoom(ctx, meeting.ID, "roomA", "user1"))
+	rooms, err = repo.ListBreakoutRooms(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.BreakoutRoom{{ID: "roomB", ParticipantCount: 2}}, rooms)
+
+	assert.NoError(t, repo.ClearBreakoutRooms(ctx, meeting.ID))
+	rooms, err = repo.ListBreakoutRooms(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, rooms)
+}
+
+func TestMeetingIndicators(t *testing.T) {
+	repo, _, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Indicators need the meeting to exist
+	assert.ErrorIs(t, repo.SetMeetingRecording(ctx, "missing", models.RecordingStatusRecording), redis.ErrNotFound)
+	assert.ErrorIs(t, repo.SetMeetingSharing(ctx, "missing", true), redis.ErrNotFound)
+
+	meeting := &models.Meeting{ID: "meeting654", Topic: "Demo", Status: models.MeetingStatusStarted}
+	assert.NoError(t, repo.SaveMeeting(ctx, meeting))
+
+	assert.NoError(t, repo.SetMeetingRecording(ctx, meeting.ID, models.RecordingStatusRecording))
+	assert.NoError(t, repo.SetMeetingSharing(ctx, meeting.ID, true))
+
+	saved, err := repo.GetMeeting(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RecordingStatusRecording, saved.Recording)
+	assert.True(t, saved.Sharing)
+
+	// Saving the meeting again does not reset the indicators
+	assert.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: meeting.ID, Topic: "Demo (renamed)", Status: models.MeetingStatusStarted}))
+	meetings, err := repo.ListMeetings(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, meetings, 1)
+	assert.True(t, meetings[0].IsRecording())
+	assert.True(t, meetings[0].Sharing)
+
+	assert.NoError(t, repo.SetMeetingRecording(ctx, meeting.ID, models.RecordingStatusPaused))
+	assert.NoError(t, repo.SetMeetingSharing(ctx, meeting.ID, false))
+	meetings, err = repo.ListAllMeetings(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, meetings, 1)
+	assert.Equal(t, models.RecordingStatusPaused, meetings[0].Recording)
+	assert.False(t, meetings[0].Sharing)
+
+	assert.NoError(t, repo.SetMeetingRecording(ctx, meeting.ID, models.RecordingStatusNone))
+	saved, err = repo.GetMeeting(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.False(t, saved.IsRecording())
+}
+
+func TestMeetingAccount(t *testing.T) {
+	repo, _, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	meeting := &models.Meeting{ID: models.MeetingKey("sandbox123", "meeting1"), AccountID: "sandbox123", Topic: "Sandbox Test", Status: models.MeetingStatusStarted}
+	assert.NoError(t, repo.SaveMeeting(ctx, meeting))
+	assert.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "meeting1", AccountID: "main123", Topic: "Main Test", Status: models.MeetingStatusStarted}))
+
+	saved, err := repo.GetMeeting(ctx, meeting.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "sandbox123", saved.AccountID)
+	assert.Equal(t, "Sandbox Test", saved.Topic)
+	assert.Equal(t, "meeting1", saved.ZoomID())
+
+	meetings, err := repo.ListMeetings(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, meetings, 2)
+}
+
+func TestMeetingStatusIndex(t *testing.T) {
+	repo, mr, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	t.Run("SaveMovesMeetingBetweenStatuses", func(t *testing.T) {
+		meeting := &models.Meeting{ID: "indexed1", Status: models.MeetingStatusStarted}
+		require.NoError(t, repo.SaveMeeting(ctx, meeting))
+		members, err := mr.SMembers("test:meetingindex:started")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"indexed1"}, members)
+
+		meeting.Status = models.MeetingStatusEnded
+		require.NoError(t, repo.SaveMeeting(ctx, meeting))
+		assert.False(t, mr.Exists("test:meetingindex:started"), "Meeting should leave the index of its old status")
+		members, err = mr.SMembers("test:meetingindex:ended")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"indexed1"}, members)
+
+		active, err := repo.ListMeetings(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, active)
+
+		all, err := repo.ListAllMeetings(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 1)
+		assert.Equal(t, models.MeetingStatusEnded, all[0].Status)
+	})
+
+	t.Run("DeleteRemovesIndexEntry", func(t *testing.T) {
+		require.NoError(t, repo.DeleteMeeting(ctx, "indexed1"))
+		assert.False(t, mr.Exists("test:meetingindex:ended"))
+	})
+
+	t.Run("SubKeysAreNotListed", func(t *testing.T) {
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "indexed2", Status: models.MeetingStatusStarted}))
+		require.NoError(t, repo.AddParticipantToMeeting(ctx, "indexed2", "user1"))
+		require.NoError(t, repo.SetMeetingSharing(ctx, "indexed2", true))
+
+		all, err := repo.ListAllMeetings(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 1)
+		assert.Equal(t, "indexed2", all[0].ID)
+	})
+
+	t.Run("ExpiredMeetingsArePruned", func(t *testing.T) {
+		mr.FastForward(25 * time.Hour)
+
+		all, err := repo.ListAllMeetings(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, all)
+		assert.False(t, mr.Exists("test:meetingindex:started"), "Expired meeting should be removed from the index")
+	})
+
+	t.Run("ClearRemovesIndex", func(t *testing.T) {
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "indexed3", Status: models.MeetingStatusCreated}))
+		require.NoError(t, repo.ClearMeetings(ctx))
+		assert.False(t, mr.Exists("test:meetingindex:created"))
+
+		all, err := repo.ListAllMeetings(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, all)
+	})
+}
+
+func TestMeetingStatusIndexMigration(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+
+	// Meetings stored before the index existed, with their participant sets
+	require.NoError(t, mr.Set("test:meetings:legacy1", `{"ID":"legacy1","Status":2}`))
+	require.NoError(t, mr.Set("test:meetings:sandbox:legacy2", `{"ID":"sandbox:legacy2","AccountID":"sandbox","Status":3}`))
+	_, err = mr.SetAdd("test:meetings:legacy1:participants", "user1")
+	require.NoError(t, err)
+
+	cfg := config.RedisConfig{
+		Enabled:    true,
+		Host:       mr.Host(),
+		Port:       mr.Port(),
+		KeyPrefix:  "test:",
+		MeetingTTL: time.Hour * 24,
+	}
+	repo, err := redis.NewRepository(cfg)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	ctx := context.Background()
+
+	active, err := repo.ListMeetings(ctx)
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	assert.Equal(t, "legacy1", active[0].ID)
+
+	all, err := repo.ListAllMeetings(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+	assert.True(t, mr.Exists("test:meetingindex:migrated"))
+
+	// A meeting key written without the index after migration is not picked up again on the next start
+	require.NoError(t, mr.Set("test:meetings:legacy3", `{"ID":"legacy3","Status":2}`))
+	repo2, err := redis.NewRepository(cfg)
+	require.NoError(t, err)
+	defer repo2.Close()
+
+	all, err = repo2.ListAllMeetings(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+}
+
+func TestMeetingStateIsMerged(t *testing.T) {
+	repo, _, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{
+		ID:            "merged1",
+		Topic:         "Planning",
+		Status:        models.MeetingStatusCreated,
+		Duration:      60,
+		Host:          models.Participant{ID: "host1"},
+		OperatorEmail: "operator@example.com",
+	}))
+	require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "merged1", Status: models.MeetingStatusStarted, StartTime: time.Now()}))
+
+	saved, err := repo.GetMeeting(ctx, "merged1")
+	require.NoError(t, err)
+	assert.Equal(t, models.MeetingStatusStarted, saved.Status)
+	assert.Equal(t, "Planning", saved.Topic)
+	assert.Equal(t, 60, saved.Duration)
+	assert.Equal(t, "host1", saved.Host.ID)
+	assert.Equal(t, "operator@example.com", saved.OperatorEmail)
+
+	meetings, err := repo.ListMeetings(ctx)
+	require.NoError(t, err)
+	require.Len(t, meetings, 1)
+	assert.Equal(t, "operator@example.com", meetings[0].OperatorEmail)
+}
+
+func TestParticipantOperationsAreAtomic(t *testing.T) {
+	repo, mr, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	t.Run("MissingMeetingLeavesNoSet", func(t *testing.T) {
+		err := repo.AddParticipantToMeeting(ctx, "missing", "user1")
+		assert.True(t, errors.Is(err, redis.ErrNotFound))
+		err = repo.AddParticipantToWaitingRoom(ctx, "missing", "user1")
+		assert.True(t, errors.Is(err, redis.ErrNotFound))
+		err = repo.RemoveParticipantFromMeeting(ctx, "missing", "user1")
+		assert.True(t, errors.Is(err, redis.ErrNotFound))
+		err = repo.ClearPartipantsInMeeting(ctx, "missing")
+		assert.True(t, errors.Is(err, redis.ErrNotFound))
+
+		assert.False(t, mr.Exists("test:meetings:missing:participants"))
+		assert.False(t, mr.Exists("test:meetings:missing:waiting"))
+	})
+
+	t.Run("ParticipantSetExpiresWithMeeting", func(t *testing.T) {
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "ttl1", Status: models.MeetingStatusStarted}))
+		mr.FastForward(time.Hour)
+
+		require.NoError(t, repo.AddParticipantToMeeting(ctx, "ttl1", "user1"))
+		require.NoError(t, repo.AddParticipantToWaitingRoom(ctx, "ttl1", "user2"))
+		assert.Equal(t, 24*time.Hour, mr.TTL("test:meetings:ttl1"), "Participant activity should refresh the meeting TTL")
+		assert.Equal(t, 24*time.Hour, mr.TTL("test:meetings:ttl1:participants"))
+		assert.Equal(t, 24*time.Hour, mr.TTL("test:meetings:ttl1:waiting"))
+
+		mr.FastForward(time.Hour)
+		require.NoError(t, repo.RemoveParticipantFromMeeting(ctx, "ttl1", "user3"))
+		assert.Equal(t, mr.TTL("test:meetings:ttl1"), mr.TTL("test:meetings:ttl1:participants"))
+
+		mr.FastForward(time.Hour)
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "ttl1", Status: models.MeetingStatusStarted}))
+		assert.Equal(t, 24*time.Hour, mr.TTL("test:meetings:ttl1:participants"), "Saving the meeting should refresh the participant TTL")
+		assert.Equal(t, 24*time.Hour, mr.TTL("test:meetings:ttl1:waiting"))
+
+		mr.FastForward(25 * time.Hour)
+		assert.False(t, mr.Exists("test:meetings:ttl1"))
+		assert.False(t, mr.Exists("test:meetings:ttl1:participants"))
+		assert.False(t, mr.Exists("test:meetings:ttl1:waiting"))
+	})
+
+	t.Run("NoOrphanAfterDelete", func(t *testing.T) {
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "deleted1", Status: models.MeetingStatusStarted}))
+		require.NoError(t, repo.AddParticipantToMeeting(ctx, "deleted1", "user1"))
+		require.NoError(t, repo.DeleteMeeting(ctx, "deleted1"))
+
+		err := repo.AddParticipantToMeeting(ctx, "deleted1", "user2")
+		assert.True(t, errors.Is(err, redis.ErrNotFound))
+		assert.False(t, mr.Exists("test:meetings:deleted1:participants"))
+	})
+
+	t.Run("NoOrphanHashAfterDelete", func(t *testing.T) {
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "deleted2", Status: models.MeetingStatusStarted}))
+		require.NoError(t, repo.AddParticipantToBreakoutRoom(ctx, "deleted2", "breakoutA", "user1"))
+		require.NoError(t, repo.SetMeetingSharing(ctx, "deleted2", true))
+		require.NoError(t, repo.DeleteMeeting(ctx, "deleted2"))
+
+		assert.ErrorIs(t, repo.AddParticipantToBreakoutRoom(ctx, "deleted2", "breakoutA", "user2"), redis.ErrNotFound)
+		assert.ErrorIs(t, repo.RemoveParticipantFromBreakoutRoom(ctx, "deleted2", "breakoutA", "user1"), redis.ErrNotFound)
+		assert.ErrorIs(t, repo.ClearBreakoutRooms(ctx, "deleted2"), redis.ErrNotFound)
+		assert.ErrorIs(t, repo.SetMeetingRecording(ctx, "deleted2", models.RecordingStatusRecording), redis.ErrNotFound)
+		assert.ErrorIs(t, repo.SetMeetingSharing(ctx, "deleted2", false), redis.ErrNotFound)
+		assert.False(t, mr.Exists("test:meetings:deleted2:breakouts"))
+		assert.False(t, mr.Exists("test:meetings:deleted2:indicators"))
+	})
+
+	t.Run("HashesExpireWithMeeting", func(t *testing.T) {
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "ttl2", Status: models.MeetingStatusStarted}))
+		mr.FastForward(time.Hour)
+
+		require.NoError(t, repo.AddParticipantToBreakoutRoom(ctx, "ttl2", "breakoutA", "user1"))
+		require.NoError(t, repo.SetMeetingRecording(ctx, "ttl2", models.RecordingStatusRecording))
+		assert.Equal(t, 24*time.Hour, mr.TTL("test:meetings:ttl2"))
+		assert.Equal(t, 24*time.Hour, mr.TTL("test:meetings:ttl2:breakouts"))
+		assert.Equal(t, 24*time.Hour, mr.TTL("test:meetings:ttl2:indicators"))
+	})
+
+	t.Run("LeaveRefreshesBreakoutHash", func(t *testing.T) {
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "ttl3", Status: models.MeetingStatusStarted}))
+		require.NoError(t, repo.AddParticipantToMeeting(ctx, "ttl3", "user1"))
+		require.NoError(t, repo.AddParticipantToBreakoutRoom(ctx, "ttl3", "breakoutA", "user1"))
+		require.NoError(t, repo.AddParticipantToBreakoutRoom(ctx, "ttl3", "breakoutA", "user2"))
+		mr.FastForward(time.Hour)
+
+		// The breakout hash keeps expiring together with the meeting after a participant leaves
+		require.NoError(t, repo.RemoveParticipantFromMeeting(ctx, "ttl3", "user1"))
+		assert.Equal(t, 24*time.Hour, mr.TTL("test:meetings:ttl3"))
+		assert.Equal(t, 24*time.Hour, mr.TTL("test:meetings:ttl3:breakouts"))
+	})
+
+	t.Run("ClearRemovesSet", func(t *testing.T) {
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "clear1", Status: models.MeetingStatusStarted}))
+		require.NoError(t, repo.AddParticipantToMeeting(ctx, "clear1", "user1"))
+		require.NoError(t, repo.AddParticipantToWaitingRoom(ctx, "clear1", "user2"))
+
+		require.NoError(t, repo.ClearPartipantsInMeeting(ctx, "clear1"))
+		require.NoError(t, repo.ClearWaitingRoom(ctx, "clear1"))
+		assert.False(t, mr.Exists("test:meetings:clear1:participants"))
+		assert.False(t, mr.Exists("test:meetings:clear1:waiting"))
+	})
+
+	t.Run("ConcurrentJoins", func(t *testing.T) {
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "busy1", Status: models.MeetingStatusStarted}))
+
+		var wg sync.WaitGroup
+		for i := range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, repo.AddParticipantToMeeting(ctx, "busy1", fmt.Sprintf("user%d", i)))
+			}()
+		}
+		wg.Wait()
+
+		count, err := repo.CountParticipantsInMeeting(ctx, "busy1")
+		require.NoError(t, err)
+		assert.Equal(t, 20, count)
+	})
+}
+
+func TestCheckMeetingRecords(t *testing.T) {
+	repo, mr, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "current1", Status: models.MeetingStatusStarted}))
+	require.NoError(t, repo.AddParticipantToMeeting(ctx, "current1", "user1"))
+	require.NoError(t, mr.Set("test:meetings:legacy1", `{"ID":"legacy1","Topic":"Legacy","Status":2,"ParticipantIDs":null}`))
+	mr.SetTTL("test:meetings:legacy1", time.Hour)
+	require.NoError(t, mr.Set("test:meetings:broken1", `{"ID":`))
+	require.NoError(t, mr.Set("test:meetings:future1", `{"Version":99,"ID":"future1"}`))
+
+	t.Run("ReportsUnreadableRecords", func(t *testing.T) {
+		report, err := repo.CheckMeetingRecords(ctx, false)
+		require.NoError(t, err)
+		assert.Equal(t, 4, report.Checked)
+		assert.Equal(t, 1, report.Outdated)
+		assert.Equal(t, 0, report.Migrated)
+		require.Len(t, report.Unreadable, 2)
+
+		keys := []string{report.Unreadable[0].Key, report.Unreadable[1].Key}
+		assert.ElementsMatch(t, []string{"test:meetings:broken1", "test:meetings:future1"}, keys)
+
+		stored, err := mr.Get("test:meetings:legacy1")
+		require.NoError(t, err)
+		assert.NotContains(t, stored, "Version", "Checking should not rewrite records")
+	})
+
+	t.Run("OutdatedRecordsAreUpgradedOnRead", func(t *testing.T) {
+		meeting, err := repo.GetMeeting(ctx, "legacy1")
+		require.NoError(t, err)
+		assert.Equal(t, "Legacy", meeting.Topic)
+	})
+
+	t.Run("MigrateRewritesOutdatedRecords", func(t *testing.T) {
+		report, err := repo.CheckMeetingRecords(ctx, true)
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Migrated)
+
+		stored, err := mr.Get("test:meetings:legacy1")
+		require.NoError(t, err)
+		assert.Contains(t, stored, `"Version":1`)
+		assert.NotContains(t, stored, "ParticipantIDs")
+		assert.Equal(t, time.Hour, mr.TTL("test:meetings:legacy1"), "Migration should keep the expiry")
+
+		report, err = repo.CheckMeetingRecords(ctx, false)
+		require.NoError(t, err)
+		assert.Equal(t, 0, report.Outdated)
+	})
+}
+
+func TestRepositorySuite(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.Repository {
+		repo, _, cleanup := setupTestRedis(t)
+		t.Cleanup(cleanup)
+		return repo
+	}, redis.ErrNotFound)
+}
@@ -0,0 +1,436 @@
+// Package repositorytest provides a test suite every repository backend must pass,
+// so the backends behave the same and can be switched without changing what is shown
+package repositorytest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/navikt/zrooms/internal/models"
+	"github.com/navikt/zrooms/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run runs the suite against repositories created by newRepository, which must return an empty
+// repository for every call. errNotFound is the error the backend returns for missing entities.
+func Run(t *testing.T, newRepository func(t *testing.T) repository.Repository, errNotFound error) {
+	ctx := context.Background()
+
+	t.Run("Meetings", func(t *testing.T) {
+		repo := newRepository(t)
+
+		_, err := repo.GetMeeting(ctx, "missing")
+		assert.ErrorIs(t, err, errNotFound)
+
+		start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{
+			ID:            "acc:100",
+			AccountID:     "acc",
+			Topic:         "Standup",
+			Type:          models.MeetingTypeWebinar,
+			Status:        models.MeetingStatusCreated,
+			StartTime:     start,
+			Duration:      30,
+			Host:          models.Participant{ID: "host1"},
+			OperatorEmail: "operator@example.com",
+			LastEventTS:   100,
+		}))
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "200", Status: models.MeetingStatusEnded}))
+
+		meeting, err := repo.GetMeeting(ctx, "acc:100")
+		require.NoError(t, err)
+		assert.Equal(t, "acc", meeting.AccountID)
+		assert.Equal(t, "100", meeting.ZoomID())
+		assert.Equal(t, "Standup", meeting.Topic)
+		assert.True(t, meeting.IsWebinar())
+		assert.True(t, start.Equal(meeting.StartTime))
+		assert.Equal(t, 30, meeting.Duration)
+		assert.Equal(t, "host1", meeting.Host.ID)
+		assert.Equal(t, "operator@example.com", meeting.OperatorEmail)
+		assert.Equal(t, int64(100), meeting.LastEventTS)
+		assert.Empty(t, meeting.Participants, "Should not store participant details")
+
+		active, err := repo.ListMeetings(ctx)
+		require.NoError(t, err)
+		require.Len(t, active, 1)
+		assert.Equal(t, "acc:100", active[0].ID)
+		assert.Equal(t, "operator@example.com", active[0].OperatorEmail)
+
+		all, err := repo.ListAllMeetings(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 2)
+
+		require.NoError(t, repo.DeleteMeeting(ctx, "acc:100"))
+		_, err = repo.GetMeeting(ctx, "acc:100")
+		assert.ErrorIs(t, err, errNotFound)
+		assert.ErrorIs(t, repo.DeleteMeeting(ctx, "acc:100"), errNotFound)
+	})
+
+	t.Run("MeetingUpdatesAreMerged", func(t *testing.T) {
+		repo := newRepository(t)
+
+		start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{
+			ID:            "100",
+			Topic:         "Planning",
+			Status:        models.MeetingStatusStarted,
+			StartTime:     start,
+			Duration:      60,
+			Host:          models.Participant{ID: "host1"},
+			OperatorEmail: "operator@example.com",
+			LastEventTS:   200,
+		}))
+
+		// Updates only carry the planned start, which must not replace the actual start
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "100", Status: models.MeetingStatusUpdated, StartTime: start.Add(time.Hour), Topic: "Renamed"}))
+		end := start.Add(50 * time.Minute)
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "100", Status: models.MeetingStatusEnded, EndTime: end, LastEventTS: 150}))
+
+		meeting, err := repo.GetMeeting(ctx, "100")
+		require.NoError(t, err)
+		assert.Equal(t, models.MeetingStatusEnded, meeting.Status)
+		assert.Equal(t, "Renamed", meeting.Topic)
+		assert.True(t, start.Equal(meeting.StartTime))
+		assert.True(t, end.Equal(meeting.EndTime))
+		assert.Equal(t, 60, meeting.Duration)
+		assert.Equal(t, "host1", meeting.Host.ID)
+		assert.Equal(t, "operator@example.com", meeting.OperatorEmail)
+		assert.Equal(t, int64(200), meeting.LastEventTS, "Last event timestamp should not move backwards")
+	})
+
+	t.Run("Participants", func(t *testing.T) {
+		repo := newRepository(t)
+
+		assert.ErrorIs(t, repo.AddParticipantToMeeting(ctx, "missing", "user1"), errNotFound)
+		assert.ErrorIs(t, repo.RemoveParticipantFromMeeting(ctx, "missing", "user1"), errNotFound)
+		assert.ErrorIs(t, repo.ClearPartipantsInMeeting(ctx, "missing"), errNotFound)
+		_, err := repo.CountParticipantsInMeeting(ctx, "missing")
+		assert.ErrorIs(t, err, errNotFound)
+
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "100", Status: models.MeetingStatusStarted}))
+		require.NoError(t, repo.AddParticipantToMeeting(ctx, "100", "user1"))
+		require.NoError(t, repo.AddParticipantToMeeting(ctx, "100", "user2"))
+		require.NoError(t, repo.AddParticipantToMeeting(ctx, "100", "user2"))
+		assertCount(t, 2, repo.CountParticipantsInMeeting, "100")
+
+		require.NoError(t, repo.RemoveParticipantFromMeeting(ctx, "100", "user1"))
+		require.NoError(t, repo.RemoveParticipantFromMeeting(ctx, "100", "unknown"))
+		assertCount(t, 1, repo.CountParticipantsInMeeting, "100")
+
+		require.NoError(t, repo.ClearPartipantsInMeeting(ctx, "100"))
+		assertCount(t, 0, repo.CountParticipantsInMeeting, "100")
+
+		// Saving the meeting again keeps its participants
+		require.NoError(t, repo.AddParticipantToMeeting(ctx, "100", "user3"))
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "100", Status: models.MeetingStatusStarted}))
+		assertCount(t, 1, repo.CountParticipantsInMeeting, "100")
+
+		// Deleting the meeting removes its participants
+		require.NoError(t, repo.DeleteMeeting(ctx, "100"))
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "100", Status: models.MeetingStatusStarted}))
+		assertCount(t, 0, repo.CountParticipantsInMeeting, "100")
+	})
+
+	t.Run("ParticipantEvents", func(t *testing.T) {
+		repo := newRepository(t)
+
+		_, err := repo.RecordParticipantEvent(ctx, "missing", "user1", 1000)
+		assert.ErrorIs(t, err, errNotFound)
+
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "100", Status: models.MeetingStatusStarted}))
+		recorded, err := repo.RecordParticipantEvent(ctx, "100", "user1", 2000)
+		require.NoError(t, err)
+		assert.True(t, recorded)
+
+		// Older events are not recorded, while events as recent or later are
+		recorded, err = repo.RecordParticipantEvent(ctx, "100", "user1", 1000)
+		require.NoError(t, err)
+		assert.False(t, recorded)
+		recorded, err = repo.RecordParticipantEvent(ctx, "100", "user1", 2000)
+		require.NoError(t, err)
+		assert.True(t, recorded)
+		recorded, err = repo.RecordParticipantEvent(ctx, "100", "user1", 3000)
+		require.NoError(t, err)
+		assert.True(t, recorded)
+
+		// Each participant has their own event_ts
+		recorded, err = repo.RecordParticipantEvent(ctx, "100", "user2", 1000)
+		require.NoError(t, err)
+		assert.True(t, recorded)
+
+		// Deleting the meeting removes the recorded event_ts
+		require.NoError(t, repo.DeleteMeeting(ctx, "100"))
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "100", Status: models.MeetingStatusStarted}))
+		recorded, err = repo.RecordParticipantEvent(ctx, "100", "user1", 1000)
+		require.NoError(t, err)
+		assert.True(t, recorded)
+	})
+
+	t.Run("WaitingRoom", func(t *testing.T) {
+		repo := newRepository(t)
+
+		assert.ErrorIs(t, repo.AddParticipantToWaitingRoom(ctx, "missing", "user1"), errNotFound)
+
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "100", Status: models.MeetingStatusStarted}))
+		require.NoError(t, repo.AddParticipantToWaitingRoom(ctx, "100", "user1"))
+		require.NoError(t, repo.AddParticipantToWaitingRoom(ctx, "100", "user2"))
+		assertCount(t, 2, repo.CountParticipantsInWaitingRoom, "100")
+		assertCount(t, 0, repo.CountParticipantsInMeeting, "100")
+
+		require.NoError(t, repo.RemoveParticipantFromWaitingRoom(ctx, "100", "user1"))
+		assertCount(t, 1, repo.CountParticipantsInWaitingRoom, "100")
+
+		require.NoError(t, repo.ClearWaitingRoom(ctx, "100"))
+		assertCount(t, 0, repo.CountParticipantsInWaitingRoom, "100")
+	})
+
+	t.Run("BreakoutRooms", func(t *testing.T) {
+		repo := newRepository(t)
+
+		_, err := repo.ListBreakoutRooms(ctx, "missing")
+		assert.ErrorIs(t, err, errNotFound)
+
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "100", Status: models.MeetingStatusStarted}))
+		require.NoError(t, repo.AddParticipantToBreakoutRoom(ctx, "100", "roomB", "user1"))
+		require.NoError(t, repo.AddParticipantToBreakoutRoom(ctx, "100", "roomA", "user2"))
+		require.NoError(t, repo.AddParticipantToBreakoutRoom(ctx, "100", "roomB", "user3"))
+
+		rooms, err := repo.ListBreakoutRooms(ctx, "100")
+		require.NoError(t, err)
+		assert.Equal(t, []models.BreakoutRoom{{ID: "roomA", ParticipantCount: 1}, {ID: "roomB", ParticipantCount: 2}}, rooms)
+
+		// Moving to another room, then leaving the old one, keeps the participant in the new room
+		require.NoError(t, repo.AddParticipantToBreakoutRoom(ctx, "100", "roomA", "user1"))
+		require.NoError(t, repo.RemoveParticipantFromBreakoutRoom(ctx, "100", "roomB", "user1"))
+		rooms, err = repo.ListBreakoutRooms(ctx, "100")
+		require.NoError(t, err)
+		assert.Equal(t, []models.BreakoutRoom{{ID: "roomA", ParticipantCount: 2}, {ID: "roomB", ParticipantCount: 1}}, rooms)
+
+		// Leaving the meeting straight from a breakout room also leaves the breakout room
+		require.NoError(t, repo.AddParticipantToMeeting(ctx, "100", "user3"))
+		require.NoError(t, repo.RemoveParticipantFromMeeting(ctx, "100", "user3"))
+		rooms, err = repo.ListBreakoutRooms(ctx, "100")
+		require.NoError(t, err)
+		assert.Equal(t, []models.BreakoutRoom{{ID: "roomA", ParticipantCount: 2}}, rooms)
+
+		require.NoError(t, repo.ClearBreakoutRooms(ctx, "100"))
+		rooms, err = repo.ListBreakoutRooms(ctx, "100")
+		require.NoError(t, err)
+		assert.Empty(t, rooms)
+	})
+
+	t.Run("Indicators", func(t *testing.T) {
+		repo := newRepository(t)
+
+		assert.ErrorIs(t, repo.SetMeetingRecording(ctx, "missing", models.RecordingStatusRecording), errNotFound)
+
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "100", Status: models.MeetingStatusStarted}))
+		require.NoError(t, repo.SetMeetingRecording(ctx, "100", models.RecordingStatusPaused))
+		require.NoError(t, repo.SetMeetingSharing(ctx, "100", true))
+
+		// Saving the meeting does not reset the indicators
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "100", Status: models.MeetingStatusStarted}))
+
+		meeting, err := repo.GetMeeting(ctx, "100")
+		require.NoError(t, err)
+		assert.Equal(t, models.RecordingStatusPaused, meeting.Recording)
+		assert.True(t, meeting.Sharing)
+
+		require.NoError(t, repo.SetMeetingRecording(ctx, "100", models.RecordingStatusNone))
+		require.NoError(t, repo.SetMeetingSharing(ctx, "100", false))
+		meetings, err := repo.ListMeetings(ctx)
+		require.NoError(t, err)
+		require.Len(t, meetings, 1)
+		assert.False(t, meetings[0].IsRecording())
+		assert.False(t, meetings[0].Sharing)
+	})
+
+	t.Run("Rooms", func(t *testing.T) {
+		repo := newRepository(t)
+
+		_, err := repo.GetRoom(ctx, "missing")
+		assert.ErrorIs(t, err, errNotFound)
+
+		require.NoError(t, repo.SaveRoom(ctx, &models.Room{ID: "room1", Name: "Oslo", Status: models.RoomStatusInMeeting, MeetingID: "100"}))
+		require.NoError(t, repo.SaveRoom(ctx, &models.Room{ID: "room2", Name: "Bergen", Status: models.RoomStatusAvailable}))
+		require.NoError(t, repo.SaveRoom(ctx, &models.Room{ID: "room1", Name: "Oslo", Status: models.RoomStatusAvailable}))
+
+		room, err := repo.GetRoom(ctx, "room1")
+		require.NoError(t, err)
+		assert.Equal(t, models.RoomStatusAvailable, room.Status)
+		assert.Empty(t, room.MeetingID)
+
+		rooms, err := repo.ListRooms(ctx)
+		require.NoError(t, err)
+		assert.Len(t, rooms, 2)
+
+		require.NoError(t, repo.ClearRooms(ctx))
+		rooms, err = repo.ListRooms(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, rooms)
+	})
+
+	t.Run("DeviceAlerts", func(t *testing.T) {
+		repo := newRepository(t)
+
+		_, err := repo.GetDeviceAlert(ctx, "missing")
+		assert.ErrorIs(t, err, errNotFound)
+
+		require.NoError(t, repo.SaveDeviceAlert(ctx, &models.DeviceAlert{ID: "alert1", RoomID: "room1", Issue: "Camera disconnected"}))
+		require.NoError(t, repo.SaveDeviceAlert(ctx, &models.DeviceAlert{ID: "alert2", RoomID: "room2", Issue: "Microphone disconnected"}))
+
+		alert, err := repo.GetDeviceAlert(ctx, "alert1")
+		require.NoError(t, err)
+		assert.Equal(t, "Camera disconnected", alert.Issue)
+
+		alerts, err := repo.ListDeviceAlerts(ctx)
+		require.NoError(t, err)
+		assert.Len(t, alerts, 2)
+
+		require.NoError(t, repo.ClearDeviceAlerts(ctx))
+		alerts, err = repo.ListDeviceAlerts(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, alerts)
+	})
+
+	t.Run("WebhookSeen", func(t *testing.T) {
+		repo := newRepository(t)
+
+		first, err := repo.MarkWebhookSeen(ctx, "delivery1", time.Hour)
+		require.NoError(t, err)
+		assert.True(t, first)
+
+		first, err = repo.MarkWebhookSeen(ctx, "delivery1", time.Hour)
+		require.NoError(t, err)
+		assert.False(t, first)
+
+		require.NoError(t, repo.UnmarkWebhookSeen(ctx, "delivery1"))
+		first, err = repo.MarkWebhookSeen(ctx, "delivery1", time.Hour)
+		require.NoError(t, err)
+		assert.True(t, first)
+	})
+
+	t.Run("DeadLetters", func(t *testing.T) {
+		repo := newRepository(t)
+
+		_, err := repo.GetDeadLetter(ctx, "missing")
+		assert.ErrorIs(t, err, errNotFound)
+		assert.ErrorIs(t, repo.DeleteDeadLetter(ctx, "missing"), errNotFound)
+
+		now := time.Now()
+		older := &models.DeadLetter{ID: "dl1", Event: models.WebhookEvent{Event: "meeting.started"}, Reason: "failed", Attempts: 3, FailedAt: now.Add(-time.Minute)}
+		newer := &models.DeadLetter{ID: "dl2", Event: models.WebhookEvent{Event: "meeting.ended"}, Reason: "failed", Attempts: 1, FailedAt: now}
+		require.NoError(t, repo.SaveDeadLetter(ctx, older))
+		require.NoError(t, repo.SaveDeadLetter(ctx, newer))
+
+		deadLetter, err := repo.GetDeadLetter(ctx, "dl1")
+		require.NoError(t, err)
+		assert.Equal(t, "meeting.started", deadLetter.Event.Event)
+		assert.Equal(t, 3, deadLetter.Attempts)
+
+		deadLetters, err := repo.ListDeadLetters(ctx)
+		require.NoError(t, err)
+		require.Len(t, deadLetters, 2)
+		assert.Equal(t, "dl2", deadLetters[0].ID, "Most recent failure should be first")
+
+		require.NoError(t, repo.DeleteDeadLetter(ctx, "dl1"))
+		deadLetters, err = repo.ListDeadLetters(ctx)
+		require.NoError(t, err)
+		assert.Len(t, deadLetters, 1)
+	})
+
+	t.Run("DeadLettersAreCapped", func(t *testing.T) {
+		repo := newRepository(t)
+
+		const extra = 2
+		start := time.Now().Add(-time.Hour)
+		for i := range models.DeadLetterCapacity + extra {
+			deadLetter := &models.DeadLetter{ID: fmt.Sprintf("dl%d", i), Event: models.WebhookEvent{Event: "meeting.started"}, Reason: "failed", FailedAt: start.Add(time.Duration(i) * time.Second)}
+			require.NoError(t, repo.SaveDeadLetter(ctx, deadLetter))
+		}
+
+		// The oldest failures are dropped, and replacing a kept dead letter does not drop another
+		deadLetters, err := repo.ListDeadLetters(ctx)
+		require.NoError(t, err)
+		assert.Len(t, deadLetters, models.DeadLetterCapacity)
+		for i := range extra {
+			_, err := repo.GetDeadLetter(ctx, fmt.Sprintf("dl%d", i))
+			assert.ErrorIs(t, err, errNotFound)
+		}
+
+		replaced, err := repo.GetDeadLetter(ctx, fmt.Sprintf("dl%d", extra))
+		require.NoError(t, err)
+		replaced.Attempts++
+		replaced.FailedAt = time.Now()
+		require.NoError(t, repo.SaveDeadLetter(ctx, replaced))
+
+		deadLetters, err = repo.ListDeadLetters(ctx)
+		require.NoError(t, err)
+		assert.Len(t, deadLetters, models.DeadLetterCapacity)
+		assert.Equal(t, replaced.ID, deadLetters[0].ID)
+	})
+
+	t.Run("EventJournal", func(t *testing.T) {
+		repo := newRepository(t)
+
+		events := []*models.WebhookEvent{
+			{Event: "meeting.started", Payload: json.RawMessage(`{"object":{"id":"100"}}`), EventTS: 1},
+			{Event: "meeting.participant_joined", Payload: json.RawMessage(`{"object":{"id":"100","participant":{"id":"user1"}}}`), EventTS: 2},
+			{Event: "meeting.ended", Payload: json.RawMessage(`{"object":{"id":"100"}}`), EventTS: 3},
+		}
+		for _, event := range events {
+			require.NoError(t, repo.AppendEvent(ctx, event))
+		}
+
+		var replayed []int64
+		require.NoError(t, repo.ReplayEvents(ctx, func(event *models.WebhookEvent) error {
+			replayed = append(replayed, event.EventTS)
+			assert.Equal(t, "100", event.MeetingID())
+			return nil
+		}))
+		assert.Equal(t, []int64{1, 2, 3}, replayed)
+
+		// Replay stops at the first error, and the journal may be used while replaying
+		stop := errors.New("stop")
+		count := 0
+		err := repo.ReplayEvents(ctx, func(event *models.WebhookEvent) error {
+			count++
+			if err := repo.SaveMeeting(ctx, &models.Meeting{ID: event.MeetingID(), Status: models.MeetingStatusStarted}); err != nil {
+				return err
+			}
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, count)
+
+		// Clearing meetings keeps the journal
+		require.NoError(t, repo.AddParticipantToMeeting(ctx, "100", "user1"))
+		require.NoError(t, repo.ClearMeetings(ctx))
+		meetings, err := repo.ListAllMeetings(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, meetings)
+		_, err = repo.CountParticipantsInMeeting(ctx, "100")
+		assert.ErrorIs(t, err, errNotFound)
+
+		replayed = nil
+		require.NoError(t, repo.ReplayEvents(ctx, func(event *models.WebhookEvent) error {
+			replayed = append(replayed, event.EventTS)
+			return nil
+		}))
+		assert.Len(t, replayed, 3)
+	})
+}
+
+// assertCount asserts that a participant count of a meeting is as expected
+func assertCount(t *testing.T, expected int, count func(ctx context.Context, meetingID string) (int, error), meetingID string) {
+	t.Helper()
+
+	actual, err := count(context.Background(), meetingID)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
@@ -0,0 +1,7 @@
+package schema
+
+import "errors"
+
+// ErrNotFound is returned by every repository backend when a requested entity is not found,
+// so callers can tell a missing entity from a storage failure without knowing the backend
+var ErrNotFound = errors.New("entity not found")
@@ -0,0 +1,120 @@
+// Package schema defines the persisted form of meeting state shared by the repository backends
+package schema
+
+import (
+	"time"
+
+	"github.com/navikt/zrooms/internal/models"
+)
+
+// MeetingRecordVersion is the version of the MeetingRecord layout written by this build.
+// Records stored before the layout was versioned have version 0.
+const MeetingRecordVersion = 1
+
+// MeetingRecord is the meeting state kept by every repository backend, so switching backends
+// does not change what is shown. Participants and live indicators are stored separately by each backend.
+// Field names are the JSON keys, matching records written before the schema was shared.
+type MeetingRecord struct {
+	Version       int
+	ID            string // Meeting ID
+	AccountID     string // Zoom account the meeting belongs to
+	Topic         string // Meeting Topic
+	Type          models.MeetingType
+	Status        models.MeetingStatus
+	StartTime     time.Time
+	EndTime       time.Time
+	Duration      int                // Planned duration in minutes
+	Host          models.Participant // Only the host ID is known from Zoom events
+	OperatorEmail string             // Email of the user who created/updated the meeting
+	LastEventTS   int64              // Zoom event_ts (ms) of the last lifecycle event applied
+}
+
+// NewMeetingRecord creates a record for a meeting that is not stored yet
+func NewMeetingRecord(meeting *models.Meeting) *MeetingRecord {
+	return &MeetingRecord{
+		Version:       MeetingRecordVersion,
+		ID:            meeting.ID,
+		AccountID:     meeting.AccountID,
+		Topic:         meeting.Topic,
+		Type:          meeting.Type,
+		Status:        meeting.Status,
+		StartTime:     meeting.StartTime,
+		EndTime:       meeting.EndTime,
+		Duration:      meeting.Duration,
+		Host:          meeting.Host,
+		OperatorEmail: meeting.OperatorEmail,
+		LastEventTS:   meeting.LastEventTS,
+	}
+}
+
+// Merge applies an update of the meeting to the stored record. Events only carry some of the
+// meeting's fields, so empty fields in the update keep the stored value.
+func (r *MeetingRecord) Merge(meeting *models.Meeting) {
+	r.Version = MeetingRecordVersion
+	r.Status = meeting.Status
+
+	// Only update topic if it's provided and not empty
+	if meeting.Topic != "" {
+		r.Topic = meeting.Topic
+	}
+
+	// Update the account if provided
+	if meeting.AccountID != "" {
+		r.AccountID = meeting.AccountID
+	}
+
+	// Update the meeting type if provided
+	if meeting.Type != "" {
+		r.Type = meeting.Type
+	}
+
+	// Update operator email if provided
+	if meeting.OperatorEmail != "" {
+		r.OperatorEmail = meeting.OperatorEmail
+	}
+
+	// Update the host if provided
+	if meeting.Host.ID != "" || meeting.Host.Email != "" || meeting.Host.Name != "" {
+		r.Host = meeting.Host
+	}
+
+	// Update planned duration if provided
+	if meeting.Duration != 0 {
+		r.Duration = meeting.Duration
+	}
+
+	// Update start time when provided, except for updates which only carry the planned start
+	if !meeting.StartTime.IsZero() && meeting.Status != models.MeetingStatusUpdated {
+		r.StartTime = meeting.StartTime
+	}
+
+	// Set end time if the meeting has ended, and clear it when a recurring meeting starts again
+	if meeting.Status == models.MeetingStatusEnded {
+		r.EndTime = meeting.EndTime
+	} else if meeting.Status == models.MeetingStatusStarted {
+		r.EndTime = time.Time{}
+	}
+
+	// Never move the last applied event timestamp backwards
+	if meeting.LastEventTS > r.LastEventTS {
+		r.LastEventTS = meeting.LastEventTS
+	}
+}
+
+// Meeting converts the record back to a meeting model, without participants or live indicators
+func (r *MeetingRecord) Meeting() *models.Meeting {
+	return &models.Meeting{
+		ID:            r.ID,
+		AccountID:     r.AccountID,
+		Topic:         r.Topic,
+		Type:          r.Type,
+		Status:        r.Status,
+		StartTime:     r.StartTime,
+		EndTime:       r.EndTime,
+		Duration:      r.Duration,
+		Host:          r.Host,
+		OperatorEmail: r.OperatorEmail,
+		LastEventTS:   r.LastEventTS,
+		Participants:  []models.Participant{}, // Empty slice, we don't store participant details
+	}
+}
@@ -0,0 +1,103 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/navikt/zrooms/internal/models"
+	"github.com/navikt/zrooms/internal/repository/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeetingRecord(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	created := &models.Meeting{
+		ID:            "123",
+		AccountID:     "acc",
+		Topic:         "Standup",
+		Type:          models.MeetingTypeMeeting,
+		Status:        models.MeetingStatusCreated,
+		StartTime:     start,
+		Duration:      30,
+		Host:          models.Participant{ID: "host1"},
+		OperatorEmail: "operator@example.com",
+		LastEventTS:   100,
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		record := schema.NewMeetingRecord(created)
+		assert.Equal(t, schema.MeetingRecordVersion, record.Version)
+
+		data, err := json.Marshal(record)
+		require.NoError(t, err)
+		var decoded schema.MeetingRecord
+		require.NoError(t, json.Unmarshal(data, &decoded))
+
+		meeting := decoded.Meeting()
+		assert.Equal(t, "Standup", meeting.Topic)
+		assert.Equal(t, 30, meeting.Duration)
+		assert.Equal(t, "host1", meeting.Host.ID)
+		assert.Equal(t, "operator@example.com", meeting.OperatorEmail)
+		assert.True(t, start.Equal(meeting.StartTime))
+		assert.NotNil(t, meeting.Participants)
+	})
+
+	t.Run("MergeKeepsFieldsMissingFromUpdate", func(t *testing.T) {
+		record := schema.NewMeetingRecord(created)
+		ended := time.Date(2026, 3, 2, 9, 40, 0, 0, time.UTC)
+		record.Merge(&models.Meeting{ID: "123", Status: models.MeetingStatusEnded, EndTime: ended, LastEventTS: 90})
+
+		assert.Equal(t, models.MeetingStatusEnded, record.Status)
+		assert.Equal(t, "Standup", record.Topic)
+		assert.Equal(t, "acc", record.AccountID)
+		assert.Equal(t, 30, record.Duration)
+		assert.Equal(t, "host1", record.Host.ID)
+		assert.Equal(t, "operator@example.com", record.OperatorEmail)
+		assert.True(t, start.Equal(record.StartTime))
+		assert.True(t, ended.Equal(record.EndTime))
+		assert.Equal(t, int64(100), record.LastEventTS, "Last event timestamp should not move backwards")
+	})
+
+	t.Run("RestartClearsEndTime", func(t *testing.T) {
+		roundTrip := func(record *schema.MeetingRecord) *schema.MeetingRecord {
+			data, err := json.Marshal(record)
+			require.NoError(t, err)
+			var decoded schema.MeetingRecord
+			require.NoError(t, json.Unmarshal(data, &decoded))
+			return &decoded
+		}
+
+		record := schema.NewMeetingRecord(created)
+		record.Merge(&models.Meeting{ID: "123", Status: models.MeetingStatusEnded, EndTime: start.Add(40 * time.Minute), LastEventTS: 200})
+		record = roundTrip(record)
+		require.False(t, record.EndTime.IsZero())
+
+		// A recurring meeting starting again is running, so it has no end time
+		restarted := start.Add(24 * time.Hour)
+		record.Merge(&models.Meeting{ID: "123", Status: models.MeetingStatusStarted, StartTime: restarted, LastEventTS: 300})
+		meeting := roundTrip(record).Meeting()
+		assert.Equal(t, models.MeetingStatusStarted, meeting.Status)
+		assert.True(t, restarted.Equal(meeting.StartTime))
+		assert.True(t, meeting.EndTime.IsZero())
+	})
+
+	t.Run("MergeIgnoresPlannedStartOfUpdate", func(t *testing.T) {
+		record := schema.NewMeetingRecord(created)
+		record.Merge(&models.Meeting{ID: "123", Status: models.MeetingStatusUpdated, StartTime: start.Add(time.Hour), Topic: "Renamed", Duration: 45})
+
+		assert.Equal(t, "Renamed", record.Topic)
+		assert.Equal(t, 45, record.Duration)
+		assert.True(t, start.Equal(record.StartTime))
+	})
+
+	t.Run("ReadsUnversionedRecords", func(t *testing.T) {
+		var record schema.MeetingRecord
+		require.NoError(t, json.Unmarshal([]byte(`{"ID":"123","Topic":"Old","Status":2,"Duration":15,"ParticipantIDs":null}`), &record))
+		assert.Equal(t, 0, record.Version)
+		assert.Equal(t, "Old", record.Topic)
+		assert.Equal(t, models.MeetingStatusStarted, record.Status)
+		assert.Equal(t, 15, record.Duration)
+	})
+}
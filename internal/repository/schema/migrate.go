@@ -0,0 +1,119 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupportedVersion is returned for records written by a newer build with a layout this build does not know
+var ErrUnsupportedVersion = errors.New("unsupported meeting record version")
+
+// meetingMigration upgrades the decoded fields of a stored meeting record by one version
+type meetingMigration func(fields map[string]any) error
+
+// meetingMigrations holds, for each version of the MeetingRecord layout, the migration upgrading a record
+// to the next version. Changing the layout means bumping MeetingRecordVersion and registering a migration
+// from the previous version here, so records already stored stay readable.
+var meetingMigrations = map[int]meetingMigration{
+	0: migrateUnversioned,
+}
+
+// migrateUnversioned upgrades records written before the layout was versioned, which held an always
+// empty list of participant IDs; participants are stored apart from the meeting record
+func migrateUnversioned(fields map[string]any) error {
+	delete(fields, "ParticipantIDs")
+	return nil
+}
+
+// DecodeMeetingRecord reads a stored meeting record, upgrading it to the current version of the layout.
+// It reports whether the record was upgraded, so callers can write it back.
+func DecodeMeetingRecord(data []byte) (*MeetingRecord, bool, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // Keep event timestamps exact while the fields are migrated
+
+	var fields map[string]any
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, false, fmt.Errorf("failed to decode meeting record: %w", err)
+	}
+	if fields == nil {
+		return nil, false, errors.New("failed to decode meeting record: not an object")
+	}
+
+	version, err := recordVersion(fields)
+	if err != nil {
+		return nil, false, err
+	}
+	if version > MeetingRecordVersion {
+		return nil, false, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+
+	upgraded := version < MeetingRecordVersion
+	for ; version < MeetingRecordVersion; version++ {
+		migrate, ok := meetingMigrations[version]
+		if !ok {
+			return nil, false, fmt.Errorf("no migration from meeting record version %d", version)
+		}
+		if err := migrate(fields); err != nil {
+			return nil, false, fmt.Errorf("failed to migrate meeting record from version %d: %w", version, err)
+		}
+	}
+
+	if upgraded {
+		fields["Version"] = MeetingRecordVersion
+		if data, err = json.Marshal(fields); err != nil {
+			return nil, false, fmt.Errorf("failed to encode migrated meeting record: %w", err)
+		}
+	}
+
+	var record MeetingRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, false, fmt.Errorf("failed to decode meeting record: %w", err)
+	}
+	if record.ID == "" {
+		return nil, false, errors.New("meeting record has no ID")
+	}
+
+	return &record, upgraded, nil
+}
+
+// recordVersion returns the layout version of decoded record fields, 0 when the record is unversioned
+func recordVersion(fields map[string]any) (int, error) {
+	value, ok := fields["Version"]
+	if !ok {
+		return 0, nil
+	}
+
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("invalid meeting record version %v", value)
+	}
+	version, err := number.Int64()
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid meeting record version %v", value)
+	}
+	return int(version), nil
+}
+
+// UnreadableRecord is a stored meeting record that cannot be read by this build
+type UnreadableRecord struct {
+	Key   string
+	Error string
+}
+
+// CheckReport is the result of checking the stored meeting records
+type CheckReport struct {
+	Checked int
+	// Records written with an older layout, which are upgraded when read
+	Outdated int
+	// Outdated records written back with the current layout
+	Migrated   int
+	Unreadable []UnreadableRecord
+}
+
+// String summarises the report for logging
+func (r *CheckReport) String() string {
+	return fmt.Sprintf("%d meeting records checked, %d outdated, %d migrated, %d unreadable",
+		r.Checked, r.Outdated, r.Migrated, len(r.Unreadable))
+}
@@ -0,0 +1,47 @@
+package schema_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/navikt/zrooms/internal/models"
+	"github.com/navikt/zrooms/internal/repository/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeMeetingRecord(t *testing.T) {
+	t.Run("UpgradesUnversionedRecords", func(t *testing.T) {
+		record, upgraded, err := schema.DecodeMeetingRecord([]byte(`{"ID":"123","Topic":"Old","Status":2,"ParticipantIDs":null,"LastEventTS":1718000000123}`))
+		require.NoError(t, err)
+		assert.True(t, upgraded)
+		assert.Equal(t, schema.MeetingRecordVersion, record.Version)
+		assert.Equal(t, "Old", record.Topic)
+		assert.Equal(t, models.MeetingStatusStarted, record.Status)
+		assert.Equal(t, int64(1718000000123), record.LastEventTS)
+	})
+
+	t.Run("ReadsCurrentRecords", func(t *testing.T) {
+		data, err := json.Marshal(schema.NewMeetingRecord(&models.Meeting{ID: "123", Topic: "Current", OperatorEmail: "operator@example.com"}))
+		require.NoError(t, err)
+
+		record, upgraded, err := schema.DecodeMeetingRecord(data)
+		require.NoError(t, err)
+		assert.False(t, upgraded)
+		assert.Equal(t, "Current", record.Topic)
+		assert.Equal(t, "operator@example.com", record.OperatorEmail)
+	})
+
+	t.Run("RejectsNewerVersions", func(t *testing.T) {
+		_, _, err := schema.DecodeMeetingRecord([]byte(`{"Version":99,"ID":"123"}`))
+		assert.True(t, errors.Is(err, schema.ErrUnsupportedVersion))
+	})
+
+	t.Run("RejectsUnreadableRecords", func(t *testing.T) {
+		for _, data := range []string{`not json`, `null`, `[]`, `{"Version":"one","ID":"123"}`, `{"ID":"123","Status":"started"}`, `{"Topic":"No ID"}`} {
+			_, _, err := schema.DecodeMeetingRecord([]byte(data))
+			assert.Error(t, err, data)
+		}
+	})
+}
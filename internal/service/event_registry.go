@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/navikt/zrooms/internal/models"
+)
+
+// FieldKind is the JSON type a payload field must have
+type FieldKind string
+
+const (
+	// FieldString is a non-empty JSON string
+	FieldString FieldKind = "string"
+	// FieldObject is a JSON object
+	FieldObject FieldKind = "object"
+)
+
+// PayloadField is a field an event payload must contain
+type PayloadField struct {
+	// Dot separated path into the payload, e.g. "object.participant"
+	Path string
+	Kind FieldKind
+}
+
+// PayloadSchema describes the fields an event handler needs in the event payload
+type PayloadSchema struct {
+	Required []PayloadField
+}
+
+// Validate checks that the payload is a JSON object containing every required field
+func (s PayloadSchema) Validate(payload json.RawMessage) error {
+	var root map[string]any
+	if err := json.Unmarshal(payload, &root); err != nil {
+		return fmt.Errorf("payload is not a JSON object: %v", err)
+	}
+
+	for _, field := range s.Required {
+		value, ok := lookupField(root, field.Path)
+		if !ok {
+			return fmt.Errorf("payload is missing %s", field.Path)
+		}
+
+		switch field.Kind {
+		case FieldString:
+			if str, isString := value.(string); !isString || str == "" {
+				return fmt.Errorf("payload field %s must be a non-empty string", field.Path)
+			}
+		case FieldObject:
+			if _, isObject := value.(map[string]any); !isObject {
+				return fmt.Errorf("payload field %s must be an object", field.Path)
+			}
+		}
+	}
+	return nil
+}
+
+// lookupField follows a dot separated path through nested JSON objects
+func lookupField(root map[string]any, path string) (any, bool) {
+	var value any = root
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok || value == nil {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// EventHandler applies webhook events of the types it is registered for
+type EventHandler struct {
+	// Schema the event payload is validated against before Apply is called
+	Schema PayloadSchema
+	Apply  func(ctx context.Context, event *models.WebhookEvent) (ApplyResult, error)
+}
+
+// UnknownEvent counts received webhook events of a type no handler is registered for
+type UnknownEvent struct {
+	Event     string
+	Count     int64
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// EventRegistry maps webhook event names to the handlers applying them
+type EventRegistry struct {
+	mu       sync.RWMutex
+	handlers map[string]EventHandler
+	unknown  map[string]*UnknownEvent
+}
+
+// NewEventRegistry creates an empty event registry
+func NewEventRegistry() *EventRegistry {
+	return &EventRegistry{
+		handlers: make(map[string]EventHandler),
+		unknown:  make(map[string]*UnknownEvent),
+	}
+}
+
+// Register makes the handler apply events with the given names, replacing any handler registered before
+func (r *EventRegistry) Register(handler EventHandler, events ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, event := range events {
+		if _, exists := r.handlers[event]; exists {
+			log.Printf("Replacing handler for webhook event %s", event)
+		}
+		r.handlers[event] = handler
+		// Events received before a handler was registered are no longer unknown
+		delete(r.unknown, event)
+	}
+}
+
+// Handler returns the handler registered for an event name
+func (r *EventRegistry) Handler(event string) (EventHandler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	handler, ok := r.handlers[event]
+	return handler, ok
+}
+
+// Events returns the names of all events a handler is registered for, sorted by name
+func (r *EventRegistry) Events() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	events := make([]string, 0, len(r.handlers))
+	for event := range r.handlers {
+		events = append(events, event)
+	}
+	sort.Strings(events)
+	return events
+}
+
+// RecordUnknown counts a received event no handler is registered for
+func (r *EventRegistry) RecordUnknown(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	unknown, exists := r.unknown[event]
+	if !exists {
+		unknown = &UnknownEvent{Event: event, FirstSeen: now}
+		r.unknown[event] = unknown
+	}
+	unknown.Count++
+	unknown.LastSeen = now
+}
+
+// UnknownEvents returns the counted unknown events, most frequent first
+func (r *EventRegistry) UnknownEvents() []UnknownEvent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	events := make([]UnknownEvent, 0, len(r.unknown))
+	for _, unknown := range r.unknown {
+		events = append(events, *unknown)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Count != events[j].Count {
+			return events[i].Count > events[j].Count
+		}
+		return events[i].Event < events[j].Event
+	})
+	return events
+}
@@ -0,0 +1,105 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/navikt/zrooms/internal/models"
+	"github.com/navikt/zrooms/internal/repository/memory"
+	"github.com/navikt/zrooms/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadSchema_Validate(t *testing.T) {
+	schema := service.PayloadSchema{Required: []service.PayloadField{
+		{Path: "object", Kind: service.FieldObject},
+		{Path: "object.id", Kind: service.FieldString},
+		{Path: "object.participant", Kind: service.FieldObject},
+	}}
+
+	tests := []struct {
+		name    string
+		payload string
+		wantErr string
+	}{
+		{name: "Valid", payload: `{"object": {"id": "123", "participant": {"id": "part1"}}}`},
+		{name: "NotAnObject", payload: `["object"]`, wantErr: "payload is not a JSON object"},
+		{name: "MissingObject", payload: `{"account_id": "abc"}`, wantErr: "payload is missing object"},
+		{name: "EmptyID", payload: `{"object": {"id": "", "participant": {}}}`, wantErr: "payload field object.id must be a non-empty string"},
+		{name: "NumericID", payload: `{"object": {"id": 123, "participant": {}}}`, wantErr: "payload field object.id must be a non-empty string"},
+		{name: "NullParticipant", payload: `{"object": {"id": "123", "participant": null}}`, wantErr: "payload is missing object.participant"},
+		{name: "ParticipantNotObject", payload: `{"object": {"id": "123", "participant": "part1"}}`, wantErr: "payload field object.participant must be an object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.Validate(json.RawMessage(tt.payload))
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMeetingService_EventHandlerRegistry(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	ctx := context.Background()
+
+	t.Run("BuiltInHandlersRegistered", func(t *testing.T) {
+		handled := meetingService.HandledEvents()
+		assert.Contains(t, handled, "meeting.started")
+		assert.Contains(t, handled, "webinar.participant_left")
+		assert.Contains(t, handled, "zoomroom.alert")
+		assert.IsIncreasing(t, handled)
+	})
+
+	t.Run("PayloadValidatedBeforeApplying", func(t *testing.T) {
+		_, err := meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.participant_joined", "payload": {"object": {"id": "111"}}}`))
+		assert.ErrorIs(t, err, service.ErrInvalidEvent)
+		assert.ErrorContains(t, err, "object.participant")
+	})
+
+	t.Run("UnknownEventsCounted", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			result, err := meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.chat_message_sent", "payload": {"object": {"id": "111"}}}`))
+			require.NoError(t, err)
+			assert.Equal(t, service.EventUnsupported, result.Outcome)
+		}
+		_, err := meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.registration_created", "payload": {"object": {"id": "111"}}}`))
+		require.NoError(t, err)
+
+		unknown := meetingService.UnknownEvents()
+		require.Len(t, unknown, 2)
+		assert.Equal(t, "meeting.chat_message_sent", unknown[0].Event, "Most frequent unknown events come first")
+		assert.Equal(t, int64(2), unknown[0].Count)
+		assert.False(t, unknown[0].FirstSeen.After(unknown[0].LastSeen))
+		assert.Equal(t, "meeting.registration_created", unknown[1].Event)
+		assert.Equal(t, int64(1), unknown[1].Count)
+	})
+
+	t.Run("CustomHandler", func(t *testing.T) {
+		var applied []string
+		meetingService.RegisterEventHandler(service.EventHandler{
+			Schema: service.PayloadSchema{Required: []service.PayloadField{{Path: "object.id", Kind: service.FieldString}}},
+			Apply: func(ctx context.Context, event *models.WebhookEvent) (service.ApplyResult, error) {
+				applied = append(applied, event.MeetingID())
+				return service.ApplyResult{Outcome: service.EventApplied, MeetingID: event.MeetingID()}, nil
+			},
+		}, "meeting.chat_message_sent")
+
+		result, err := meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.chat_message_sent", "payload": {"object": {"id": "111"}}}`))
+		require.NoError(t, err)
+		assert.Equal(t, service.EventApplied, result.Outcome)
+		assert.Equal(t, []string{"111"}, applied)
+
+		// An event type that has a handler now is no longer listed as unknown
+		unknown := meetingService.UnknownEvents()
+		require.Len(t, unknown, 1)
+		assert.Equal(t, "meeting.registration_created", unknown[0].Event)
+	})
+}
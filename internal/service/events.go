@@ -0,0 +1,687 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/navikt/zrooms/internal/models"
+	"github.com/navikt/zrooms/internal/repository"
+)
+
+// ErrInvalidEvent is returned for events that can never be applied, so retrying them is pointless
+var ErrInvalidEvent = errors.New("invalid webhook event")
+
+// EventOutcome describes what applying a webhook event did to the stored state
+type EventOutcome string
+
+const (
+	// EventApplied means the event changed the stored state
+	EventApplied EventOutcome = "applied"
+	// EventIgnored means the event was valid but there was nothing to change
+	EventIgnored EventOutcome = "ignored"
+	// EventOutOfOrder means the event was older than the state it refers to and was not applied
+	EventOutOfOrder EventOutcome = "out_of_order"
+	// EventUnsupported means events of this type are not handled
+	EventUnsupported EventOutcome = "unsupported"
+)
+
+// ApplyResult is the result of applying a webhook event
+type ApplyResult struct {
+	Outcome EventOutcome
+	// ID the meeting the event refers to is stored under, namespaced by account. Empty for room events.
+	MeetingID string
+	// ID of the Zoom Room the event refers to. Empty for meeting events.
+	RoomID string
+}
+
+// ApplyEvent applies a single webhook event to the stored state and notifies registered callbacks
+// about the changes. The event is applied by the handler registered for its type, after validating
+// the payload against the handler's schema. Events without a handler are counted as unknown.
+// Errors wrapping ErrInvalidEvent mean the event can never be applied and should not be retried.
+func (s *MeetingService) ApplyEvent(ctx context.Context, event *models.WebhookEvent) (ApplyResult, error) {
+	handler, ok := s.events.Handler(event.Event)
+	if !ok {
+		// Count unknown event types but do not treat them as a failure
+		log.Printf("Unsupported webhook event type: %s", event.Event)
+		s.events.RecordUnknown(event.Event)
+		return ApplyResult{Outcome: EventUnsupported}, nil
+	}
+
+	if err := handler.Schema.Validate(event.Payload); err != nil {
+		return ApplyResult{}, fmt.Errorf("%w: %s event: %v", ErrInvalidEvent, event.Event, err)
+	}
+
+	return handler.Apply(ctx, event)
+}
+
+// RegisterEventHandler makes the handler apply webhook events with the given names,
+// replacing the built-in handler for any of them
+func (s *MeetingService) RegisterEventHandler(handler EventHandler, events ...string) {
+	s.events.Register(handler, events...)
+}
+
+// HandledEvents returns the names of all webhook events a handler is registered for
+func (s *MeetingService) HandledEvents() []string {
+	return s.events.Events()
+}
+
+// UnknownEvents returns the received webhook events no handler is registered for, most frequent first
+func (s *MeetingService) UnknownEvents() []UnknownEvent {
+	return s.events.UnknownEvents()
+}
+
+// Payload fields required by the built-in event handlers
+var (
+	objectField      = PayloadField{Path: "object", Kind: FieldObject}
+	objectIDField    = PayloadField{Path: "object.id", Kind: FieldString}
+	participantField = PayloadField{Path: "object.participant", Kind: FieldObject}
+)
+
+// registerEventHandlers registers the built-in handlers for the Zoom events zrooms supports
+func (s *MeetingService) registerEventHandlers() {
+	meetingSchema := PayloadSchema{Required: []PayloadField{objectField, objectIDField}}
+	participantSchema := PayloadSchema{Required: []PayloadField{objectField, objectIDField, participantField}}
+	roomSchema := PayloadSchema{Required: []PayloadField{objectField, objectIDField}}
+
+	s.events.Register(EventHandler{Schema: meetingSchema, Apply: s.applyMeetingCreated}, "meeting.created")
+	s.events.Register(EventHandler{Schema: meetingSchema, Apply: s.applyMeetingDeleted}, "meeting.deleted")
+	// Webinars carry the same payload as meetings and are stored with their own type
+	s.events.Register(EventHandler{Schema: meetingSchema, Apply: s.applyMeetingStarted}, "meeting.started", "webinar.started")
+	s.events.Register(EventHandler{Schema: meetingSchema, Apply: s.applyMeetingEnded}, "meeting.ended", "webinar.ended")
+	s.events.Register(EventHandler{Schema: meetingSchema, Apply: s.applyMeetingUpdated}, "meeting.updated")
+	s.events.Register(EventHandler{Schema: participantSchema, Apply: s.applyParticipantJoined},
+		"meeting.participant_joined", "webinar.participant_joined")
+	s.events.Register(EventHandler{Schema: participantSchema, Apply: s.applyParticipantLeft},
+		"meeting.participant_left", "webinar.participant_left")
+	s.events.Register(EventHandler{Schema: meetingSchema, Apply: s.applyRecordingEvent},
+		"recording.started", "recording.resumed", "recording.paused", "recording.stopped")
+	s.events.Register(EventHandler{Schema: meetingSchema, Apply: s.applySharingEvent},
+		"meeting.sharing_started", "meeting.sharing_ended", "webinar.sharing_started", "webinar.sharing_ended")
+	s.events.Register(EventHandler{Schema: participantSchema, Apply: s.applyParticipantJoinedBreakoutRoom},
+		"meeting.participant_joined_breakout_room")
+	s.events.Register(EventHandler{Schema: participantSchema, Apply: s.applyParticipantLeftBreakoutRoom},
+		"meeting.participant_left_breakout_room")
+	s.events.Register(EventHandler{Schema: participantSchema, Apply: s.applyParticipantWaiting},
+		"meeting.participant_put_in_waiting_room", "meeting.participant_joined_waiting_room", "meeting.participant_jbh_waiting")
+	s.events.Register(EventHandler{Schema: participantSchema, Apply: s.applyParticipantStoppedWaiting},
+		"meeting.participant_admitted", "meeting.participant_left_waiting_room", "meeting.participant_jbh_joined")
+	s.events.Register(EventHandler{Schema: roomSchema, Apply: s.applyRoomEvent},
+		"zoomroom.checked_in", "zoomroom.checked_out", "zoomroom.started_meeting", "zoomroom.ended_meeting")
+	s.events.Register(EventHandler{Schema: roomSchema, Apply: s.applyRoomAlert}, "zoomroom.alert", "zoomroom.delayed_alert")
+}
+
+// meetingKey returns the ID the meeting an event refers to is stored under.
+// Meetings of additional accounts are namespaced by account; the default account's are not.
+func (s *MeetingService) meetingKey(event *models.WebhookEvent) string {
+	return s.meetingKeyFor(event.AccountID(), event.MeetingID())
+}
+
+// meetingKeyFor returns the ID a meeting of the given Zoom account is stored under
+func (s *MeetingService) meetingKeyFor(accountID, zoomID string) string {
+	if zoomID == "" {
+		return ""
+	}
+	return models.MeetingKey(s.accounts.ByID(accountID).ID, zoomID)
+}
+
+// scopeMeeting namespaces a meeting built from an event by its account, see meetingKey
+func (s *MeetingService) scopeMeeting(meeting *models.Meeting) {
+	meeting.ID = s.meetingKeyFor(meeting.AccountID, meeting.ID)
+}
+
+// staleEventFor looks up the stored meeting and reports whether the event is older than the last
+// lifecycle event applied to it. The stored meeting is returned when it exists, so callers can merge.
+func (s *MeetingService) staleEventFor(ctx context.Context, meetingID string, event *models.WebhookEvent) (*models.Meeting, bool) {
+	existing, err := s.repo.GetMeeting(ctx, meetingID)
+	if err != nil {
+		return nil, false
+	}
+
+	if existing.IsStaleEvent(event.EventTS) {
+		log.Printf("Out-of-order %s event for meeting %s: event_ts=%d, last applied=%d",
+			event.Event, meetingID, event.EventTS, existing.LastEventTS)
+		return existing, true
+	}
+
+	return existing, false
+}
+
+// isForEndedMeeting reports whether an event happened before the meeting ended but was delivered after it,
+// so that it must not bring back state that ending the meeting cleared
+func (s *MeetingService) isForEndedMeeting(ctx context.Context, event *models.WebhookEvent, meetingID string) bool {
+	existing, err := s.repo.GetMeeting(ctx, meetingID)
+	if err != nil || existing.Status != models.MeetingStatusEnded || !existing.IsStaleEvent(event.EventTS) {
+		return false
+	}
+
+	log.Printf("Out-of-order %s event for ended meeting %s: event_ts=%d, ended=%d",
+		event.Event, meetingID, event.EventTS, existing.LastEventTS)
+	return true
+}
+
+// isStaleParticipantEvent records the event_ts of a join or leave for the participant and reports whether
+// it is older than the last join or leave applied for them. Events without event_ts are never stale.
+func (s *MeetingService) isStaleParticipantEvent(ctx context.Context, event *models.WebhookEvent, meetingID, participantID string) (bool, error) {
+	if event.EventTS == 0 {
+		return false, nil
+	}
+
+	recorded, err := s.repo.RecordParticipantEvent(ctx, meetingID, participantID, event.EventTS)
+	if err != nil {
+		return false, fmt.Errorf("error recording participant event: %w", err)
+	}
+	if !recorded {
+		log.Printf("Out-of-order %s event for participant %s of meeting %s: event_ts=%d",
+			event.Event, participantID, meetingID, event.EventTS)
+	}
+	return !recorded, nil
+}
+
+// applyMeetingCreated applies a meeting.created event
+func (s *MeetingService) applyMeetingCreated(ctx context.Context, event *models.WebhookEvent) (ApplyResult, error) {
+	meeting := event.ProcessMeetingCreated()
+	if meeting == nil {
+		return ApplyResult{}, fmt.Errorf("%w: failed to process meeting.created event", ErrInvalidEvent)
+	}
+	s.scopeMeeting(meeting)
+	result := ApplyResult{MeetingID: meeting.ID}
+
+	// Ignore a late creation of a meeting that has already progressed further
+	if _, stale := s.staleEventFor(ctx, meeting.ID, event); stale {
+		result.Outcome = EventOutOfOrder
+		return result, nil
+	}
+
+	log.Printf("Meeting created: ID=%s, StartTime=%s, Duration=%d", meeting.ID, meeting.StartTime, meeting.Duration)
+
+	// Save the scheduled meeting with its planned start time and duration
+	if err := s.createMeeting(ctx, meeting); err != nil {
+		return result, err
+	}
+	result.Outcome = EventApplied
+	return result, nil
+}
+
+// applyMeetingDeleted applies a meeting.deleted event
+func (s *MeetingService) applyMeetingDeleted(ctx context.Context, event *models.WebhookEvent) (ApplyResult, error) {
+	var payload models.StandardEventPayload
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return ApplyResult{}, fmt.Errorf("%w: error parsing payload for meeting deleted event: %v", ErrInvalidEvent, err)
+	}
+
+	meetingID := s.meetingKey(event)
+	if meetingID == "" {
+		return ApplyResult{}, fmt.Errorf("%w: meeting.deleted event is missing meeting ID", ErrInvalidEvent)
+	}
+	result := ApplyResult{MeetingID: meetingID}
+
+	// Ignore a deletion that is older than the last lifecycle event applied to the meeting
+	if _, stale := s.staleEventFor(ctx, meetingID, event); stale {
+		result.Outcome = EventOutOfOrder
+		return result, nil
+	}
+
+	log.Printf("Meeting deleted: ID=%s", meetingID)
+	if err := s.repo.DeleteMeeting(ctx, meetingID); err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			return result, fmt.Errorf("error deleting meeting: %w", err)
+		}
+		// The meeting may never have been stored, e.g. if it was created before zrooms was installed
+		log.Printf("Deleted meeting %s is not stored", meetingID)
+		result.Outcome = EventIgnored
+		return result, nil
+	}
+
+	// Connected dashboards drop the meeting
+	s.NotifyMeetingDeleted(meetingID)
+	result.Outcome = EventApplied
+	return result, nil
+}
+
+// applyMeetingStarted applies a meeting.started or webinar.started event
+func (s *MeetingService) applyMeetingStarted(ctx context.Context, event *models.WebhookEvent) (ApplyResult, error) {
+	meeting := event.ProcessMeetingStarted()
+	if meeting == nil {
+		return ApplyResult{}, fmt.Errorf("%w: failed to process meeting.started event", ErrInvalidEvent)
+	}
+	s.scopeMeeting(meeting)
+	result := ApplyResult{MeetingID: meeting.ID}
+
+	// A late start must not make a meeting that has since ended live again.
+	// Only fill in the start time if the stored meeting is missing it.
+	if existing, stale := s.staleEventFor(ctx, meeting.ID, event); stale {
+		if existing.StartTime.IsZero() {
+			existing.StartTime = meeting.StartTime
+			if err := s.repo.SaveMeeting(ctx, existing); err != nil {
+				return result, fmt.Errorf("error merging start time into meeting: %w", err)
+			}
+		}
+		result.Outcome = EventOutOfOrder
+		return result, nil
+	}
+
+	log.Printf("Meeting started: ID=%s", meeting.ID)
+	if err := s.startMeeting(ctx, meeting); err != nil {
+		return result, err
+	}
+	result.Outcome = EventApplied
+	return result, nil
+}
+
+// applyMeetingUpdated applies a meeting.updated event
+func (s *MeetingService) applyMeetingUpdated(ctx context.Context, event *models.WebhookEvent) (ApplyResult, error) {
+	meeting := event.ProcessMeetingUpdated()
+	if meeting == nil {
+		return ApplyResult{}, fmt.Errorf("%w: failed to process meeting.updated event", ErrInvalidEvent)
+	}
+	s.scopeMeeting(meeting)
+	result := ApplyResult{MeetingID: meeting.ID}
+
+	// Ignore updates older than the last lifecycle event applied to the meeting
+	existing, stale := s.staleEventFor(ctx, meeting.ID, event)
+	if stale {
+		result.Outcome = EventOutOfOrder
+		return result, nil
+	}
+
+	// An update to a running or finished meeting must not turn it back into a scheduled one
+	if existing != nil && (existing.Status == models.MeetingStatusStarted || existing.Status == models.MeetingStatusEnded) {
+		meeting.Status = existing.Status
+	}
+
+	log.Printf("Meeting updated: ID=%s", meeting.ID)
+	if err := s.updateMeeting(ctx, meeting); err != nil {
+		return result, err
+	}
+	result.Outcome = EventApplied
+	return result, nil
+}
+
+// applyMeetingEnded applies a meeting.ended or webinar.ended event
+func (s *MeetingService) applyMeetingEnded(ctx context.Context, event *models.WebhookEvent) (ApplyResult, error) {
+	meeting := event.ProcessMeetingEnded()
+	if meeting == nil {
+		return ApplyResult{}, fmt.Errorf("%w: failed to process meeting.ended event", ErrInvalidEvent)
+	}
+	s.scopeMeeting(meeting)
+	result := ApplyResult{MeetingID: meeting.ID}
+
+	// Parse the standard event payload to access object properties
+	var payload models.StandardEventPayload
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return result, fmt.Errorf("%w: error parsing payload for meeting ended event: %v", ErrInvalidEvent, err)
+	}
+
+	// Get existing meeting to preserve important details, ignoring ends older than the last
+	// lifecycle event (e.g. a delayed end of a previous occurrence of a recurring meeting)
+	existingMeeting, stale := s.staleEventFor(ctx, meeting.ID, event)
+	if stale {
+		result.Outcome = EventOutOfOrder
+		return result, nil
+	}
+	if existingMeeting != nil {
+		// Keep topic from existing meeting if it's not set in the new one
+		if meeting.Topic == "" {
+			if existingMeeting.Topic != "" {
+				meeting.Topic = existingMeeting.Topic
+			} else if payload.Object.Topic != "" {
+				meeting.Topic = payload.Object.Topic
+			}
+		}
+		// Preserve operator email from existing meeting if not set in the ended event
+		if meeting.OperatorEmail == "" && existingMeeting.OperatorEmail != "" {
+			meeting.OperatorEmail = existingMeeting.OperatorEmail
+		}
+	}
+
+	log.Printf("Meeting ended: ID=%s", meeting.ID)
+	if err := s.endMeeting(ctx, meeting); err != nil {
+		return result, err
+	}
+	result.Outcome = EventApplied
+	return result, nil
+}
+
+// applyParticipantJoined applies a participant_joined event
+func (s *MeetingService) applyParticipantJoined(ctx context.Context, event *models.WebhookEvent) (ApplyResult, error) {
+	participant := event.ProcessParticipantJoined()
+	if participant == nil {
+		return ApplyResult{}, fmt.Errorf("%w: failed to process participant_joined event", ErrInvalidEvent)
+	}
+
+	meetingID := s.meetingKey(event)
+	participantID := participant.ID
+	result := ApplyResult{MeetingID: meetingID}
+
+	// Ignore joins that happened before the meeting ended but were delivered after it.
+	// Joins older than the start are still applied, as Zoom may report the host joining first.
+	if s.isForEndedMeeting(ctx, event, meetingID) {
+		result.Outcome = EventOutOfOrder
+		return result, nil
+	}
+
+	// Ignore a join delivered after a later leave of the same participant, which would leave them present for good
+	stale, err := s.isStaleParticipantEvent(ctx, event, meetingID, participantID)
+	if err != nil {
+		return result, err
+	}
+	if stale {
+		result.Outcome = EventOutOfOrder
+		return result, nil
+	}
+
+	// Only store the participant ID to avoid storing PII
+	log.Printf("Participant joined: MeetingID=%s, ParticipantID=%s", meetingID, participantID)
+	if err := s.repo.AddParticipantToMeeting(ctx, meetingID, participantID); err != nil {
+		return result, fmt.Errorf("error adding participant: %w", err)
+	}
+
+	// A participant in the meeting is no longer waiting, even if the admitted event is missed
+	if err := s.repo.RemoveParticipantFromWaitingRoom(ctx, meetingID, participantID); err != nil {
+		log.Printf("Error removing joined participant from waiting room: %v", err)
+	}
+
+	s.NotifyParticipantJoined(meetingID, participantID)
+	result.Outcome = EventApplied
+	return result, nil
+}
+
+// applyParticipantLeft applies a participant_left event
+func (s *MeetingService) applyParticipantLeft(ctx context.Context, event *models.WebhookEvent) (ApplyResult, error) {
+	participant := event.ProcessParticipantLeft()
+	if participant == nil {
+		return ApplyResult{}, fmt.Errorf("%w: failed to process participant_left event", ErrInvalidEvent)
+	}
+
+	meetingID := s.meetingKey(event)
+	participantID := participant.ID
+	result := ApplyResult{MeetingID: meetingID}
+
+	// Moving from the main room into a breakout room is reported as leaving, but the participant is still in the meeting
+	if event.IsLeftForBreakoutRoom() {
+		log.Printf("Participant moved to breakout room: MeetingID=%s, ParticipantID=%s", meetingID, participantID)
+		result.Outcome = EventIgnored
+		return result, nil
+	}
+
+	// Ignore a leave delivered after a later join of the same participant, e.g. when they rejoined
+	stale, err := s.isStaleParticipantEvent(ctx, event, meetingID, participantID)
+	if err != nil {
+		return result, err
+	}
+	if stale {
+		result.Outcome = EventOutOfOrder
+		return result, nil
+	}
+
+	log.Printf("Participant left: MeetingID=%s, ParticipantID=%s", meetingID, participantID)
+	if err := s.repo.RemoveParticipantFromMeeting(ctx, meetingID, participantID); err != nil {
+		return result, fmt.Errorf("error removing participant: %w", err)
+	}
+
+	s.NotifyParticipantLeft(meetingID, participantID)
+	result.Outcome = EventApplied
+	return result, nil
+}
+
+// applyRecordingEvent applies recording.started, resumed, paused and stopped events
+func (s *MeetingService) applyRecordingEvent(ctx context.Context, event *models.WebhookEvent) (ApplyResult, error) {
+	meetingID := s.meetingKey(event)
+	if meetingID == "" {
+		return ApplyResult{}, fmt.Errorf("%w: %s event without meeting ID", ErrInvalidEvent, event.Event)
+	}
+	result := ApplyResult{MeetingID: meetingID}
+
+	recording := models.RecordingStatusRecording
+	switch event.Event {
+	case "recording.paused":
+		recording = models.RecordingStatusPaused
+	case "recording.stopped":
+		recording = models.RecordingStatusNone
+	}
+
+	if s.isForEndedMeeting(ctx, event, meetingID) {
+		result.Outcome = EventOutOfOrder
+		return result, nil
+	}
+
+	log.Printf("Recording %s: MeetingID=%s", strings.TrimPrefix(event.Event, "recording."), meetingID)
+	if err := s.repo.SetMeetingRecording(ctx, meetingID, recording); err != nil {
+		return result, fmt.Errorf("error setting recording indicator: %w", err)
+	}
+
+	s.NotifyMeetingIndicatorsChanged(meetingID)
+	result.Outcome = EventApplied
+	return result, nil
+}
+
+// applySharingEvent applies sharing_started and sharing_ended events for meetings and webinars
+func (s *MeetingService) applySharingEvent(ctx context.Context, event *models.WebhookEvent) (ApplyResult, error) {
+	meetingID := s.meetingKey(event)
+	if meetingID == "" {
+		return ApplyResult{}, fmt.Errorf("%w: %s event without meeting ID", ErrInvalidEvent, event.Event)
+	}
+	result := ApplyResult{MeetingID: meetingID}
+
+	sharing := strings.HasSuffix(event.Event, ".sharing_started")
+
+	if s.isForEndedMeeting(ctx, event, meetingID) {
+		result.Outcome = EventOutOfOrder
+		return result, nil
+	}
+
+	log.Printf("Screen sharing changed: MeetingID=%s, Sharing=%t", meetingID, sharing)
+	if err := s.repo.SetMeetingSharing(ctx, meetingID, sharing); err != nil {
+		return result, fmt.Errorf("error setting sharing indicator: %w", err)
+	}
+
+	s.NotifyMeetingIndicatorsChanged(meetingID)
+	result.Outcome = EventApplied
+	return result, nil
+}
+
+// applyParticipantJoinedBreakoutRoom applies a meeting.participant_joined_breakout_room event.
+// The participant stays counted in the meeting, and is also counted in the breakout room.
+func (s *MeetingService) applyParticipantJoinedBreakoutRoom(ctx context.Context, event *models.WebhookEvent) (ApplyResult, error) {
+	participant := event.ProcessParticipantJoined()
+	if participant == nil {
+		return ApplyResult{}, fmt.Errorf("%w: failed to process participant_joined_breakout_room event", ErrInvalidEvent)
+	}
+
+	meetingID := s.meetingKey(event)
+	breakoutRoomID := event.BreakoutRoomID()
+	if breakoutRoomID == "" {
+		return ApplyResult{}, fmt.Errorf("%w: participant_joined_breakout_room event without breakout room", ErrInvalidEvent)
+	}
+	result := ApplyResult{MeetingID: meetingID}
+
+	// Ignore joins that happened before the meeting ended but were delivered after it
+	if s.isForEndedMeeting(ctx, event, meetingID) {
+		result.Outcome = EventOutOfOrder
+		return result, nil
+	}
+
+	log.Printf("Participant joined breakout room: MeetingID=%s, BreakoutRoom=%s, ParticipantID=%s",
+		meetingID, breakoutRoomID, participant.ID)
+	// Adding is idempotent, and covers a missed join of the main room
+	if err := s.repo.AddParticipantToMeeting(ctx, meetingID, participant.ID); err != nil {
+		return result, fmt.Errorf("error adding participant: %w", err)
+	}
+	if err := s.repo.AddParticipantToBreakoutRoom(ctx, meetingID, breakoutRoomID, participant.ID); err != nil {
+		return result, fmt.Errorf("error adding breakout room participant: %w", err)
+	}
+
+	s.NotifyParticipantJoined(meetingID, participant.ID)
+	result.Outcome = EventApplied
+	return result, nil
+}
+
+// applyParticipantLeftBreakoutRoom applies a meeting.participant_left_breakout_room event.
+// The participant returns to the main room; leaving the meeting altogether is reported by participant_left.
+func (s *MeetingService) applyParticipantLeftBreakoutRoom(ctx context.Context, event *models.WebhookEvent) (ApplyResult, error) {
+	participant := event.ProcessParticipantLeft()
+	if participant == nil {
+		return ApplyResult{}, fmt.Errorf("%w: failed to process participant_left_breakout_room event", ErrInvalidEvent)
+	}
+
+	meetingID := s.meetingKey(event)
+	breakoutRoomID := event.BreakoutRoomID()
+	if breakoutRoomID == "" {
+		return ApplyResult{}, fmt.Errorf("%w: participant_left_breakout_room event without breakout room", ErrInvalidEvent)
+	}
+	result := ApplyResult{MeetingID: meetingID}
+
+	log.Printf("Participant left breakout room: MeetingID=%s, BreakoutRoom=%s, ParticipantID=%s",
+		meetingID, breakoutRoomID, participant.ID)
+	if err := s.repo.RemoveParticipantFromBreakoutRoom(ctx, meetingID, breakoutRoomID, participant.ID); err != nil {
+		return result, fmt.Errorf("error removing breakout room participant: %w", err)
+	}
+
+	s.NotifyParticipantLeft(meetingID, participant.ID)
+	result.Outcome = EventApplied
+	return result, nil
+}
+
+// applyParticipantWaiting applies events for a participant starting to wait to be admitted:
+// put in or joined the waiting room, or waiting for the host to start the meeting (jbh_waiting)
+func (s *MeetingService) applyParticipantWaiting(ctx context.Context, event *models.WebhookEvent) (ApplyResult, error) {
+	var payload models.StandardEventPayload
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return ApplyResult{}, fmt.Errorf("%w: error parsing payload for %s event: %v", ErrInvalidEvent, event.Event, err)
+	}
+	if payload.Object.Participant == nil {
+		return ApplyResult{}, fmt.Errorf("%w: %s event without participant", ErrInvalidEvent, event.Event)
+	}
+
+	meetingID := s.meetingKey(event)
+	participantID := payload.Object.Participant.ID
+	result := ApplyResult{MeetingID: meetingID}
+
+	existing, err := s.repo.GetMeeting(ctx, meetingID)
+	if err != nil {
+		// Participants can wait before the meeting has started, so make the meeting known as scheduled.
+		// LastEventTS is left unset, as only lifecycle events order the meeting's status: a start
+		// delivered after this event but stamped before it must still make the meeting live.
+		log.Printf("Participant waiting for unknown meeting %s, storing it as scheduled", meetingID)
+		if err := s.createMeeting(ctx, &models.Meeting{
+			ID:           meetingID,
+			AccountID:    payload.AccountID,
+			Topic:        payload.Object.Topic,
+			Type:         models.MeetingTypeMeeting,
+			Participants: []models.Participant{},
+		}); err != nil {
+			return result, err
+		}
+	} else if existing.Status == models.MeetingStatusEnded && existing.IsStaleEvent(event.EventTS) {
+		// Delivered after the meeting ended; nobody is waiting any more
+		log.Printf("Out-of-order %s event for ended meeting %s: event_ts=%d, ended=%d",
+			event.Event, meetingID, event.EventTS, existing.LastEventTS)
+		result.Outcome = EventOutOfOrder
+		return result, nil
+	}
+
+	log.Printf("Participant waiting: MeetingID=%s, ParticipantID=%s", meetingID, participantID)
+	if err := s.repo.AddParticipantToWaitingRoom(ctx, meetingID, participantID); err != nil {
+		return result, fmt.Errorf("error adding waiting participant: %w", err)
+	}
+
+	s.NotifyWaitingRoomChanged(meetingID)
+	result.Outcome = EventApplied
+	return result, nil
+}
+
+// applyParticipantStoppedWaiting applies events for a participant no longer waiting:
+// admitted, left the waiting room, or joined before the host
+func (s *MeetingService) applyParticipantStoppedWaiting(ctx context.Context, event *models.WebhookEvent) (ApplyResult, error) {
+	var payload models.StandardEventPayload
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return ApplyResult{}, fmt.Errorf("%w: error parsing payload for %s event: %v", ErrInvalidEvent, event.Event, err)
+	}
+	if payload.Object.Participant == nil {
+		return ApplyResult{}, fmt.Errorf("%w: %s event without participant", ErrInvalidEvent, event.Event)
+	}
+
+	meetingID := s.meetingKey(event)
+	participantID := payload.Object.Participant.ID
+	result := ApplyResult{MeetingID: meetingID}
+
+	log.Printf("Participant stopped waiting: MeetingID=%s, ParticipantID=%s", meetingID, participantID)
+	if err := s.repo.RemoveParticipantFromWaitingRoom(ctx, meetingID, participantID); err != nil {
+		return result, fmt.Errorf("error removing waiting participant: %w", err)
+	}
+
+	s.NotifyWaitingRoomChanged(meetingID)
+	result.Outcome = EventApplied
+	return result, nil
+}
+
+// applyRoomEvent applies zoomroom.* events that change the state of a physical room
+func (s *MeetingService) applyRoomEvent(ctx context.Context, event *models.WebhookEvent) (ApplyResult, error) {
+	roomID := event.MeetingID() // Room events carry the room ID as object ID
+	if roomID == "" {
+		return ApplyResult{}, fmt.Errorf("%w: %s event without room ID", ErrInvalidEvent, event.Event)
+	}
+	result := ApplyResult{RoomID: roomID}
+
+	// Room events build on the stored state, e.g. a meeting ending leaves a checked-in room occupied
+	previous, err := s.repo.GetRoom(ctx, roomID)
+	if err != nil {
+		previous = nil // First event for this room
+	} else if previous.IsStaleEvent(event.EventTS) {
+		log.Printf("Ignoring out-of-order %s event for room %s: event_ts=%d, last applied=%d",
+			event.Event, roomID, event.EventTS, previous.LastEventTS)
+		result.Outcome = EventOutOfOrder
+		return result, nil
+	}
+
+	room := event.ProcessRoomEvent(previous)
+	if room == nil {
+		return result, fmt.Errorf("%w: failed to process %s event", ErrInvalidEvent, event.Event)
+	}
+	// Link a newly started meeting as it is stored, namespaced by account
+	if room.MeetingID != "" && (previous == nil || room.MeetingID != previous.MeetingID) {
+		room.MeetingID = s.meetingKeyFor(event.AccountID(), room.MeetingID)
+	}
+	result.MeetingID = room.MeetingID
+
+	log.Printf("Room %s (%s) is now %s", room.ID, room.Name, room.Status)
+	if err := s.saveRoom(ctx, room); err != nil {
+		return result, err
+	}
+	result.Outcome = EventApplied
+	return result, nil
+}
+
+// applyRoomAlert applies zoomroom.alert and zoomroom.delayed_alert events about device health
+func (s *MeetingService) applyRoomAlert(ctx context.Context, event *models.WebhookEvent) (ApplyResult, error) {
+	alert := event.ProcessRoomAlert()
+	if alert == nil {
+		return ApplyResult{}, fmt.Errorf("%w: failed to process %s event", ErrInvalidEvent, event.Event)
+	}
+	result := ApplyResult{RoomID: alert.RoomID}
+
+	if previous, err := s.repo.GetDeviceAlert(ctx, alert.ID); err == nil {
+		if previous.IsStaleEvent(event.EventTS) {
+			log.Printf("Ignoring out-of-order %s event for room %s: event_ts=%d, last applied=%d",
+				event.Event, alert.RoomID, event.EventTS, previous.LastEventTS)
+			result.Outcome = EventOutOfOrder
+			return result, nil
+		}
+		alert.MergePrevious(previous)
+	}
+
+	if alert.IsActive() {
+		log.Printf("Device alert raised for room %s: %s %s", alert.RoomID, alert.Component, alert.Issue)
+	} else {
+		log.Printf("Device alert cleared for room %s: %s %s", alert.RoomID, alert.Component, alert.Issue)
+	}
+	if err := s.saveDeviceAlert(ctx, alert); err != nil {
+		return result, err
+	}
+	result.Outcome = EventApplied
+	return result, nil
+}
@@ -0,0 +1,217 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/navikt/zrooms/internal/config"
+	"github.com/navikt/zrooms/internal/models"
+	"github.com/navikt/zrooms/internal/repository/memory"
+	"github.com/navikt/zrooms/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEvent parses a webhook event from its JSON representation
+func newEvent(t *testing.T, payload string) *models.WebhookEvent {
+	t.Helper()
+	var event models.WebhookEvent
+	require.NoError(t, json.Unmarshal([]byte(payload), &event))
+	return &event
+}
+
+// TestMeetingService_ApplyEvent tests that events are applied once, with a result describing what happened
+func TestMeetingService_ApplyEvent(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	ctx := context.Background()
+
+	var updates []*models.Meeting
+	meetingService.RegisterUpdateCallback(func(meeting *models.Meeting) {
+		updates = append(updates, meeting)
+	})
+
+	t.Run("MeetingStarted", func(t *testing.T) {
+		result, err := meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.started", "payload": {"object": {"uuid": "uuid1", "id": "111", "topic": "Standup"}}, "event_ts": 1620000000000}`))
+		require.NoError(t, err)
+		assert.Equal(t, service.ApplyResult{Outcome: service.EventApplied, MeetingID: "111"}, result)
+
+		require.Len(t, updates, 1, "Callbacks should be notified exactly once")
+		assert.Equal(t, models.MeetingStatusStarted, updates[0].Status)
+
+		meeting, err := repo.GetMeeting(ctx, "111")
+		require.NoError(t, err)
+		assert.Equal(t, "Standup", meeting.Topic)
+		assert.Equal(t, models.MeetingStatusStarted, meeting.Status)
+	})
+
+	t.Run("ParticipantJoined", func(t *testing.T) {
+		result, err := meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.participant_joined", "payload": {"object": {"uuid": "uuid1", "id": "111", "participant": {"id": "part1"}}}, "event_ts": 1620000001000}`))
+		require.NoError(t, err)
+		assert.Equal(t, service.EventApplied, result.Outcome)
+
+		count, err := repo.CountParticipantsInMeeting(ctx, "111")
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("MeetingEnded", func(t *testing.T) {
+		result, err := meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.ended", "payload": {"object": {"uuid": "uuid1", "id": "111"}}, "event_ts": 1620000600000}`))
+		require.NoError(t, err)
+		assert.Equal(t, service.EventApplied, result.Outcome)
+
+		meeting, err := repo.GetMeeting(ctx, "111")
+		require.NoError(t, err)
+		assert.Equal(t, models.MeetingStatusEnded, meeting.Status)
+		assert.Equal(t, "Standup", meeting.Topic, "Topic should be kept from the stored meeting")
+
+		count, err := repo.CountParticipantsInMeeting(ctx, "111")
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("LateEventsAreOutOfOrder", func(t *testing.T) {
+		notified := len(updates)
+
+		result, err := meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.started", "payload": {"object": {"uuid": "uuid1", "id": "111"}}, "event_ts": 1620000300000}`))
+		require.NoError(t, err)
+		assert.Equal(t, service.EventOutOfOrder, result.Outcome)
+
+		result, err = meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.sharing_started", "payload": {"object": {"uuid": "uuid1", "id": "111", "participant": {"id": "part1"}}}, "event_ts": 1620000300000}`))
+		require.NoError(t, err)
+		assert.Equal(t, service.EventOutOfOrder, result.Outcome)
+
+		assert.Len(t, updates, notified, "Events that are not applied should not notify callbacks")
+	})
+
+	t.Run("LateParticipantEventsAreOutOfOrder", func(t *testing.T) {
+		_, err := meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.started", "payload": {"object": {"uuid": "uuid3", "id": "333"}}, "event_ts": 1620001000000}`))
+		require.NoError(t, err)
+
+		// The leave arrives before the join that happened earlier, so the participant must stay gone
+		result, err := meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.participant_left", "payload": {"object": {"uuid": "uuid3", "id": "333", "participant": {"id": "part1"}}}, "event_ts": 1620001002000}`))
+		require.NoError(t, err)
+		assert.Equal(t, service.EventApplied, result.Outcome)
+		result, err = meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.participant_joined", "payload": {"object": {"uuid": "uuid3", "id": "333", "participant": {"id": "part1"}}}, "event_ts": 1620001001000}`))
+		require.NoError(t, err)
+		assert.Equal(t, service.EventOutOfOrder, result.Outcome)
+
+		count, err := repo.CountParticipantsInMeeting(ctx, "333")
+		require.NoError(t, err)
+		assert.Equal(t, 0, count)
+
+		// A rejoin is applied, and the leave before it is out of order when it arrives late
+		result, err = meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.participant_joined", "payload": {"object": {"uuid": "uuid3", "id": "333", "participant": {"id": "part1"}}}, "event_ts": 1620001004000}`))
+		require.NoError(t, err)
+		assert.Equal(t, service.EventApplied, result.Outcome)
+		result, err = meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.participant_left", "payload": {"object": {"uuid": "uuid3", "id": "333", "participant": {"id": "part1"}}}, "event_ts": 1620001003000}`))
+		require.NoError(t, err)
+		assert.Equal(t, service.EventOutOfOrder, result.Outcome)
+
+		count, err = repo.CountParticipantsInMeeting(ctx, "333")
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("StartAfterWaitingStampedLater", func(t *testing.T) {
+		// A waiting participant makes the meeting known before its start is delivered, with a later event_ts
+		result, err := meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.participant_joined_waiting_room", "payload": {"object": {"uuid": "uuid4", "id": "444", "participant": {"id": "part1"}}}, "event_ts": 1620002002000}`))
+		require.NoError(t, err)
+		assert.Equal(t, service.EventApplied, result.Outcome)
+
+		result, err = meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.started", "payload": {"object": {"uuid": "uuid4", "id": "444"}}, "event_ts": 1620002001000}`))
+		require.NoError(t, err)
+		assert.Equal(t, service.EventApplied, result.Outcome)
+
+		meeting, err := repo.GetMeeting(ctx, "444")
+		require.NoError(t, err)
+		assert.Equal(t, models.MeetingStatusStarted, meeting.Status)
+	})
+
+	t.Run("LeftForBreakoutRoomIsIgnored", func(t *testing.T) {
+		result, err := meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.participant_left", "payload": {"object": {"uuid": "uuid2", "id": "222", "participant": {"id": "part1", "leave_reason": "left the meeting to join breakout room"}}}, "event_ts": 1620000700000}`))
+		require.NoError(t, err)
+		assert.Equal(t, service.EventIgnored, result.Outcome)
+	})
+
+	t.Run("InvalidEvent", func(t *testing.T) {
+		_, err := meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.deleted", "payload": {"object": {"uuid": "uuid1"}}}`))
+		assert.ErrorIs(t, err, service.ErrInvalidEvent)
+	})
+
+	t.Run("UnsupportedEvent", func(t *testing.T) {
+		result, err := meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.chat_message_sent", "payload": {"object": {"id": "111"}}}`))
+		require.NoError(t, err)
+		assert.Equal(t, service.EventUnsupported, result.Outcome)
+	})
+
+	t.Run("RoomEvent", func(t *testing.T) {
+		result, err := meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "zoomroom.checked_in", "payload": {"object": {"id": "room1", "room_name": "Oslo"}}, "event_ts": 1620000800000}`))
+		require.NoError(t, err)
+		assert.Equal(t, service.EventApplied, result.Outcome)
+		assert.Equal(t, "room1", result.RoomID)
+
+		room, err := repo.GetRoom(ctx, "room1")
+		require.NoError(t, err)
+		assert.Equal(t, models.RoomStatusCheckedIn, room.Status)
+	})
+}
+
+// TestMeetingService_ApplyEventAccounts tests that meetings of additional accounts are stored namespaced by account
+func TestMeetingService_ApplyEventAccounts(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	meetingService.SetAccounts(config.ZoomAccounts{
+		{Name: config.DefaultAccountName},
+		{Name: "sandbox", ID: "sandbox123"},
+	})
+	ctx := context.Background()
+
+	result, err := meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.started", "payload": {"account_id": "sandbox123", "object": {"uuid": "uuid1", "id": "999", "topic": "Sandbox"}}, "event_ts": 1620000000000}`))
+	require.NoError(t, err)
+	assert.Equal(t, models.MeetingKey("sandbox123", "999"), result.MeetingID)
+
+	result, err = meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.started", "payload": {"account_id": "main123", "object": {"uuid": "uuid2", "id": "999", "topic": "Main"}}, "event_ts": 1620000000000}`))
+	require.NoError(t, err)
+	assert.Equal(t, "999", result.MeetingID)
+
+	meeting, err := repo.GetMeeting(ctx, models.MeetingKey("sandbox123", "999"))
+	require.NoError(t, err)
+	assert.Equal(t, "Sandbox", meeting.Topic)
+}
+
+// failingDeleteRepository is a memory repository whose meetings cannot be deleted
+type failingDeleteRepository struct {
+	*memory.Repository
+}
+
+// DeleteMeeting always fails, as a storage failure would
+func (r *failingDeleteRepository) DeleteMeeting(ctx context.Context, id string) error {
+	return errors.New("storage unavailable")
+}
+
+// TestMeetingService_ApplyMeetingDeleted tests that only a missing meeting makes a deletion ignored,
+// while storage failures are returned so the event can be retried or kept as a dead letter
+func TestMeetingService_ApplyMeetingDeleted(t *testing.T) {
+	ctx := context.Background()
+	deleted := newEvent(t, `{"event": "meeting.deleted", "payload": {"object": {"uuid": "uuid1", "id": "111"}}, "event_ts": 1620000000000}`)
+
+	t.Run("UnknownMeeting", func(t *testing.T) {
+		meetingService := service.NewMeetingService(memory.NewRepository())
+		result, err := meetingService.ApplyEvent(ctx, deleted)
+		require.NoError(t, err)
+		assert.Equal(t, service.EventIgnored, result.Outcome)
+	})
+
+	t.Run("StorageFailure", func(t *testing.T) {
+		repo := &failingDeleteRepository{Repository: memory.NewRepository()}
+		require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "111", Status: models.MeetingStatusCreated}))
+
+		meetingService := service.NewMeetingService(repo)
+		_, err := meetingService.ApplyEvent(ctx, deleted)
+		assert.ErrorContains(t, err, "storage unavailable")
+		assert.NotErrorIs(t, err, service.ErrInvalidEvent, "The deletion should be retried")
+	})
+}
@@ -2,9 +2,12 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"sort"
 	"time"
 
+	"github.com/navikt/zrooms/internal/config"
 	"github.com/navikt/zrooms/internal/models"
 	"github.com/navikt/zrooms/internal/repository"
 )
@@ -12,18 +15,39 @@ import (
 // MeetingUpdateCallback is a function type for meeting update callbacks
 type MeetingUpdateCallback func(*models.Meeting)
 
+// RoomUpdateCallback is a function type for room update callbacks
+type RoomUpdateCallback func(*models.Room)
+
+// DeviceAlertCallback is a function type for device alert callbacks
+type DeviceAlertCallback func(*models.DeviceAlert)
+
 // MeetingService provides business logic for working with meetings
 type MeetingService struct {
-	repo            repository.Repository
-	updateCallbacks []MeetingUpdateCallback
+	repo                 repository.Repository
+	accounts             config.ZoomAccounts // Zoom accounts meetings are namespaced by, default account first
+	events               *EventRegistry      // Handlers applying webhook events, by event name
+	updateCallbacks      []MeetingUpdateCallback
+	roomUpdateCallbacks  []RoomUpdateCallback
+	deviceAlertCallbacks []DeviceAlertCallback
 }
 
-// NewMeetingService creates a new MeetingService with the given repository
+// NewMeetingService creates a new MeetingService with the given repository,
+// with handlers registered for all webhook events zrooms supports
 func NewMeetingService(repo repository.Repository) *MeetingService {
-	return &MeetingService{
+	s := &MeetingService{
 		repo:            repo,
+		accounts:        config.GetZoomConfig().AllAccounts(),
+		events:          NewEventRegistry(),
 		updateCallbacks: make([]MeetingUpdateCallback, 0),
 	}
+	s.registerEventHandlers()
+	return s
+}
+
+// SetAccounts sets the Zoom accounts events are applied for, default account first.
+// Meetings of accounts other than the default account are stored namespaced by account.
+func (s *MeetingService) SetAccounts(accounts config.ZoomAccounts) {
+	s.accounts = accounts
 }
 
 // RegisterUpdateCallback registers a callback function to be called when meeting data changes
@@ -31,6 +55,16 @@ func (s *MeetingService) RegisterUpdateCallback(callback MeetingUpdateCallback)
 	s.updateCallbacks = append(s.updateCallbacks, callback)
 }
 
+// RegisterRoomUpdateCallback registers a callback function to be called when room state changes
+func (s *MeetingService) RegisterRoomUpdateCallback(callback RoomUpdateCallback) {
+	s.roomUpdateCallbacks = append(s.roomUpdateCallbacks, callback)
+}
+
+// RegisterDeviceAlertCallback registers a callback function to be called when a device alert is raised or cleared
+func (s *MeetingService) RegisterDeviceAlertCallback(callback DeviceAlertCallback) {
+	s.deviceAlertCallbacks = append(s.deviceAlertCallbacks, callback)
+}
+
 // notifyUpdate calls all registered callbacks with the updated meeting
 func (s *MeetingService) notifyUpdate(meeting *models.Meeting) {
 	for _, callback := range s.updateCallbacks {
@@ -38,11 +72,19 @@ func (s *MeetingService) notifyUpdate(meeting *models.Meeting) {
 	}
 }
 
+// notifyRoomUpdate calls all registered room callbacks with the updated room
+func (s *MeetingService) notifyRoomUpdate(room *models.Room) {
+	for _, callback := range s.roomUpdateCallbacks {
+		callback(room)
+	}
+}
+
 // MeetingStatusData represents data for the web UI
 type MeetingStatusData struct {
 	Meeting          *models.Meeting
 	Status           string
 	ParticipantCount int
+	WaitingCount     int // Participants in the waiting room or waiting for the host
 	StartedAt        time.Time
 }
 
@@ -74,9 +116,16 @@ func (s *MeetingService) GetMeetingStatusData(ctx context.Context, includeEnded
 			participantCount = 0 // Default to 0 if there's an error
 		}
 
-		// For ended meetings, always set participant count to 0
+		// Get the number of participants waiting to be admitted
+		waitingCount, err := s.repo.CountParticipantsInWaitingRoom(ctx, meeting.ID)
+		if err != nil {
+			waitingCount = 0
+		}
+
+		// For ended meetings, always set participant and waiting counts to 0
 		if meeting.Status == models.MeetingStatusEnded {
 			participantCount = 0
+			waitingCount = 0
 		}
 
 		// Determine meeting status string
@@ -92,6 +141,7 @@ func (s *MeetingService) GetMeetingStatusData(ctx context.Context, includeEnded
 			Meeting:          meeting,
 			Status:           statusStr,
 			ParticipantCount: participantCount,
+			WaitingCount:     waitingCount,
 			StartedAt:        meeting.StartTime,
 		})
 	}
@@ -99,8 +149,73 @@ func (s *MeetingService) GetMeetingStatusData(ctx context.Context, includeEnded
 	return result, nil
 }
 
-// NotifyMeetingStarted handles notifications when a meeting starts
-func (s *MeetingService) NotifyMeetingStarted(meeting *models.Meeting) {
+// GetRooms returns all known Zoom Rooms sorted by name for the web UI.
+// Rooms in a meeting get the meeting topic from the stored meeting when the room event had none.
+func (s *MeetingService) GetRooms(ctx context.Context) ([]*models.Room, error) {
+	rooms, err := s.repo.ListRooms(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, room := range rooms {
+		if room.Status == models.RoomStatusInMeeting && room.MeetingTopic == "" && room.MeetingID != "" {
+			if meeting, err := s.repo.GetMeeting(ctx, room.MeetingID); err == nil {
+				room.MeetingTopic = meeting.Topic
+			}
+		}
+	}
+
+	sort.Slice(rooms, func(i, j int) bool {
+		if rooms[i].Name != rooms[j].Name {
+			return rooms[i].Name < rooms[j].Name
+		}
+		return rooms[i].ID < rooms[j].ID
+	})
+
+	return rooms, nil
+}
+
+// GetDeviceAlerts returns all device alerts for the admin UI.
+// Active alerts come first, each group ordered by the most recent change.
+func (s *MeetingService) GetDeviceAlerts(ctx context.Context) ([]*models.DeviceAlert, error) {
+	alerts, err := s.repo.ListDeviceAlerts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lastChange := func(alert *models.DeviceAlert) time.Time {
+		if alert.ClearedAt.After(alert.RaisedAt) {
+			return alert.ClearedAt
+		}
+		return alert.RaisedAt
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].IsActive() != alerts[j].IsActive() {
+			return alerts[i].IsActive()
+		}
+		return lastChange(alerts[i]).After(lastChange(alerts[j]))
+	})
+
+	return alerts, nil
+}
+
+// createMeeting stores a scheduled meeting and notifies callbacks about it
+func (s *MeetingService) createMeeting(ctx context.Context, meeting *models.Meeting) error {
+	// Ensure the meeting has status Created
+	meeting.Status = models.MeetingStatusCreated
+
+	// First save the meeting to ensure it exists with its planned start time and duration
+	if err := s.repo.SaveMeeting(ctx, meeting); err != nil {
+		return fmt.Errorf("error saving meeting: %w", err)
+	}
+	// Notify all registered callbacks about the scheduled meeting
+	s.notifyUpdate(meeting)
+	return nil
+}
+
+// startMeeting stores a meeting as started and notifies callbacks about it
+func (s *MeetingService) startMeeting(ctx context.Context, meeting *models.Meeting) error {
 	// Ensure the meeting has status Started
 	meeting.Status = models.MeetingStatusStarted
 
@@ -110,28 +225,34 @@ func (s *MeetingService) NotifyMeetingStarted(meeting *models.Meeting) {
 	}
 
 	// First save the meeting to ensure it exists and status is updated
-	ctx := context.Background()
 	if err := s.repo.SaveMeeting(ctx, meeting); err != nil {
-		log.Printf("Error saving started meeting state: %v", err)
+		return fmt.Errorf("error saving meeting: %w", err)
 	}
 	// Notify all registered callbacks about the meeting starting
 	s.notifyUpdate(meeting)
+	return nil
 }
 
-func (s *MeetingService) NotifyMeetingUpdated(meeting *models.Meeting) {
-	meeting.Status = models.MeetingStatusUpdated
+// updateMeeting stores the changed details of a meeting and notifies callbacks about it
+func (s *MeetingService) updateMeeting(ctx context.Context, meeting *models.Meeting) error {
+	// Keep the status of meetings that are running or finished, so an update
+	// does not turn them back into scheduled meetings
+	if meeting.Status != models.MeetingStatusStarted && meeting.Status != models.MeetingStatusEnded {
+		meeting.Status = models.MeetingStatusUpdated
+	}
 
 	// First save the meeting to ensure it exists and status is updated
-	ctx := context.Background()
 	if err := s.repo.SaveMeeting(ctx, meeting); err != nil {
-		log.Printf("Error saving updated meeting state: %v", err)
+		return fmt.Errorf("error saving meeting: %w", err)
 	}
-	// Notify all registered callbacks about the meeting starting
+	// Notify all registered callbacks about the meeting update
 	s.notifyUpdate(meeting)
+	return nil
 }
 
-// NotifyMeetingEnded handles notifications when a meeting ends
-func (s *MeetingService) NotifyMeetingEnded(meeting *models.Meeting) {
+// endMeeting stores a meeting as ended, clears everything that only applies to a running meeting
+// and notifies callbacks about it
+func (s *MeetingService) endMeeting(ctx context.Context, meeting *models.Meeting) error {
 	// Ensure the meeting has status Ended
 	meeting.Status = models.MeetingStatusEnded
 
@@ -141,9 +262,8 @@ func (s *MeetingService) NotifyMeetingEnded(meeting *models.Meeting) {
 	}
 
 	// First save the meeting to ensure it exists and has the correct status and endTime
-	ctx := context.Background()
 	if err := s.repo.SaveMeeting(ctx, meeting); err != nil {
-		log.Printf("Error saving ended meeting state: %v", err)
+		return fmt.Errorf("error updating meeting: %w", err)
 	}
 
 	err := s.repo.ClearPartipantsInMeeting(ctx, meeting.ID)
@@ -151,8 +271,27 @@ func (s *MeetingService) NotifyMeetingEnded(meeting *models.Meeting) {
 		log.Printf("Error clearing participants for meeting ID (%s): %v", meeting.ID, err)
 
 	}
+	if err := s.repo.ClearWaitingRoom(ctx, meeting.ID); err != nil {
+		log.Printf("Error clearing waiting room for meeting ID (%s): %v", meeting.ID, err)
+	}
+	if err := s.repo.ClearBreakoutRooms(ctx, meeting.ID); err != nil {
+		log.Printf("Error clearing breakout rooms for meeting ID (%s): %v", meeting.ID, err)
+	}
+	if err := s.repo.SetMeetingRecording(ctx, meeting.ID, models.RecordingStatusNone); err != nil {
+		log.Printf("Error clearing recording indicator for meeting ID (%s): %v", meeting.ID, err)
+	}
+	if err := s.repo.SetMeetingSharing(ctx, meeting.ID, false); err != nil {
+		log.Printf("Error clearing sharing indicator for meeting ID (%s): %v", meeting.ID, err)
+	}
 	// Notify all registered callbacks about the meeting ending
 	s.notifyUpdate(meeting)
+	return nil
+}
+
+// NotifyMeetingDeleted handles notifications when a meeting has been deleted
+func (s *MeetingService) NotifyMeetingDeleted(meetingID string) {
+	// The meeting is gone from the repository, so only its ID is available
+	s.notifyUpdate(&models.Meeting{ID: meetingID})
 }
 
 // NotifyParticipantJoined handles notifications when a participant joins a meeting
@@ -180,3 +319,49 @@ func (s *MeetingService) NotifyParticipantLeft(meetingID string, participantID s
 	// Notify about the change
 	s.notifyUpdate(meeting)
 }
+
+// NotifyWaitingRoomChanged handles notifications when a participant starts or stops waiting to be admitted
+func (s *MeetingService) NotifyWaitingRoomChanged(meetingID string) {
+	meeting, err := s.repo.GetMeeting(context.Background(), meetingID)
+	if err != nil {
+		log.Printf("Error getting meeting for waiting room notification: %v", err)
+		return
+	}
+
+	// Notify about the change
+	s.notifyUpdate(meeting)
+}
+
+// NotifyMeetingIndicatorsChanged handles notifications when a meeting starts or stops being recorded or shared
+func (s *MeetingService) NotifyMeetingIndicatorsChanged(meetingID string) {
+	meeting, err := s.repo.GetMeeting(context.Background(), meetingID)
+	if err != nil {
+		log.Printf("Error getting meeting for indicator notification: %v", err)
+		return
+	}
+
+	// Notify about the change
+	s.notifyUpdate(meeting)
+}
+
+// saveRoom stores the state of a Zoom Room and notifies callbacks about it
+func (s *MeetingService) saveRoom(ctx context.Context, room *models.Room) error {
+	if err := s.repo.SaveRoom(ctx, room); err != nil {
+		return fmt.Errorf("error saving room: %w", err)
+	}
+	// Notify all registered callbacks about the room change
+	s.notifyRoomUpdate(room)
+	return nil
+}
+
+// saveDeviceAlert stores a raised or cleared device alert and notifies callbacks about it
+func (s *MeetingService) saveDeviceAlert(ctx context.Context, alert *models.DeviceAlert) error {
+	if err := s.repo.SaveDeviceAlert(ctx, alert); err != nil {
+		return fmt.Errorf("error saving device alert: %w", err)
+	}
+	// Notify all registered callbacks about the alert
+	for _, callback := range s.deviceAlertCallbacks {
+		callback(alert)
+	}
+	return nil
+}
@@ -159,13 +159,73 @@ func TestMeetingService_UpdateCallbacks(t *testing.T) {
 	// 2. Notify about a participant leaving
 	meetingService.NotifyParticipantLeft(meeting.ID, "user1")
 
-	// 3. Notify about meeting starting
-	meetingService.NotifyMeetingStarted(meeting)
+	// 3. Apply the meeting starting
+	_, err := meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.started", "payload": {"object": {"uuid": "uuid1", "id": "test-meeting"}}, "event_ts": 1620000000000}`))
+	require.NoError(t, err)
 
-	// 4. Notify about meeting ending
-	meeting.Status = models.MeetingStatusEnded
-	meetingService.NotifyMeetingEnded(meeting)
+	// 4. Apply the meeting ending
+	_, err = meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.ended", "payload": {"object": {"uuid": "uuid1", "id": "test-meeting"}}, "event_ts": 1620000600000}`))
+	require.NoError(t, err)
 
 	// Verify callback was called the expected number of times (4 operations)
 	mockCallback.AssertNumberOfCalls(t, "OnUpdate", 4)
 }
+
+// TestMeetingService_ScheduledMeetings tests the scheduled status of created and updated meetings
+func TestMeetingService_ScheduledMeetings(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	ctx := context.Background()
+
+	planned := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	_, err := meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.created", "payload": {"object": {"uuid": "uuid1", "id": "scheduled-meeting", "topic": "Planning", "start_time": "2025-06-02T09:00:00Z", "duration": 30}}, "event_ts": 1748000000000}`))
+	require.NoError(t, err)
+
+	t.Run("CreatedMeetingIsScheduled", func(t *testing.T) {
+		data, err := meetingService.GetMeetingStatusData(ctx, false)
+		require.NoError(t, err)
+		require.Len(t, data, 1)
+		assert.Equal(t, "scheduled", data[0].Status)
+		assert.Equal(t, planned, data[0].StartedAt.UTC())
+		assert.Equal(t, 30, data[0].Meeting.Duration)
+	})
+
+	t.Run("UpdateKeepsRunningMeetingActive", func(t *testing.T) {
+		_, err := meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.started", "payload": {"object": {"uuid": "uuid1", "id": "scheduled-meeting"}}, "event_ts": 1748854800000}`))
+		require.NoError(t, err)
+		_, err = meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "meeting.updated", "payload": {"object": {"uuid": "uuid1", "id": "scheduled-meeting", "topic": "Renamed"}}, "event_ts": 1748854900000}`))
+		require.NoError(t, err)
+
+		data, err := meetingService.GetMeetingStatusData(ctx, false)
+		require.NoError(t, err)
+		require.Len(t, data, 1)
+		assert.Equal(t, "in_progress", data[0].Status)
+		assert.Equal(t, "Renamed", data[0].Meeting.Topic)
+	})
+}
+
+func TestMeetingService_Rooms(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	ctx := context.Background()
+
+	var notified []string
+	meetingService.RegisterRoomUpdateCallback(func(room *models.Room) {
+		notified = append(notified, room.ID)
+	})
+
+	require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "meeting123", Topic: "Standup", Status: models.MeetingStatusStarted}))
+	_, err := meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "zoomroom.started_meeting", "payload": {"object": {"id": "room2", "room_name": "Trondheim", "meeting_id": "meeting123"}}, "event_ts": 1620000000000}`))
+	require.NoError(t, err)
+	_, err = meetingService.ApplyEvent(ctx, newEvent(t, `{"event": "zoomroom.checked_out", "payload": {"object": {"id": "room1", "room_name": "Bergen"}}, "event_ts": 1620000000000}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"room2", "room1"}, notified)
+
+	rooms, err := meetingService.GetRooms(ctx)
+	require.NoError(t, err)
+	require.Len(t, rooms, 2)
+	assert.Equal(t, "Bergen", rooms[0].Name, "Rooms should be sorted by name")
+	assert.Equal(t, "Trondheim", rooms[1].Name)
+	assert.Equal(t, "Standup", rooms[1].MeetingTopic, "Topic should come from the stored meeting")
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/navikt/zrooms/internal/config"
+)
+
+// HealthState is the overall health reported by the silence watchdog
+type HealthState string
+
+const (
+	// HealthUp means webhook events arrive as expected, or no traffic is expected
+	HealthUp HealthState = "UP"
+	// HealthDegraded means no valid webhook event has arrived for longer than expected, so data may be stale
+	HealthDegraded HealthState = "DEGRADED"
+)
+
+// WatchdogStatus describes whether webhook events arrive as expected
+type WatchdogStatus struct {
+	Status HealthState
+	// Zero when no valid event has been received since the service started
+	LastEventAt time.Time
+	// Whether the time of the status is within the expected traffic window
+	ExpectingTraffic bool
+	// How long events have been missing while traffic was expected
+	Silence time.Duration
+	Reason  string
+}
+
+// Stale reports whether the data shown may be out of date
+func (s WatchdogStatus) Stale() bool {
+	return s.Status == HealthDegraded
+}
+
+// SilenceWatchdog tracks when the last valid webhook event arrived and reports a degraded state when events
+// stop arriving during the expected traffic window, for example because Zoom deactivated the event subscription
+type SilenceWatchdog struct {
+	cfg       config.WatchdogConfig
+	startedAt time.Time
+
+	mu          sync.RWMutex
+	lastEventAt time.Time
+}
+
+// NewSilenceWatchdog creates a watchdog for a service started at the given time, which counts as the
+// start of the silence until the first event arrives
+func NewSilenceWatchdog(cfg config.WatchdogConfig, startedAt time.Time) *SilenceWatchdog {
+	return &SilenceWatchdog{
+		cfg:       cfg,
+		startedAt: startedAt,
+	}
+}
+
+// RecordEvent records that a valid webhook event arrived at the given time
+func (w *SilenceWatchdog) RecordEvent(at time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if at.After(w.lastEventAt) {
+		w.lastEventAt = at
+	}
+}
+
+// Status returns the current watchdog status
+func (w *SilenceWatchdog) Status() WatchdogStatus {
+	return w.StatusAt(time.Now())
+}
+
+// StatusAt returns the watchdog status at the given time
+func (w *SilenceWatchdog) StatusAt(now time.Time) WatchdogStatus {
+	w.mu.RLock()
+	lastEventAt := w.lastEventAt
+	w.mu.RUnlock()
+
+	status := WatchdogStatus{Status: HealthUp, LastEventAt: lastEventAt}
+	if w.cfg.SilenceThreshold <= 0 {
+		return status
+	}
+
+	windowStart, expecting := w.expectedWindowStart(now)
+	status.ExpectingTraffic = expecting
+	if !expecting {
+		return status
+	}
+
+	// Silence outside the expected window, such as overnight, does not count
+	silenceStart := windowStart
+	for _, t := range []time.Time{w.startedAt, lastEventAt} {
+		if t.After(silenceStart) {
+			silenceStart = t
+		}
+	}
+	if now.After(silenceStart) {
+		status.Silence = now.Sub(silenceStart)
+	}
+
+	if status.Silence > w.cfg.SilenceThreshold {
+		status.Status = HealthDegraded
+		status.Reason = "no webhook events received for " + status.Silence.Round(time.Minute).String()
+	}
+	return status
+}
+
+// expectedWindowStart returns when the expected traffic window containing the given time started,
+// and false when traffic is not expected at that time
+func (w *SilenceWatchdog) expectedWindowStart(now time.Time) (time.Time, bool) {
+	if !slices.Contains(w.cfg.ExpectedDays, now.Weekday()) {
+		return time.Time{}, false
+	}
+
+	start := timeOfDay(now, w.cfg.ExpectedFrom)
+	end := timeOfDay(now, w.cfg.ExpectedUntil)
+	if now.Before(start) || !now.Before(end) {
+		return time.Time{}, false
+	}
+	return start, true
+}
+
+// timeOfDay returns the wall clock time at the given offset from midnight on the day of t,
+// so the window follows local time across daylight saving changes
+func timeOfDay(t time.Time, offset time.Duration) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, int(offset/time.Minute), 0, 0, t.Location())
+}
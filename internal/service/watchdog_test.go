@@ -0,0 +1,82 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/navikt/zrooms/internal/config"
+	"github.com/navikt/zrooms/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSilenceWatchdog tests that missing webhook events only degrade health during the expected traffic window
+func TestSilenceWatchdog(t *testing.T) {
+	cfg := config.WatchdogConfig{
+		SilenceThreshold: time.Hour,
+		ExpectedDays:     []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		ExpectedFrom:     8 * time.Hour,
+		ExpectedUntil:    16 * time.Hour,
+	}
+	// Friday afternoon, with the service running over the weekend
+	startedAt := time.Date(2025, 6, 6, 15, 0, 0, 0, time.UTC)
+	monday := func(hour, minute int) time.Time {
+		return time.Date(2025, 6, 9, hour, minute, 0, 0, time.UTC)
+	}
+
+	t.Run("OutsideExpectedWindow", func(t *testing.T) {
+		watchdog := service.NewSilenceWatchdog(cfg, startedAt)
+
+		for _, now := range []time.Time{
+			monday(7, 59),
+			monday(16, 0),
+			time.Date(2025, 6, 7, 12, 0, 0, 0, time.UTC), // Saturday
+		} {
+			status := watchdog.StatusAt(now)
+			assert.Equal(t, service.HealthUp, status.Status, now)
+			assert.False(t, status.ExpectingTraffic, now)
+		}
+	})
+
+	t.Run("SilenceCountsFromStartOfWindow", func(t *testing.T) {
+		watchdog := service.NewSilenceWatchdog(cfg, startedAt)
+
+		status := watchdog.StatusAt(monday(8, 30))
+		assert.Equal(t, service.HealthUp, status.Status)
+		assert.True(t, status.ExpectingTraffic)
+		assert.Equal(t, 30*time.Minute, status.Silence)
+
+		status = watchdog.StatusAt(monday(9, 30))
+		assert.Equal(t, service.HealthDegraded, status.Status)
+		assert.True(t, status.Stale())
+		assert.Equal(t, 90*time.Minute, status.Silence)
+		assert.Equal(t, "no webhook events received for 1h30m0s", status.Reason)
+	})
+
+	t.Run("EventsKeepHealthUp", func(t *testing.T) {
+		watchdog := service.NewSilenceWatchdog(cfg, startedAt)
+		watchdog.RecordEvent(monday(9, 0))
+		watchdog.RecordEvent(monday(8, 45)) // Out of order reports do not move the last event back
+
+		status := watchdog.StatusAt(monday(9, 30))
+		assert.Equal(t, service.HealthUp, status.Status)
+		assert.Equal(t, monday(9, 0), status.LastEventAt)
+		assert.Equal(t, 30*time.Minute, status.Silence)
+
+		assert.Equal(t, service.HealthDegraded, watchdog.StatusAt(monday(10, 1)).Status)
+	})
+
+	t.Run("StartedDuringWindow", func(t *testing.T) {
+		watchdog := service.NewSilenceWatchdog(cfg, monday(10, 0))
+		assert.Equal(t, service.HealthUp, watchdog.StatusAt(monday(10, 59)).Status)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		disabled := cfg
+		disabled.SilenceThreshold = 0
+		watchdog := service.NewSilenceWatchdog(disabled, startedAt)
+
+		status := watchdog.StatusAt(monday(15, 0))
+		assert.Equal(t, service.HealthUp, status.Status)
+		assert.False(t, status.ExpectingTraffic)
+	})
+}
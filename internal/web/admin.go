@@ -1,6 +1,7 @@
 package web
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/navikt/zrooms/internal/config"
 	"github.com/navikt/zrooms/internal/models"
 	"github.com/navikt/zrooms/internal/repository"
 	"github.com/navikt/zrooms/internal/service"
@@ -21,15 +23,24 @@ import (
 type AdminHandler struct {
 	meetingService *service.MeetingService
 	repo           repository.Repository
+	replayer       EventReplayer
+	webhookMonitor WebhookMonitor // Optional; recent deliveries and secret matches are not shown when nil
 	templates      *template.Template
+	accounts       config.ZoomAccounts
 }
 
 // NewAdminHandler creates a new admin handler
-func NewAdminHandler(meetingService *service.MeetingService, repo repository.Repository, templatesDir string) (*AdminHandler, error) {
+func NewAdminHandler(meetingService *service.MeetingService, repo repository.Repository, replayer EventReplayer, templatesDir string) (*AdminHandler, error) {
+	accounts := config.GetZoomConfig().AllAccounts()
+
 	// Parse admin templates
 	tmpl, err := template.New("").Funcs(template.FuncMap{
+		"filterQuery":    filterQuery,
+		"accountName":    accountNameFunc(accounts),
 		"formatTime":     formatTime,
 		"formatDateTime": formatDateTime,
+		"formatLatency":  formatLatency,
+		"outcomeClass":   deliveryOutcomeClass,
 		"statusClass":    statusClass,
 		"statusText":     statusText,
 		"slice":          slice,
@@ -43,10 +54,17 @@ func NewAdminHandler(meetingService *service.MeetingService, repo repository.Rep
 	return &AdminHandler{
 		meetingService: meetingService,
 		repo:           repo,
+		replayer:       replayer,
 		templates:      tmpl,
+		accounts:       accounts,
 	}, nil
 }
 
+// SetWebhookMonitor sets the source of the recent webhook deliveries and secret matches shown in the admin UI
+func (h *AdminHandler) SetWebhookMonitor(monitor WebhookMonitor) {
+	h.webhookMonitor = monitor
+}
+
 // SetupAdminRoutes registers admin routes on the given mux with authentication
 func (h *AdminHandler) SetupAdminRoutes(mux *http.ServeMux) {
 	auth := NewAuthMiddleware()
@@ -56,6 +74,15 @@ func (h *AdminHandler) SetupAdminRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/admin/meetings/", auth.RequireAuth(h.handleMeetingDetail))
 	mux.HandleFunc("/admin/meetings/delete/", auth.RequireAuth(h.handleDeleteMeeting))
 	mux.HandleFunc("/admin/meetings/raw/", auth.RequireAuth(h.handleMeetingRawData))
+	mux.HandleFunc("/admin/deadletters", auth.RequireAuth(h.handleDeadLetters))
+	mux.HandleFunc("/admin/deadletters/replay/", auth.RequireAuth(h.handleReplayDeadLetter))
+	mux.HandleFunc("/admin/deadletters/discard/", auth.RequireAuth(h.handleDiscardDeadLetter))
+	mux.HandleFunc("/admin/devices", auth.RequireAuth(h.handleDeviceHealth))
+	mux.HandleFunc("/admin/devices/partial", auth.RequireAuth(h.handleDeviceHealthPartial))
+	mux.HandleFunc("/admin/secrets", auth.RequireAuth(h.handleWebhookSecrets))
+	mux.HandleFunc("/admin/diagnostics", auth.RequireAuth(h.handleDiagnostics))
+	mux.HandleFunc("/admin/webhooks", auth.RequireAuth(h.handleWebhookDeliveries))
+	mux.HandleFunc("/admin/webhooks/", auth.RequireAuth(h.handleWebhookDelivery))
 }
 
 // handleAdminDashboard renders the main admin dashboard
@@ -107,9 +134,15 @@ func (h *AdminHandler) handleMeetingsList(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Get participant counts for each meeting
+	// Get participant counts for each meeting, optionally only meetings or webinars of one account
+	meetingType := typeFilter(r)
+	accountName := accountFilter(r, h.accounts)
 	meetingsWithCounts := make([]MeetingWithParticipants, 0, len(allMeetings))
 	for _, meeting := range allMeetings {
+		if !matchesType(meeting, meetingType) || !matchesAccount(meeting, accountName, h.accounts) {
+			continue
+		}
+
 		count, err := h.repo.CountParticipantsInMeeting(ctx, meeting.ID)
 		if err != nil {
 			count = 0 // Default to 0 if there's an error
@@ -123,13 +156,19 @@ func (h *AdminHandler) handleMeetingsList(w http.ResponseWriter, r *http.Request
 
 	// Prepare view model
 	viewModel := struct {
-		Meetings    []MeetingWithParticipants
-		LastUpdated string
-		CurrentYear int
+		Meetings     []MeetingWithParticipants
+		Type         string
+		Account      string
+		AccountNames []string
+		LastUpdated  string
+		CurrentYear  int
 	}{
-		Meetings:    meetingsWithCounts,
-		LastUpdated: time.Now().Format("2006-01-02 15:04:05"),
-		CurrentYear: time.Now().Year(),
+		Meetings:     meetingsWithCounts,
+		Type:         meetingType,
+		Account:      accountName,
+		AccountNames: accountNames(h.accounts),
+		LastUpdated:  time.Now().Format("2006-01-02 15:04:05"),
+		CurrentYear:  time.Now().Year(),
 	}
 
 	// Render template
@@ -166,17 +205,36 @@ func (h *AdminHandler) handleMeetingDetail(w http.ResponseWriter, r *http.Reques
 		participantCount = 0
 	}
 
+	// Get the breakout rooms; participants in them are still counted in the meeting
+	breakoutRooms, err := h.repo.ListBreakoutRooms(ctx, meetingID)
+	if err != nil {
+		breakoutRooms = nil
+	}
+	mainRoomCount := participantCount
+	for _, room := range breakoutRooms {
+		mainRoomCount -= room.ParticipantCount
+	}
+	if mainRoomCount < 0 {
+		mainRoomCount = 0
+	}
+
 	// Prepare view model
 	viewModel := struct {
 		Meeting          *models.Meeting
 		ParticipantCount int
+		MainRoomCount    int
+		BreakoutRooms    []models.BreakoutRoom
 		HostID           string // Add this field for template compatibility
+		AccountName      string
 		LastUpdated      string
 		CurrentYear      int
 	}{
 		Meeting:          meeting,
 		ParticipantCount: participantCount,
+		MainRoomCount:    mainRoomCount,
+		BreakoutRooms:    breakoutRooms,
 		HostID:           meeting.Host.ID, // Extract host ID for easy template access
+		AccountName:      h.accounts.ByID(meeting.AccountID).Name,
 		LastUpdated:      time.Now().Format("2006-01-02 15:04:05"),
 		CurrentYear:      time.Now().Year(),
 	}
@@ -228,8 +286,15 @@ func (h *AdminHandler) handleMeetingRawData(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	// Query Zoom with the credentials of the account the meeting belongs to
+	account := h.accounts.ByID("")
+	if meeting, err := h.repo.GetMeeting(r.Context(), meetingID); err == nil {
+		account = h.accounts.ByID(meeting.AccountID)
+		meetingID = meeting.ZoomID()
+	}
+
 	// Create Zoom API manager
-	apiManager := zoom.NewAPIManager()
+	apiManager := zoom.NewAPIManagerForAccount(account)
 	client, err := apiManager.GetClient()
 	if err != nil {
 		log.Printf("Error creating Zoom API client: %v", err)
@@ -273,6 +338,375 @@ func (h *AdminHandler) handleMeetingRawData(w http.ResponseWriter, r *http.Reque
 	w.Write(prettyBytes)
 }
 
+// handleDeadLetters lists webhook events that could not be applied
+func (h *AdminHandler) handleDeadLetters(w http.ResponseWriter, r *http.Request) {
+	deadLetters, err := h.repo.ListDeadLetters(r.Context())
+	if err != nil {
+		log.Printf("Error listing dead letters: %v", err)
+		http.Error(w, "Failed to get failed events", http.StatusInternalServerError)
+		return
+	}
+
+	// Prepare view model
+	viewModel := struct {
+		DeadLetters []*models.DeadLetter
+		Result      string
+		LastUpdated string
+		CurrentYear int
+	}{
+		DeadLetters: deadLetters,
+		Result:      r.URL.Query().Get("result"),
+		LastUpdated: time.Now().Format("2006-01-02 15:04:05"),
+		CurrentYear: time.Now().Year(),
+	}
+
+	// Render template
+	err = h.templates.ExecuteTemplate(w, "dead_letters.html", viewModel)
+	if err != nil {
+		log.Printf("Error rendering dead letters template: %v", err)
+		// Don't call http.Error here as headers may already be written
+		return
+	}
+}
+
+// handleReplayDeadLetter applies a dead letter again (POST only)
+// The dead letter is removed if the replay succeeds, otherwise its failure reason is updated
+func (h *AdminHandler) handleReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract dead letter ID from URL path
+	id := strings.TrimPrefix(r.URL.Path, "/admin/deadletters/replay/")
+	if id == "" {
+		http.Error(w, "Dead letter ID required", http.StatusBadRequest)
+		return
+	}
+
+	if h.replayer == nil {
+		http.Error(w, "Replay not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx := r.Context()
+
+	deadLetter, err := h.repo.GetDeadLetter(ctx, id)
+	if err != nil {
+		log.Printf("Error getting dead letter %s: %v", id, err)
+		http.Error(w, "Failed event not found", http.StatusNotFound)
+		return
+	}
+
+	result := "replayed"
+	if err := h.replayer.ProcessEvent(ctx, &deadLetter.Event); err != nil {
+		log.Printf("Replay of dead letter %s failed: %v", id, err)
+		result = "failed"
+
+		// Keep the dead letter with the latest failure
+		deadLetter.Reason = err.Error()
+		deadLetter.Attempts++
+		deadLetter.FailedAt = time.Now()
+		if err := h.repo.SaveDeadLetter(ctx, deadLetter); err != nil {
+			log.Printf("Error updating dead letter %s: %v", id, err)
+		}
+	} else {
+		log.Printf("Replayed dead letter %s (%s)", id, deadLetter.Event.Event)
+		if err := h.repo.DeleteDeadLetter(ctx, id); err != nil {
+			log.Printf("Error deleting replayed dead letter %s: %v", id, err)
+		}
+	}
+
+	// Redirect back to the dead letter list
+	http.Redirect(w, r, "/admin/deadletters?result="+result, http.StatusSeeOther)
+}
+
+// handleDiscardDeadLetter deletes a dead letter without applying it (POST only)
+func (h *AdminHandler) handleDiscardDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract dead letter ID from URL path
+	id := strings.TrimPrefix(r.URL.Path, "/admin/deadletters/discard/")
+	if id == "" {
+		http.Error(w, "Dead letter ID required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.DeleteDeadLetter(r.Context(), id); err != nil {
+		log.Printf("Error discarding dead letter %s: %v", id, err)
+		http.Error(w, "Failed to discard event", http.StatusInternalServerError)
+		return
+	}
+
+	// Redirect back to the dead letter list
+	http.Redirect(w, r, "/admin/deadletters?result=discarded", http.StatusSeeOther)
+}
+
+// DeviceHealthData holds the device alerts shown on the device health page
+type DeviceHealthData struct {
+	Alerts      []*models.DeviceAlert
+	ActiveCount int
+	LastUpdated string
+	CurrentYear int
+}
+
+// deviceHealthData loads device alerts, active ones first
+func (h *AdminHandler) deviceHealthData(ctx context.Context) (DeviceHealthData, error) {
+	alerts, err := h.meetingService.GetDeviceAlerts(ctx)
+	if err != nil {
+		return DeviceHealthData{}, err
+	}
+
+	data := DeviceHealthData{
+		Alerts:      alerts,
+		LastUpdated: time.Now().Format("2006-01-02 15:04:05"),
+		CurrentYear: time.Now().Year(),
+	}
+	for _, alert := range alerts {
+		if alert.IsActive() {
+			data.ActiveCount++
+		}
+	}
+
+	return data, nil
+}
+
+// handleDeviceHealth lists Zoom Room device alerts with the time each was raised and cleared
+func (h *AdminHandler) handleDeviceHealth(w http.ResponseWriter, r *http.Request) {
+	viewModel, err := h.deviceHealthData(r.Context())
+	if err != nil {
+		log.Printf("Error listing device alerts: %v", err)
+		http.Error(w, "Failed to get device alerts", http.StatusInternalServerError)
+		return
+	}
+
+	// Render template
+	err = h.templates.ExecuteTemplate(w, "devices.html", viewModel)
+	if err != nil {
+		log.Printf("Error rendering device health template: %v", err)
+		// Don't call http.Error here as headers may already be written
+		return
+	}
+}
+
+// handleDeviceHealthPartial renders just the device alert list for HTMX updates
+func (h *AdminHandler) handleDeviceHealthPartial(w http.ResponseWriter, r *http.Request) {
+	viewModel, err := h.deviceHealthData(r.Context())
+	if err != nil {
+		log.Printf("Error listing device alerts: %v", err)
+		http.Error(w, "Failed to get device alerts", http.StatusInternalServerError)
+		return
+	}
+
+	err = h.templates.ExecuteTemplate(w, "device_alert_list", viewModel)
+	if err != nil {
+		log.Printf("Error rendering device alert list: %v", err)
+		http.Error(w, "Failed to render device alerts", http.StatusInternalServerError)
+	}
+}
+
+// WebhookSecretStatus describes a configured webhook secret without revealing it
+type WebhookSecretStatus struct {
+	AccountName string
+	Name        string
+	Fingerprint string
+	ExpiresAt   time.Time
+	Expired     bool
+	LastMatched time.Time // Zero when no recent event was verified with the secret
+}
+
+// webhookSecretStatuses lists the configured secrets of all accounts with when each last verified an event
+func webhookSecretStatuses(accounts config.ZoomAccounts, matches []models.WebhookSecretMatch, now time.Time) []WebhookSecretStatus {
+	var statuses []WebhookSecretStatus
+	for _, account := range accounts {
+		for _, secret := range account.WebhookSecrets {
+			status := WebhookSecretStatus{
+				AccountName: account.Name,
+				Name:        secret.Name,
+				Fingerprint: secret.Fingerprint(),
+				ExpiresAt:   secret.ExpiresAt,
+				Expired:     secret.IsExpired(now),
+			}
+			// Matches are ordered newest first
+			for _, match := range matches {
+				if match.AccountName == account.Name && match.SecretName == secret.Name {
+					status.LastMatched = match.ReceivedAt
+					break
+				}
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// handleWebhookSecrets shows the configured webhook secrets and which secret verified each recent event,
+// so an admin can tell when a previous secret is no longer used and can be retired
+func (h *AdminHandler) handleWebhookSecrets(w http.ResponseWriter, r *http.Request) {
+	var matches []models.WebhookSecretMatch
+	if h.webhookMonitor != nil {
+		matches = h.webhookMonitor.RecentSecretMatches()
+	}
+
+	// Prepare view model
+	viewModel := struct {
+		Secrets     []WebhookSecretStatus
+		Matches     []models.WebhookSecretMatch
+		LastUpdated string
+		CurrentYear int
+	}{
+		Secrets:     webhookSecretStatuses(h.accounts, matches, time.Now()),
+		Matches:     matches,
+		LastUpdated: time.Now().Format("2006-01-02 15:04:05"),
+		CurrentYear: time.Now().Year(),
+	}
+
+	// Render template
+	err := h.templates.ExecuteTemplate(w, "secrets.html", viewModel)
+	if err != nil {
+		log.Printf("Error rendering webhook secrets template: %v", err)
+		// Don't call http.Error here as headers may already be written
+		return
+	}
+}
+
+// handleDiagnostics lists received webhook events no handler is registered for, and the events that are handled
+func (h *AdminHandler) handleDiagnostics(w http.ResponseWriter, r *http.Request) {
+	// Prepare view model
+	viewModel := struct {
+		UnknownEvents []service.UnknownEvent
+		HandledEvents []string
+		LastUpdated   string
+		CurrentYear   int
+	}{
+		UnknownEvents: h.meetingService.UnknownEvents(),
+		HandledEvents: h.meetingService.HandledEvents(),
+		LastUpdated:   time.Now().Format("2006-01-02 15:04:05"),
+		CurrentYear:   time.Now().Year(),
+	}
+
+	// Render template
+	err := h.templates.ExecuteTemplate(w, "diagnostics.html", viewModel)
+	if err != nil {
+		log.Printf("Error rendering diagnostics template: %v", err)
+		// Don't call http.Error here as headers may already be written
+		return
+	}
+}
+
+// handleWebhookDeliveries lists the most recent webhook requests with their signature result, outcome and latency,
+// and the source addresses rejected by the IP allowlist
+func (h *AdminHandler) handleWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	var deliveries []models.WebhookDelivery
+	var rejectedSources []models.RejectedSource
+	if h.webhookMonitor != nil {
+		deliveries = h.webhookMonitor.RecentDeliveries()
+		rejectedSources = h.webhookMonitor.RejectedSources()
+	}
+
+	// Prepare view model
+	viewModel := struct {
+		Deliveries      []models.WebhookDelivery
+		RejectedSources []models.RejectedSource
+		LastUpdated     string
+		CurrentYear     int
+	}{
+		Deliveries:      deliveries,
+		RejectedSources: rejectedSources,
+		LastUpdated:     time.Now().Format("2006-01-02 15:04:05"),
+		CurrentYear:     time.Now().Year(),
+	}
+
+	// Render template
+	err := h.templates.ExecuteTemplate(w, "webhooks.html", viewModel)
+	if err != nil {
+		log.Printf("Error rendering webhook deliveries template: %v", err)
+		// Don't call http.Error here as headers may already be written
+		return
+	}
+}
+
+// handleWebhookDelivery shows a single webhook request with its payload, personal data redacted
+func (h *AdminHandler) handleWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	// Extract delivery ID from URL path
+	deliveryID := strings.TrimPrefix(r.URL.Path, "/admin/webhooks/")
+	if deliveryID == "" {
+		http.Error(w, "Delivery ID required", http.StatusBadRequest)
+		return
+	}
+
+	if h.webhookMonitor == nil {
+		http.Error(w, "Delivery not found", http.StatusNotFound)
+		return
+	}
+	delivery, ok := h.webhookMonitor.Delivery(deliveryID)
+	if !ok {
+		// Old deliveries are dropped from the log as new ones arrive
+		http.Error(w, "Delivery not found", http.StatusNotFound)
+		return
+	}
+
+	// Pretty format the redacted payload
+	var payload bytes.Buffer
+	if len(delivery.Payload) > 0 {
+		if err := json.Indent(&payload, delivery.Payload, "", "  "); err != nil {
+			log.Printf("Error formatting payload of webhook delivery %s: %v", deliveryID, err)
+			payload.Reset()
+			payload.Write(delivery.Payload)
+		}
+	}
+
+	// Prepare view model
+	viewModel := struct {
+		Delivery    models.WebhookDelivery
+		Payload     string
+		LastUpdated string
+		CurrentYear int
+	}{
+		Delivery:    delivery,
+		Payload:     payload.String(),
+		LastUpdated: time.Now().Format("2006-01-02 15:04:05"),
+		CurrentYear: time.Now().Year(),
+	}
+
+	// Render template
+	err := h.templates.ExecuteTemplate(w, "webhook_detail.html", viewModel)
+	if err != nil {
+		log.Printf("Error rendering webhook delivery template: %v", err)
+		// Don't call http.Error here as headers may already be written
+		return
+	}
+}
+
+// formatLatency formats a delivery latency for display, rounded to a readable precision
+func formatLatency(latency time.Duration) string {
+	switch {
+	case latency <= 0:
+		return "-"
+	case latency < time.Millisecond:
+		return latency.Round(time.Microsecond).String()
+	case latency < time.Second:
+		return latency.Round(100 * time.Microsecond).String()
+	default:
+		return latency.Round(time.Millisecond).String()
+	}
+}
+
+// deliveryOutcomeClass returns the CSS class for a webhook delivery outcome
+func deliveryOutcomeClass(outcome models.DeliveryOutcome) string {
+	switch outcome {
+	case models.DeliveryOutcome(service.EventApplied), models.DeliveryURLValidation:
+		return "status-active"
+	case models.DeliveryRejected, models.DeliveryMalformed, models.DeliveryFailed:
+		return "status-ended"
+	default:
+		return "status-scheduled"
+	}
+}
+
 // AdminStats holds statistics for the admin dashboard
 type AdminStats struct {
 	TotalMeetings     int
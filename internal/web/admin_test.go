@@ -0,0 +1,348 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/navikt/zrooms/internal/models"
+	"github.com/navikt/zrooms/internal/repository/memory"
+	"github.com/navikt/zrooms/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReplayer records replayed events and fails when told to
+type fakeReplayer struct {
+	replayed []string
+	err      error
+}
+
+func (f *fakeReplayer) ProcessEvent(ctx context.Context, event *models.WebhookEvent) error {
+	f.replayed = append(f.replayed, event.Event)
+	return f.err
+}
+
+func TestAdminDeadLetters(t *testing.T) {
+	repo := memory.NewRepository()
+	replayer := &fakeReplayer{}
+	handler, err := NewAdminHandler(service.NewMeetingService(repo), repo, replayer, "templates")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	deadLetter := models.NewDeadLetter(models.WebhookEvent{
+		Event:   "meeting.participant_joined",
+		Payload: json.RawMessage(`{"object": {"id": "meeting123"}}`),
+	}, "meeting not found", 4)
+	require.NoError(t, repo.SaveDeadLetter(ctx, deadLetter))
+
+	t.Run("ListShowsReason", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.handleDeadLetters(rr, httptest.NewRequest("GET", "/admin/deadletters", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), "meeting.participant_joined")
+		assert.Contains(t, rr.Body.String(), "meeting not found")
+		assert.Contains(t, rr.Body.String(), "/admin/deadletters/replay/"+deadLetter.ID)
+	})
+
+	t.Run("ReplayRequiresPost", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.handleReplayDeadLetter(rr, httptest.NewRequest("GET", "/admin/deadletters/replay/"+deadLetter.ID, nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	})
+
+	t.Run("FailedReplayKeepsDeadLetter", func(t *testing.T) {
+		replayer.err = errors.New("still not found")
+		rr := httptest.NewRecorder()
+		handler.handleReplayDeadLetter(rr, httptest.NewRequest("POST", "/admin/deadletters/replay/"+deadLetter.ID, nil))
+		assert.Equal(t, http.StatusSeeOther, rr.Code)
+		assert.Equal(t, "/admin/deadletters?result=failed", rr.Header().Get("Location"))
+
+		stored, err := repo.GetDeadLetter(ctx, deadLetter.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "still not found", stored.Reason)
+		assert.Equal(t, 5, stored.Attempts)
+	})
+
+	t.Run("SuccessfulReplayRemovesDeadLetter", func(t *testing.T) {
+		replayer.err = nil
+		rr := httptest.NewRecorder()
+		handler.handleReplayDeadLetter(rr, httptest.NewRequest("POST", "/admin/deadletters/replay/"+deadLetter.ID, nil))
+		assert.Equal(t, "/admin/deadletters?result=replayed", rr.Header().Get("Location"))
+		assert.Equal(t, []string{"meeting.participant_joined", "meeting.participant_joined"}, replayer.replayed)
+
+		_, err := repo.GetDeadLetter(ctx, deadLetter.ID)
+		assert.ErrorIs(t, err, memory.ErrNotFound)
+	})
+
+	t.Run("Discard", func(t *testing.T) {
+		other := models.NewDeadLetter(models.WebhookEvent{Event: "meeting.started"}, "invalid payload", 1)
+		require.NoError(t, repo.SaveDeadLetter(ctx, other))
+
+		rr := httptest.NewRecorder()
+		handler.handleDiscardDeadLetter(rr, httptest.NewRequest("POST", "/admin/deadletters/discard/"+other.ID, nil))
+		assert.Equal(t, "/admin/deadletters?result=discarded", rr.Header().Get("Location"))
+
+		_, err := repo.GetDeadLetter(ctx, other.ID)
+		assert.ErrorIs(t, err, memory.ErrNotFound)
+		assert.Len(t, replayer.replayed, 2, "Discarded events are not replayed")
+	})
+}
+
+func TestAdminDeviceHealth(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	handler, err := NewAdminHandler(meetingService, repo, nil, "templates")
+	require.NoError(t, err)
+
+	raisedAt := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC)
+	applyEvent(t, meetingService, fmt.Sprintf(`{"event": "zoomroom.alert", "payload": {"object": {"id": "room1", "room_name": "Oslo", "issue": "Controller is offline", "alert_type": 3, "component": 2, "alert_kind": 1}}, "event_ts": %d}`,
+		raisedAt.UnixMilli()))
+	applyEvent(t, meetingService, fmt.Sprintf(`{"event": "zoomroom.delayed_alert", "payload": {"object": {"id": "room2", "issue": "Battery low", "alert_type": 7, "component": 1, "alert_kind": 1}}, "event_ts": %d}`,
+		raisedAt.Add(-time.Hour).UnixMilli()))
+	applyEvent(t, meetingService, fmt.Sprintf(`{"event": "zoomroom.delayed_alert", "payload": {"object": {"id": "room2", "issue": "Battery low", "alert_type": 7, "component": 1, "alert_kind": 2}}, "event_ts": %d}`,
+		raisedAt.Add(-30*time.Minute).UnixMilli()))
+
+	t.Run("Page", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.handleDeviceHealth(rr, httptest.NewRequest("GET", "/admin/devices", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		body := rr.Body.String()
+		assert.Contains(t, body, `sse-connect="/events"`)
+		assert.Contains(t, body, "sse:device-alert")
+		assert.Contains(t, body, "1 active of 2 alerts")
+		assert.Contains(t, body, "Controller is offline")
+		assert.Contains(t, body, formatDateTime(raisedAt.Local()), "Raised time should be shown")
+		assert.Contains(t, body, formatDateTime(raisedAt.Add(-30*time.Minute).Local()), "Cleared time should be shown")
+		assert.Contains(t, body, "(delayed)")
+		assert.Less(t, strings.Index(body, "Oslo"), strings.Index(body, "room2"), "Active alerts should be listed first")
+	})
+
+	t.Run("Partial", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.handleDeviceHealthPartial(rr, httptest.NewRequest("GET", "/admin/devices/partial", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Controller is offline")
+		assert.NotContains(t, rr.Body.String(), "<html")
+	})
+}
+
+func TestAdminMeetingsTypeFilter(t *testing.T) {
+	repo := memory.NewRepository()
+	handler, err := NewAdminHandler(service.NewMeetingService(repo), repo, nil, "templates")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "meeting1", Topic: "Team Standup", Status: models.MeetingStatusStarted}))
+	require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "webinar1", Topic: "All Hands", Type: models.MeetingTypeWebinar, Status: models.MeetingStatusStarted}))
+
+	rr := httptest.NewRecorder()
+	handler.handleMeetingsList(rr, httptest.NewRequest("GET", "/admin/meetings?type=webinar", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "All Hands")
+	assert.Contains(t, rr.Body.String(), `<span class="type-badge">Webinar</span>`)
+	assert.Contains(t, rr.Body.String(), "1 total webinars")
+	assert.NotContains(t, rr.Body.String(), "Team Standup")
+}
+
+func TestAdminMeetingDetailBreakoutRooms(t *testing.T) {
+	repo := memory.NewRepository()
+	handler, err := NewAdminHandler(service.NewMeetingService(repo), repo, nil, "templates")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "meeting1", Topic: "Workshop", Status: models.MeetingStatusStarted}))
+	for _, id := range []string{"part1", "part2", "part3", "part4"} {
+		require.NoError(t, repo.AddParticipantToMeeting(ctx, "meeting1", id))
+	}
+	require.NoError(t, repo.AddParticipantToBreakoutRoom(ctx, "meeting1", "breakoutA", "part1"))
+	require.NoError(t, repo.AddParticipantToBreakoutRoom(ctx, "meeting1", "breakoutA", "part2"))
+	require.NoError(t, repo.AddParticipantToBreakoutRoom(ctx, "meeting1", "breakoutB", "part3"))
+
+	rr := httptest.NewRecorder()
+	handler.handleMeetingDetail(rr, httptest.NewRequest("GET", "/admin/meetings/meeting1", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	body := rr.Body.String()
+	assert.Contains(t, body, "4 participants")
+	assert.Regexp(t, `Main room</td>\s*<td>1</td>`, body)
+	assert.Regexp(t, `<code>breakoutA</code></td>\s*<td>2</td>`, body)
+	assert.Regexp(t, `<code>breakoutB</code></td>\s*<td>1</td>`, body)
+}
+
+func TestAdminMeetingsAccountFilter(t *testing.T) {
+	t.Setenv("ZOOM_ACCOUNTS", "sandbox")
+	t.Setenv("ZOOM_ACCOUNT_SANDBOX_ID", "sandbox123")
+
+	repo := memory.NewRepository()
+	handler, err := NewAdminHandler(service.NewMeetingService(repo), repo, nil, "templates")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: "meeting1", AccountID: "main123", Topic: "Team Standup", Status: models.MeetingStatusStarted}))
+	require.NoError(t, repo.SaveMeeting(ctx, &models.Meeting{ID: models.MeetingKey("sandbox123", "meeting1"), AccountID: "sandbox123", Topic: "Sandbox Test", Status: models.MeetingStatusStarted}))
+
+	rr := httptest.NewRecorder()
+	handler.handleMeetingsList(rr, httptest.NewRequest("GET", "/admin/meetings?account=sandbox", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Sandbox Test")
+	assert.Contains(t, rr.Body.String(), `<span class="account-badge">sandbox</span>`)
+	assert.NotContains(t, rr.Body.String(), "Team Standup")
+
+	rr = httptest.NewRecorder()
+	handler.handleMeetingDetail(rr, httptest.NewRequest("GET", "/admin/meetings/sandbox123:meeting1", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "<code>meeting1</code>")
+	assert.Contains(t, rr.Body.String(), "sandbox <code>sandbox123</code>")
+}
+
+// fakeWebhookMonitor returns fixed lists of recent deliveries, secret matches and rejected sources
+type fakeWebhookMonitor struct {
+	deliveries []models.WebhookDelivery
+	matches    []models.WebhookSecretMatch
+	rejected   []models.RejectedSource
+}
+
+func (f *fakeWebhookMonitor) RecentDeliveries() []models.WebhookDelivery {
+	return f.deliveries
+}
+
+func (f *fakeWebhookMonitor) Delivery(id string) (models.WebhookDelivery, bool) {
+	for _, delivery := range f.deliveries {
+		if delivery.ID == id {
+			return delivery, true
+		}
+	}
+	return models.WebhookDelivery{}, false
+}
+
+func (f *fakeWebhookMonitor) RecentSecretMatches() []models.WebhookSecretMatch {
+	return f.matches
+}
+
+func (f *fakeWebhookMonitor) RejectedSources() []models.RejectedSource {
+	return f.rejected
+}
+
+func TestAdminWebhookSecrets(t *testing.T) {
+	t.Setenv("ZOOM_WEBHOOK_SECRET_TOKEN", "new_secret")
+	t.Setenv("ZOOM_WEBHOOK_PREVIOUS_SECRET_TOKENS", "old_secret@2099-01-01,expired_secret@2000-01-01")
+
+	repo := memory.NewRepository()
+	handler, err := NewAdminHandler(service.NewMeetingService(repo), repo, nil, "templates")
+	require.NoError(t, err)
+
+	receivedAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	handler.SetWebhookMonitor(&fakeWebhookMonitor{matches: []models.WebhookSecretMatch{
+		{ReceivedAt: receivedAt, Event: "meeting.started", MeetingID: "meeting1", AccountName: "default", SecretName: "previous-1"},
+	}})
+
+	rr := httptest.NewRecorder()
+	handler.handleWebhookSecrets(rr, httptest.NewRequest("GET", "/admin/secrets", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	body := rr.Body.String()
+	assert.NotContains(t, body, "old_secret")
+	assert.Regexp(t, `<td>primary</td>\s*<td><code>[0-9a-f]{8}</code></td>\s*<td>Never</td>\s*<td><span class="status-active">Active</span></td>\s*<td>-</td>`, body)
+	assert.Regexp(t, `<td>previous-1</td>\s*<td><code>[0-9a-f]{8}</code></td>\s*<td>2099-01-01 00:00:00</td>\s*<td><span class="status-active">Active</span></td>\s*<td>2025-06-01 12:00:00</td>`, body)
+	assert.Regexp(t, `<td>previous-2</td>\s*<td><code>[0-9a-f]{8}</code></td>\s*<td>2000-01-01 00:00:00</td>\s*<td><span class="status-ended">Expired</span></td>`, body)
+	assert.Regexp(t, `<code>meeting.started</code></td>\s*<td><span class="meeting-id">meeting1</span></td>\s*<td>default</td>\s*<td>previous-1</td>`, body)
+}
+
+func TestAdminDiagnostics(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	handler, err := NewAdminHandler(meetingService, repo, nil, "templates")
+	require.NoError(t, err)
+
+	var event models.WebhookEvent
+	require.NoError(t, json.Unmarshal([]byte(`{"event": "meeting.chat_message_sent", "payload": {"object": {"id": "111"}}}`), &event))
+	for i := 0; i < 3; i++ {
+		_, err := meetingService.ApplyEvent(context.Background(), &event)
+		require.NoError(t, err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.handleDiagnostics(rr, httptest.NewRequest("GET", "/admin/diagnostics", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	body := rr.Body.String()
+	assert.Regexp(t, `<code>meeting.chat_message_sent</code></td>\s*<td>3</td>`, body)
+	assert.Contains(t, body, "<li><code>meeting.started</code></li>")
+}
+
+func TestAdminWebhookDeliveries(t *testing.T) {
+	repo := memory.NewRepository()
+	handler, err := NewAdminHandler(service.NewMeetingService(repo), repo, nil, "templates")
+	require.NoError(t, err)
+
+	receivedAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	handler.SetWebhookMonitor(&fakeWebhookMonitor{deliveries: []models.WebhookDelivery{
+		{
+			ID:         "2",
+			ReceivedAt: receivedAt,
+			Event:      "meeting.participant_joined",
+			MeetingID:  "meeting1",
+			Signature:  models.SignatureValid,
+			Outcome:    models.DeliveryOutcome(service.EventApplied),
+			Attempts:   1,
+			Latency:    1500 * time.Microsecond,
+			Payload:    models.RedactPayload(json.RawMessage(`{"event":"meeting.participant_joined","payload":{"object":{"id":"meeting1","participant":{"user_name":"Ola Nordmann"}}}}`)),
+		},
+		{
+			ID:         "1",
+			ReceivedAt: receivedAt,
+			Signature:  models.SignatureInvalid,
+			Outcome:    models.DeliveryRejected,
+			Error:      "invalid signature",
+		},
+	}, rejected: []models.RejectedSource{
+		{Address: "192.0.2.1", Count: 3, LastSeen: receivedAt},
+	}})
+
+	t.Run("List", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.handleWebhookDeliveries(rr, httptest.NewRequest("GET", "/admin/webhooks", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		body := rr.Body.String()
+		assert.Regexp(t, `<code>meeting.participant_joined</code></td>\s*<td><span class="meeting-id">meeting1</span></td>\s*<td><span class="status-active">Valid</span></td>\s*<td><span class="status-active">applied</span></td>\s*<td>1.5ms</td>`, body)
+		assert.Regexp(t, `<td><span class="status-ended">Invalid</span></td>\s*<td><span class="status-ended">rejected</span></td>`, body)
+		assert.Contains(t, body, `href="/admin/webhooks/2"`)
+		assert.Regexp(t, `<td><code>192.0.2.1</code></td>\s*<td>3</td>`, body)
+	})
+
+	t.Run("Detail", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.handleWebhookDelivery(rr, httptest.NewRequest("GET", "/admin/webhooks/2", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		body := rr.Body.String()
+		assert.Contains(t, body, "&#34;user_name&#34;: &#34;[redacted]&#34;")
+		assert.NotContains(t, body, "Ola Nordmann")
+	})
+
+	t.Run("DetailOfDroppedDelivery", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.handleWebhookDelivery(rr, httptest.NewRequest("GET", "/admin/webhooks/99", nil))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("RequiresAuthentication", func(t *testing.T) {
+		t.Setenv("NAIS_TOKEN_INTROSPECTION_ENDPOINT", "http://127.0.0.1:0/introspect")
+		mux := http.NewServeMux()
+		handler.SetupAdminRoutes(mux)
+
+		for _, path := range []string{"/admin/webhooks", "/admin/webhooks/2"} {
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+			assert.Equal(t, http.StatusUnauthorized, rr.Code, path)
+		}
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"html/template"
 	"log"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"time"
 
@@ -18,13 +19,20 @@ type Handler struct {
 	meetingService *service.MeetingService
 	templates      *template.Template
 	sseManager     *SSEManager
+	accounts       config.ZoomAccounts
+	freshness      FreshnessMonitor // Optional; the stale data banner is never shown when nil
 }
 
 // NewHandler creates a new web UI handler
 func NewHandler(meetingService *service.MeetingService, templatesDir string) (*Handler, error) {
+	accounts := config.GetZoomConfig().AllAccounts()
+
 	// Parse templates
 	tmpl, err := template.New("").Funcs(template.FuncMap{
-		"formatTime": formatTime,
+		"formatTime":     formatTime,
+		"formatDateTime": formatDateTime,
+		"filterQuery":    filterQuery,
+		"accountName":    accountNameFunc(accounts),
 	}).ParseGlob(filepath.Join(templatesDir, "*.html"))
 
 	if err != nil {
@@ -38,9 +46,23 @@ func NewHandler(meetingService *service.MeetingService, templatesDir string) (*H
 		meetingService: meetingService,
 		templates:      tmpl,
 		sseManager:     sseManager,
+		accounts:       accounts,
 	}, nil
 }
 
+// SetFreshnessMonitor sets the source of the stale data banner shown when webhook events stop arriving
+func (h *Handler) SetFreshnessMonitor(monitor FreshnessMonitor) {
+	h.freshness = monitor
+}
+
+// freshnessStatus returns the current watchdog status, which is never stale without a freshness monitor
+func (h *Handler) freshnessStatus() service.WatchdogStatus {
+	if h.freshness == nil {
+		return service.WatchdogStatus{Status: service.HealthUp}
+	}
+	return h.freshness.Status()
+}
+
 // formatTime is a template helper function to format time
 func formatTime(t time.Time) string {
 	if t.IsZero() {
@@ -49,6 +71,96 @@ func formatTime(t time.Time) string {
 	return t.Format("15:04:05")
 }
 
+// typeFilter returns the meeting type requested with the "type" query parameter,
+// or an empty string to show both meetings and webinars
+func typeFilter(r *http.Request) string {
+	switch meetingType := models.MeetingType(r.URL.Query().Get("type")); meetingType {
+	case models.MeetingTypeMeeting, models.MeetingTypeWebinar:
+		return string(meetingType)
+	default:
+		return ""
+	}
+}
+
+// matchesType reports whether a meeting is of the given type; an empty type matches all.
+// Meetings stored without a type are regular meetings.
+func matchesType(meeting *models.Meeting, meetingType string) bool {
+	switch models.MeetingType(meetingType) {
+	case models.MeetingTypeWebinar:
+		return meeting.IsWebinar()
+	case models.MeetingTypeMeeting:
+		return !meeting.IsWebinar()
+	default:
+		return true
+	}
+}
+
+// accountFilter returns the name of the account requested with the "account" query parameter,
+// or an empty string to show meetings of all accounts
+func accountFilter(r *http.Request, accounts config.ZoomAccounts) string {
+	name := r.URL.Query().Get("account")
+	if _, ok := accounts.ByName(name); !ok {
+		return ""
+	}
+	return name
+}
+
+// matchesAccount reports whether a meeting belongs to the named account; an empty name matches all.
+// Meetings from accounts that are not configured belong to the default account.
+func matchesAccount(meeting *models.Meeting, accountName string, accounts config.ZoomAccounts) bool {
+	return accountName == "" || accounts.ByID(meeting.AccountID).Name == accountName
+}
+
+// accountNames returns the names of the configured accounts, or nil when only the default account is configured
+func accountNames(accounts config.ZoomAccounts) []string {
+	if len(accounts) < 2 {
+		return nil
+	}
+
+	names := make([]string, 0, len(accounts))
+	for _, account := range accounts {
+		names = append(names, account.Name)
+	}
+	return names
+}
+
+// accountNameFunc returns a template helper giving the name of the account a meeting belongs to
+func accountNameFunc(accounts config.ZoomAccounts) func(meeting *models.Meeting) string {
+	return func(meeting *models.Meeting) string {
+		return accounts.ByID(meeting.AccountID).Name
+	}
+}
+
+// filterQuery is a template helper building the query string for a meeting type and account filter
+func filterQuery(meetingType, accountName string) string {
+	query := url.Values{}
+	if meetingType != "" {
+		query.Set("type", meetingType)
+	}
+	if accountName != "" {
+		query.Set("account", accountName)
+	}
+	if len(query) == 0 {
+		return ""
+	}
+	return "?" + query.Encode()
+}
+
+// filterMeetingStatusData keeps only meetings of the given type and account
+func filterMeetingStatusData(meetings []service.MeetingStatusData, meetingType, accountName string, accounts config.ZoomAccounts) []service.MeetingStatusData {
+	if meetingType == "" && accountName == "" {
+		return meetings
+	}
+
+	filtered := make([]service.MeetingStatusData, 0, len(meetings))
+	for _, data := range meetings {
+		if matchesType(data.Meeting, meetingType) && matchesAccount(data.Meeting, accountName, accounts) {
+			filtered = append(filtered, data)
+		}
+	}
+	return filtered
+}
+
 // SetupRoutes registers web UI routes on the given mux
 func (h *Handler) SetupRoutes(mux *http.ServeMux) {
 	// Serve static files
@@ -63,6 +175,8 @@ func (h *Handler) SetupRoutes(mux *http.ServeMux) {
 
 	// Add HTMX partial endpoints
 	mux.HandleFunc("/partial/meetings", h.HandlePartialMeetingList)
+	mux.HandleFunc("/partial/rooms", h.HandlePartialRoomList)
+	mux.HandleFunc("/partial/freshness", h.HandlePartialFreshness)
 }
 
 // handleIndex renders the main page with meeting status
@@ -73,26 +187,47 @@ func (h *Handler) handleIndex(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get meeting data, including ended meetings
+	// Get meeting data, including ended meetings, optionally only meetings or webinars of one account
+	meetingType := typeFilter(r)
+	accountName := accountFilter(r, h.accounts)
 	meetings, err := h.meetingService.GetMeetingStatusData(r.Context(), true)
 	if err != nil {
 		log.Printf("Error getting meeting data: %v", err)
 		http.Error(w, "Failed to get meeting data", http.StatusInternalServerError)
 		return
 	}
+	meetings = filterMeetingStatusData(meetings, meetingType, accountName, h.accounts)
+
+	// Get the state of the physical rooms
+	rooms, err := h.meetingService.GetRooms(r.Context())
+	if err != nil {
+		log.Printf("Error getting room data: %v", err)
+		http.Error(w, "Failed to get room data", http.StatusInternalServerError)
+		return
+	}
 
 	// Prepare view model
 	zoomConfig := config.GetZoomConfig()
 	viewModel := struct {
-		Meetings    []service.MeetingStatusData
-		LastUpdated string
-		CurrentYear int
-		OAuthURL    string
+		Meetings     []service.MeetingStatusData
+		Type         string
+		Account      string
+		AccountNames []string
+		Rooms        []*models.Room
+		LastUpdated  string
+		CurrentYear  int
+		OAuthURL     string
+		Freshness    service.WatchdogStatus
 	}{
-		Meetings:    meetings,
-		LastUpdated: time.Now().Format("2006-01-02 15:04:05"),
-		CurrentYear: time.Now().Year(),
-		OAuthURL:    zoomConfig.GetOAuthURL(),
+		Meetings:     meetings,
+		Type:         meetingType,
+		Account:      accountName,
+		AccountNames: accountNames(h.accounts),
+		Rooms:        rooms,
+		LastUpdated:  time.Now().Format("2006-01-02 15:04:05"),
+		CurrentYear:  time.Now().Year(),
+		OAuthURL:     zoomConfig.GetOAuthURL(),
+		Freshness:    h.freshnessStatus(),
 	}
 
 	// Render template
@@ -105,7 +240,7 @@ func (h *Handler) handleIndex(w http.ResponseWriter, r *http.Request) {
 
 // HandlePartialMeetingList renders just the meeting list table for HTMX updates
 func (h *Handler) HandlePartialMeetingList(w http.ResponseWriter, r *http.Request) {
-	// Get meeting data, including ended meetings
+	// Get meeting data, including ended meetings, optionally only meetings or webinars of one account
 	meetings, err := h.meetingService.GetMeetingStatusData(r.Context(), true)
 	if err != nil {
 		log.Printf("Error getting meeting data: %v", err)
@@ -115,9 +250,11 @@ func (h *Handler) HandlePartialMeetingList(w http.ResponseWriter, r *http.Reques
 
 	// Prepare view model
 	viewModel := struct {
-		Meetings []service.MeetingStatusData
+		Meetings     []service.MeetingStatusData
+		AccountNames []string
 	}{
-		Meetings: meetings,
+		Meetings:     filterMeetingStatusData(meetings, typeFilter(r), accountFilter(r, h.accounts), h.accounts),
+		AccountNames: accountNames(h.accounts),
 	}
 
 	// Render only the meeting_list template part
@@ -128,12 +265,65 @@ func (h *Handler) HandlePartialMeetingList(w http.ResponseWriter, r *http.Reques
 	}
 }
 
+// HandlePartialRoomList renders just the room list for HTMX updates
+func (h *Handler) HandlePartialRoomList(w http.ResponseWriter, r *http.Request) {
+	rooms, err := h.meetingService.GetRooms(r.Context())
+	if err != nil {
+		log.Printf("Error getting room data: %v", err)
+		http.Error(w, "Failed to get room data", http.StatusInternalServerError)
+		return
+	}
+
+	// Prepare view model
+	viewModel := struct {
+		Rooms []*models.Room
+	}{
+		Rooms: rooms,
+	}
+
+	// Render only the room_list template part
+	err = h.templates.ExecuteTemplate(w, "room_list", viewModel)
+	if err != nil {
+		log.Printf("Error rendering template: %v", err)
+		http.Error(w, "Failed to render room list", http.StatusInternalServerError)
+	}
+}
+
+// HandlePartialFreshness renders just the stale data banner, polled by the page as no event announces silence
+func (h *Handler) HandlePartialFreshness(w http.ResponseWriter, r *http.Request) {
+	// Prepare view model
+	viewModel := struct {
+		Freshness service.WatchdogStatus
+	}{
+		Freshness: h.freshnessStatus(),
+	}
+
+	// Render only the freshness_banner template part
+	err := h.templates.ExecuteTemplate(w, "freshness_banner", viewModel)
+	if err != nil {
+		log.Printf("Error rendering template: %v", err)
+		http.Error(w, "Failed to render freshness banner", http.StatusInternalServerError)
+	}
+}
+
 // NotifyMeetingUpdate sends an update notification to all SSE clients
 // This should be called whenever a meeting is updated
 func (h *Handler) NotifyMeetingUpdate(meeting *models.Meeting) {
 	h.sseManager.NotifyMeetingUpdate(meeting)
 }
 
+// NotifyRoomUpdate sends an update notification to all SSE clients
+// This should be called whenever the state of a room changes
+func (h *Handler) NotifyRoomUpdate(room *models.Room) {
+	h.sseManager.NotifyRoomUpdate(room)
+}
+
+// NotifyDeviceAlert sends a device health notification to all SSE clients
+// This should be called whenever a device alert is raised or cleared
+func (h *Handler) NotifyDeviceAlert(alert *models.DeviceAlert) {
+	h.sseManager.NotifyDeviceAlert(alert)
+}
+
 // Shutdown gracefully shuts down the web handler and its SSE manager
 func (h *Handler) Shutdown() {
 	h.sseManager.Shutdown()
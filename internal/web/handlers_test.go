@@ -0,0 +1,231 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/navikt/zrooms/internal/models"
+	"github.com/navikt/zrooms/internal/repository/memory"
+	"github.com/navikt/zrooms/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// applyEvent applies a webhook event to the meeting service, as the webhook handler does
+func applyEvent(t *testing.T, meetingService *service.MeetingService, payload string) {
+	t.Helper()
+	var event models.WebhookEvent
+	require.NoError(t, json.Unmarshal([]byte(payload), &event))
+	_, err := meetingService.ApplyEvent(context.Background(), &event)
+	require.NoError(t, err)
+}
+
+func TestPartialRoomList(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	handler, err := NewHandler(meetingService, "templates")
+	require.NoError(t, err)
+	defer handler.Shutdown()
+
+	t.Run("NoRooms", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.HandlePartialRoomList(rr, httptest.NewRequest("GET", "/partial/rooms", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), "No rooms have reported their status yet")
+	})
+
+	t.Run("RoomStates", func(t *testing.T) {
+		applyEvent(t, meetingService, `{"event": "zoomroom.started_meeting", "payload": {"object": {"id": "room1", "room_name": "Oslo", "meeting_id": "111", "topic": "Standup"}}, "event_ts": 1620123456000}`)
+		applyEvent(t, meetingService, `{"event": "zoomroom.checked_in", "payload": {"object": {"id": "room2", "room_name": "Bergen"}}, "event_ts": 1620123456000}`)
+		applyEvent(t, meetingService, `{"event": "zoomroom.checked_out", "payload": {"object": {"id": "room3"}}, "event_ts": 1620123456000}`)
+
+		rr := httptest.NewRecorder()
+		handler.HandlePartialRoomList(rr, httptest.NewRequest("GET", "/partial/rooms", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		body := rr.Body.String()
+		assert.Contains(t, body, "Oslo")
+		assert.Contains(t, body, "In Meeting")
+		assert.Contains(t, body, "Standup")
+		assert.Contains(t, body, "Bergen")
+		assert.Contains(t, body, "Checked In")
+		assert.Contains(t, body, "room3", "Rooms without a name should show their ID")
+		assert.Contains(t, body, "Available")
+	})
+}
+
+func TestMeetingTypeFilter(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	handler, err := NewHandler(meetingService, "templates")
+	require.NoError(t, err)
+	defer handler.Shutdown()
+
+	applyEvent(t, meetingService, `{"event": "meeting.started", "payload": {"object": {"uuid": "uuid1", "id": "meeting1", "topic": "Team Standup"}}, "event_ts": 1620123456000}`)
+	applyEvent(t, meetingService, `{"event": "webinar.started", "payload": {"object": {"uuid": "uuid3", "id": "webinar1", "topic": "All Hands"}}, "event_ts": 1620123456000}`)
+	// Stored before webinars were supported, so it has no type
+	require.NoError(t, repo.SaveMeeting(context.Background(), &models.Meeting{ID: "meeting2", Topic: "Legacy Sync", Status: models.MeetingStatusStarted}))
+
+	tests := []struct {
+		name     string
+		url      string
+		expected []string
+		excluded []string
+	}{
+		{name: "All", url: "/partial/meetings", expected: []string{"Team Standup", "Legacy Sync", "All Hands", "Webinar"}},
+		{name: "Meetings", url: "/partial/meetings?type=meeting", expected: []string{"Team Standup", "Legacy Sync"}, excluded: []string{"All Hands"}},
+		{name: "Webinars", url: "/partial/meetings?type=webinar", expected: []string{"All Hands", "Webinar"}, excluded: []string{"Team Standup", "Legacy Sync"}},
+		{name: "UnknownTypeShowsAll", url: "/partial/meetings?type=other", expected: []string{"Team Standup", "All Hands"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.HandlePartialMeetingList(rr, httptest.NewRequest("GET", tt.url, nil))
+			assert.Equal(t, http.StatusOK, rr.Code)
+			for _, text := range tt.expected {
+				assert.Contains(t, rr.Body.String(), text)
+			}
+			for _, text := range tt.excluded {
+				assert.NotContains(t, rr.Body.String(), text)
+			}
+		})
+	}
+
+	t.Run("IndexKeepsFilterForLiveUpdates", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.handleIndex(rr, httptest.NewRequest("GET", "/?type=webinar", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `hx-get="/partial/meetings?type=webinar"`)
+		assert.NotContains(t, rr.Body.String(), "Team Standup")
+	})
+}
+
+func TestMeetingIndicatorBadges(t *testing.T) {
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	handler, err := NewHandler(meetingService, "templates")
+	require.NoError(t, err)
+	defer handler.Shutdown()
+	ctx := context.Background()
+
+	applyEvent(t, meetingService, `{"event": "meeting.started", "payload": {"object": {"uuid": "uuid1", "id": "meeting1", "topic": "Team Standup"}}, "event_ts": 1620123456000}`)
+
+	render := func() string {
+		rr := httptest.NewRecorder()
+		handler.HandlePartialMeetingList(rr, httptest.NewRequest("GET", "/partial/meetings", nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+		return rr.Body.String()
+	}
+
+	body := render()
+	assert.NotContains(t, body, "indicator-badge")
+
+	require.NoError(t, repo.SetMeetingRecording(ctx, "meeting1", models.RecordingStatusRecording))
+	require.NoError(t, repo.SetMeetingSharing(ctx, "meeting1", true))
+	body = render()
+	assert.Contains(t, body, `<span class="indicator-badge recording">Recording</span>`)
+	assert.Contains(t, body, `<span class="indicator-badge sharing">Sharing</span>`)
+
+	require.NoError(t, repo.SetMeetingRecording(ctx, "meeting1", models.RecordingStatusPaused))
+	require.NoError(t, repo.SetMeetingSharing(ctx, "meeting1", false))
+	body = render()
+	assert.Contains(t, body, "Recording paused")
+	assert.NotContains(t, body, "indicator-badge sharing")
+}
+
+func TestMeetingAccountFilter(t *testing.T) {
+	t.Setenv("ZOOM_ACCOUNTS", "sandbox")
+	t.Setenv("ZOOM_ACCOUNT_SANDBOX_ID", "sandbox123")
+
+	repo := memory.NewRepository()
+	meetingService := service.NewMeetingService(repo)
+	handler, err := NewHandler(meetingService, "templates")
+	require.NoError(t, err)
+	defer handler.Shutdown()
+
+	applyEvent(t, meetingService, `{"event": "meeting.started", "payload": {"account_id": "main123", "object": {"uuid": "uuid1", "id": "meeting1", "topic": "Team Standup"}}, "event_ts": 1620123456000}`)
+	applyEvent(t, meetingService, `{"event": "meeting.started", "payload": {"account_id": "sandbox123", "object": {"uuid": "uuid2", "id": "meeting1", "topic": "Sandbox Test"}}, "event_ts": 1620123456000}`)
+
+	tests := []struct {
+		name     string
+		url      string
+		expected []string
+		excluded []string
+	}{
+		{name: "All", url: "/partial/meetings", expected: []string{"Team Standup", "Sandbox Test", `<span class="account-badge">sandbox</span>`}},
+		{name: "Default", url: "/partial/meetings?account=default", expected: []string{"Team Standup"}, excluded: []string{"Sandbox Test"}},
+		{name: "Sandbox", url: "/partial/meetings?account=sandbox", expected: []string{"Sandbox Test"}, excluded: []string{"Team Standup"}},
+		{name: "UnknownAccountShowsAll", url: "/partial/meetings?account=other", expected: []string{"Team Standup", "Sandbox Test"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.HandlePartialMeetingList(rr, httptest.NewRequest("GET", tt.url, nil))
+			assert.Equal(t, http.StatusOK, rr.Code)
+			for _, text := range tt.expected {
+				assert.Contains(t, rr.Body.String(), text)
+			}
+			for _, text := range tt.excluded {
+				assert.NotContains(t, rr.Body.String(), text)
+			}
+		})
+	}
+
+	t.Run("IndexKeepsFiltersForLiveUpdates", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.handleIndex(rr, httptest.NewRequest("GET", "/?type=meeting&account=sandbox", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `hx-get="/partial/meetings?account=sandbox&amp;type=meeting"`)
+		assert.Contains(t, rr.Body.String(), `href="/?type=meeting"`)
+	})
+}
+
+// fakeFreshnessMonitor returns a fixed watchdog status
+type fakeFreshnessMonitor struct {
+	status service.WatchdogStatus
+}
+
+func (f *fakeFreshnessMonitor) Status() service.WatchdogStatus {
+	return f.status
+}
+
+func TestStaleDataBanner(t *testing.T) {
+	repo := memory.NewRepository()
+	handler, err := NewHandler(service.NewMeetingService(repo), "templates")
+	require.NoError(t, err)
+	defer handler.Shutdown()
+
+	monitor := &fakeFreshnessMonitor{status: service.WatchdogStatus{Status: service.HealthUp}}
+	handler.SetFreshnessMonitor(monitor)
+
+	t.Run("Fresh", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.handleIndex(rr, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `hx-get="/partial/freshness"`)
+		assert.NotContains(t, rr.Body.String(), "Data may be stale")
+	})
+
+	t.Run("Stale", func(t *testing.T) {
+		monitor.status = service.WatchdogStatus{
+			Status:      service.HealthDegraded,
+			LastEventAt: time.Date(2025, 6, 9, 8, 15, 0, 0, time.Local),
+		}
+
+		rr := httptest.NewRecorder()
+		handler.handleIndex(rr, httptest.NewRequest("GET", "/", nil))
+		assert.Contains(t, rr.Body.String(), "Data may be stale")
+		assert.Contains(t, rr.Body.String(), "2025-06-09 08:15:00")
+
+		rr = httptest.NewRecorder()
+		handler.HandlePartialFreshness(rr, httptest.NewRequest("GET", "/partial/freshness", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Data may be stale")
+	})
+}
@@ -12,10 +12,25 @@ type MeetingServicer interface {
 	// Web UI data retrieval
 	GetMeetingStatusData(ctx context.Context, includeEnded bool) ([]service.MeetingStatusData, error)
 
-	// Webhook notification methods
-	NotifyMeetingStarted(meeting *models.Meeting)
-	NotifyMeetingUpdated(meeting *models.Meeting)
-	NotifyMeetingEnded(meeting *models.Meeting)
-	NotifyParticipantJoined(meetingID string, participantID string)
-	NotifyParticipantLeft(meetingID string, participantID string)
+	// Applies webhook events to the meeting state and notifies about the changes
+	ApplyEvent(ctx context.Context, event *models.WebhookEvent) (service.ApplyResult, error)
+}
+
+// EventReplayer applies a stored webhook event again, used to replay dead letters from the admin UI
+type EventReplayer interface {
+	ProcessEvent(ctx context.Context, event *models.WebhookEvent) error
+}
+
+// WebhookMonitor reports recent webhook deliveries, which webhook secret verified each event and
+// which source addresses were rejected by the IP allowlist, shown in the admin UI
+type WebhookMonitor interface {
+	RecentDeliveries() []models.WebhookDelivery
+	Delivery(id string) (models.WebhookDelivery, bool)
+	RecentSecretMatches() []models.WebhookSecretMatch
+	RejectedSources() []models.RejectedSource
+}
+
+// FreshnessMonitor reports whether the data shown may be stale because webhook events stopped arriving
+type FreshnessMonitor interface {
+	Status() service.WatchdogStatus
 }
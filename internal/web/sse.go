@@ -120,6 +120,32 @@ func (sm *SSEManager) NotifyMeetingUpdate(meeting *models.Meeting) {
 	}
 }
 
+// NotifyRoomUpdate sends room updates to all connected clients via broadcast channel
+func (sm *SSEManager) NotifyRoomUpdate(room *models.Room) {
+	log.Printf("Publishing SSE update event for room %s", room.ID)
+
+	// Rooms are refreshed by the same update event as meetings
+	select {
+	case sm.broadcast <- "event: update\ndata: update\n\n":
+		log.Printf("Broadcast message sent to channel")
+	default:
+		log.Printf("Broadcast channel full, dropping message")
+	}
+}
+
+// NotifyDeviceAlert sends device health changes to all connected clients via broadcast channel.
+// A separate event type is used so only the admin device health page refreshes.
+func (sm *SSEManager) NotifyDeviceAlert(alert *models.DeviceAlert) {
+	log.Printf("Publishing SSE device-alert event for room %s", alert.RoomID)
+
+	select {
+	case sm.broadcast <- "event: device-alert\ndata: update\n\n":
+		log.Printf("Broadcast message sent to channel")
+	default:
+		log.Printf("Broadcast channel full, dropping message")
+	}
+}
+
 // Shutdown gracefully shuts down the SSE manager by closing the shutdown channel
 func (sm *SSEManager) Shutdown() {
 	log.Printf("Shutting down SSE manager")
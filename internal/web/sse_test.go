@@ -23,24 +23,9 @@ func (m *MockMeetingService) GetMeetingStatusData(ctx context.Context, includeEn
 	return args.Get(0).([]service.MeetingStatusData), args.Error(1)
 }
 
-func (m *MockMeetingService) NotifyMeetingStarted(meeting *models.Meeting) {
-	m.Called(meeting)
-}
-
-func (m *MockMeetingService) NotifyMeetingUpdated(meeting *models.Meeting) {
-	m.Called(meeting)
-}
-
-func (m *MockMeetingService) NotifyMeetingEnded(meeting *models.Meeting) {
-	m.Called(meeting)
-}
-
-func (m *MockMeetingService) NotifyParticipantJoined(meetingID string, participantID string) {
-	m.Called(meetingID, participantID)
-}
-
-func (m *MockMeetingService) NotifyParticipantLeft(meetingID string, participantID string) {
-	m.Called(meetingID, participantID)
+func (m *MockMeetingService) ApplyEvent(ctx context.Context, event *models.WebhookEvent) (service.ApplyResult, error) {
+	args := m.Called(ctx, event)
+	return args.Get(0).(service.ApplyResult), args.Error(1)
 }
 
 // CreateTestMeeting creates a sample meeting for testing
@@ -199,6 +184,21 @@ func TestNotifyMeetingUpdate(t *testing.T) {
 	}
 }
 
+func TestNotifyDeviceAlert(t *testing.T) {
+	sseManager := NewSSEManager(new(MockMeetingService))
+
+	sseManager.NotifyDeviceAlert(&models.DeviceAlert{ID: "room1:2:0", RoomID: "room1"})
+
+	// Device alerts use their own event type so only the device health page refreshes
+	select {
+	case message := <-sseManager.broadcast:
+		assert.Contains(t, message, "event: device-alert")
+		assert.NotContains(t, message, "event: update")
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("Expected message on broadcast channel")
+	}
+}
+
 func TestSSEManager_Shutdown(t *testing.T) {
 	// Create a mock meeting service
 	mockService := new(MockMeetingService)
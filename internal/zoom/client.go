@@ -70,15 +70,20 @@ type TokenResponse struct {
 
 // APIManager handles Zoom API access token management using OAuth account credentials
 type APIManager struct {
-	config      config.ZoomConfig
+	account     config.ZoomAccount
 	accessToken string
 	tokenExpiry time.Time
 }
 
-// NewAPIManager creates a new Zoom API manager
+// NewAPIManager creates a new Zoom API manager for the default account
 func NewAPIManager() *APIManager {
+	return NewAPIManagerForAccount(config.GetZoomConfig().DefaultAccount())
+}
+
+// NewAPIManagerForAccount creates a new Zoom API manager using the credentials of the given account
+func NewAPIManagerForAccount(account config.ZoomAccount) *APIManager {
 	return &APIManager{
-		config: config.GetZoomConfig(),
+		account: account,
 	}
 }
 
@@ -95,13 +100,16 @@ func (m *APIManager) GetClient() (*APIClient, error) {
 
 // refreshAccessToken gets a new access token using OAuth account credentials flow
 func (m *APIManager) refreshAccessToken() error {
-	if m.config.ClientID == "" || m.config.ClientSecret == "" {
-		return fmt.Errorf("zoom client ID and secret must be configured")
+	if m.account.ClientID == "" || m.account.ClientSecret == "" {
+		return fmt.Errorf("zoom client ID and secret must be configured for account %s", m.account.Name)
 	}
 
 	// Prepare the request data for OAuth account credentials flow
 	data := url.Values{}
 	data.Set("grant_type", "account_credentials")
+	if m.account.ID != "" {
+		data.Set("account_id", m.account.ID)
+	}
 
 	req, err := http.NewRequest("POST", "https://zoom.us/oauth/token", strings.NewReader(data.Encode()))
 	if err != nil {
@@ -109,7 +117,7 @@ func (m *APIManager) refreshAccessToken() error {
 	}
 
 	// Set basic auth with client credentials
-	req.SetBasicAuth(m.config.ClientID, m.config.ClientSecret)
+	req.SetBasicAuth(m.account.ClientID, m.account.ClientSecret)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	client := &http.Client{Timeout: 30 * time.Second}